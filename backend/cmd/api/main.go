@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/adapter"
+	"github.com/omidiyanto/mino/pkg/api"
+	"github.com/omidiyanto/mino/pkg/router"
+)
+
+// main serves every route from one function, an alternative to deploying a
+// function per route. Behind a function URL or an ALB, which have no routes
+// of their own, it is the only way to serve the API.
+func main() {
+	lambda.Start(adapter.Handler(router.New(api.Routes()).Handle))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/webhook"
+)
+
+// Handler is the Lambda function handler, invoked every minute by a schedule
+// to retry the deliveries whose next attempt is due
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	now := time.Now().UTC()
+
+	deliveries, err := db.GetDueDeliveries(now.Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	// Deliveries of the same webhook share one lookup per run
+	webhooks := make(map[string]*models.Webhook)
+
+	var failed int
+	for i := range deliveries {
+		if err := retry(ctx, &deliveries[i], webhooks, now); err != nil {
+			logging.FromContext(ctx).Error("webhook delivery failed", "webhookId", deliveries[i].WebhookID, "deliveryId", deliveries[i].DeliveryID, "error", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		logging.FromContext(ctx).Warn("deliveries could not be processed, they are retried once their lease expires", "failed", failed)
+	}
+	return nil
+}
+
+// retry makes the next attempt at one delivery
+func retry(ctx context.Context, delivery *models.WebhookDelivery, webhooks map[string]*models.Webhook, now time.Time) error {
+	// Claim the delivery so that overlapping runs do not send it twice
+	err := db.ClaimDelivery(delivery, now.Add(webhook.Lease).Format(time.RFC3339))
+	if errors.Is(err, db.ErrDeliveryClaimed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	hook, ok := webhooks[delivery.WebhookID]
+	if !ok {
+		hook, err = db.GetWebhook(delivery.WebhookID, delivery.UserID)
+		if errors.Is(err, db.ErrWebhookNotFound) {
+			// The webhook was deleted meanwhile, its pending deliveries are dropped
+			delivery.Status = db.DeliveryFailed
+			delivery.LastError = "webhook was deleted"
+			delivery.CompletedAt = now.Format(time.RFC3339)
+			return db.SaveDelivery(delivery)
+		}
+		if err != nil {
+			return err
+		}
+		webhooks[delivery.WebhookID] = hook
+	}
+
+	if err := webhook.Deliver(ctx, *hook, delivery); err != nil {
+		return err
+	}
+
+	// Later deliveries of a webhook this attempt disabled must see the change
+	if delivery.Status != db.DeliverySucceeded {
+		delete(webhooks, delivery.WebhookID)
+	}
+	return nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
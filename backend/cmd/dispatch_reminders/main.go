@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/notify"
+)
+
+// Dispatch settings
+const (
+	lookback     = 15 * time.Minute // Failed reminders of earlier buckets are retried this long
+	maxCatchUp   = 6 * time.Hour    // A run checks at most this many buckets after missed runs
+	claimTimeout = 5 * time.Minute  // A claim older than this is considered abandoned
+)
+
+// notifier delivers the reminders, chosen through the environment
+var notifier notify.Notifier
+
+// Handler is the Lambda function handler, invoked every minute by a schedule.
+// A run checks the buckets from the last one a run processed, so reminders
+// due while runs were missed or failing are sent late rather than lost, and
+// the buckets of the lookback for failed reminders.
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	now := time.Now().UTC()
+
+	start := now.Add(-lookback)
+	processed, ok, err := db.GetReminderCursor()
+	if err != nil {
+		return err
+	}
+	if ok && processed.Before(start) {
+		start = processed
+	}
+	end := now
+	if end.Sub(start) > maxCatchUp {
+		// The next runs catch up with the rest
+		end = start.Add(maxCatchUp)
+		logging.FromContext(ctx).Warn("catching up with missed reminders", "from", db.ReminderBucket(start), "to", db.ReminderBucket(end))
+	}
+
+	var failed int
+	for t := start; !t.After(end); t = t.Add(time.Minute) {
+		reminders, err := db.GetPendingReminders(db.ReminderBucket(t))
+		if err != nil {
+			return err
+		}
+
+		for _, reminder := range reminders {
+			if err := dispatch(ctx, reminder, now); err != nil {
+				logging.FromContext(ctx).Error("reminder failed", "bucket", reminder.Bucket, "noteId", reminder.NoteID, "error", err)
+				failed++
+			}
+		}
+	}
+
+	// The last bucket may still get reminders due later in its minute, the
+	// next run checks it again
+	if err := db.SetReminderCursor(end); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		logging.FromContext(ctx).Warn("reminders failed, they are retried on the next run", "failed", failed)
+	}
+	return nil
+}
+
+// dispatch delivers one reminder unless it is not due yet or already handled
+func dispatch(ctx context.Context, reminder models.Reminder, now time.Time) error {
+	remindAt, err := db.ParseRemindAt(reminder.RemindAt)
+	if err != nil {
+		return err
+	}
+	if remindAt.After(now) {
+		return nil
+	}
+
+	// Claim the reminder so that overlapping runs do not send it twice
+	err = db.ClaimReminder(reminder, now, now.Add(-claimTimeout))
+	if errors.Is(err, db.ErrReminderClaimed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The note may have been deleted or rescheduled before the index caught up
+	note, err := db.GetNoteByID(reminder.NoteID, reminder.UserID)
+	if errors.Is(err, db.ErrNoteNotFound) || (err == nil && note.RemindAt != reminder.RemindAt) {
+		return db.MarkReminderSent(reminder, now)
+	}
+	if err != nil {
+		return release(reminder, err)
+	}
+
+	user, err := db.GetUserByID(reminder.UserID)
+	if err != nil {
+		return release(reminder, err)
+	}
+
+	err = notifier.Notify(ctx, notify.Reminder{
+		NoteID:   note.NoteID,
+		UserID:   note.UserID,
+		Email:    user.Email,
+		Title:    note.Title,
+		RemindAt: note.RemindAt,
+	})
+	if err != nil {
+		return release(reminder, err)
+	}
+
+	return db.MarkReminderSent(reminder, now)
+}
+
+// release drops the claim on a reminder whose delivery failed, so that the
+// next run retries it rather than waiting for the claim to time out
+func release(reminder models.Reminder, err error) error {
+	if releaseErr := db.ReleaseReminder(reminder); releaseErr != nil {
+		return errors.Join(err, fmt.Errorf("release claim: %w", releaseErr))
+	}
+	return err
+}
+
+func main() {
+	var err error
+	notifier, err = notify.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lambda.Start(Handler)
+}
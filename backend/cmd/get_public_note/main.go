@@ -0,0 +1,11 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/adapter"
+	"github.com/omidiyanto/mino/pkg/handlers/getpublicnote"
+)
+
+func main() {
+	lambda.Start(adapter.Handler(getpublicnote.Handler))
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/omidiyanto/mino/pkg/client"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+func runLogin(ctx context.Context, e *env, args []string) error {
+	email := e.flags.String("email", e.config.Email, "email of the account")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	apiURL, err := e.api()
+	if err != nil {
+		return err
+	}
+
+	input := bufio.NewReader(e.stdin)
+	if *email == "" {
+		fmt.Fprint(e.stderr, "Email: ")
+		if *email, err = readLine(input); err != nil {
+			return err
+		}
+	}
+	password := os.Getenv("MINO_PASSWORD")
+	if password == "" {
+		fmt.Fprint(e.stderr, "Password: ")
+		if password, err = readPassword(input); err != nil {
+			return err
+		}
+	}
+
+	auth, err := client.New(apiURL).Login(ctx, *email, password)
+	if err != nil {
+		return err
+	}
+	e.config.APIURL = apiURL
+	e.config.Email = auth.User.Email
+	e.config.Token = auth.Token
+	if err := e.config.save(); err != nil {
+		return err
+	}
+
+	if e.output == "json" {
+		return printJSON(e.stdout, auth.User)
+	}
+	fmt.Fprintf(e.stdout, "Logged in as %s\n", auth.User.Email)
+	return nil
+}
+
+func runLogout(ctx context.Context, e *env, args []string) error {
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	e.config.Token = ""
+	return e.config.save()
+}
+
+func runList(ctx context.Context, e *env, args []string) error {
+	var options client.ListOptions
+	e.flags.StringVar(&options.NotebookID, "notebook", "", "only the notes of a notebook")
+	e.flags.BoolVar(&options.Archived, "archived", false, "include archived notes")
+	e.flags.BoolVar(&options.Favorite, "favorites", false, "only favorite notes")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	notes, err := c.ListNotes(ctx, options)
+	if err != nil {
+		return err
+	}
+	if e.output == "json" {
+		return printJSON(e.stdout, notes)
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tUPDATED\tFLAGS")
+	for _, note := range notes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", note.NoteID, truncate(note.Title, 40), note.UpdatedAt, flags(note))
+	}
+	return w.Flush()
+}
+
+func runCat(ctx context.Context, e *env, args []string) error {
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if e.flags.NArg() != 1 {
+		return errors.New("cat takes one note ID")
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	note, err := c.GetNote(ctx, e.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if e.output == "json" {
+		return printJSON(e.stdout, note)
+	}
+	if note.Encrypted != nil {
+		return errors.New("the note is end-to-end encrypted, open it in the app")
+	}
+	body := note.Content
+	if note.Type == "checklist" {
+		body = checklist(note.Items)
+	}
+	_, err = io.WriteString(e.stdout, editorText(note.Title, strings.TrimRight(body, "\n")+"\n"))
+	return err
+}
+
+func runNew(ctx context.Context, e *env, args []string) error {
+	title := e.flags.String("title", "", "title, the first line of the text when empty")
+	notebook := e.flags.String("notebook", "", "notebook ID, the default notebook when empty")
+	format := e.flags.String("format", "markdown", "content format, markdown or plain")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	// Read the note from stdin when it is piped in, from the editor otherwise
+	var text string
+	if interactive(e.stdin) {
+		if text, err = editText(editorText(*title, "")); err != nil {
+			return err
+		}
+	} else {
+		data, err := io.ReadAll(e.stdin)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+		if *title != "" {
+			text = editorText(*title, text)
+		}
+	}
+	noteTitle, content, err := parseEditorText(text)
+	if err != nil || noteTitle == "" {
+		return errors.New("the note has no title, nothing was saved")
+	}
+
+	note, err := c.CreateNote(ctx, models.Note{
+		NotebookID: *notebook,
+		Title:      noteTitle,
+		Content:    content,
+		Format:     *format,
+	})
+	if err != nil {
+		return err
+	}
+	if e.output == "json" {
+		return printJSON(e.stdout, note)
+	}
+	fmt.Fprintln(e.stdout, note.NoteID)
+	return nil
+}
+
+func runEdit(ctx context.Context, e *env, args []string) error {
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if e.flags.NArg() != 1 {
+		return errors.New("edit takes one note ID")
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	note, err := c.GetNote(ctx, e.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	switch {
+	case note.Encrypted != nil:
+		return errors.New("the note is end-to-end encrypted, edit it in the app")
+	case note.Type == "checklist":
+		return errors.New("checklists cannot be edited as text, edit it in the app")
+	}
+
+	original := editorText(note.Title, note.Content)
+	text, err := editText(original)
+	if err != nil {
+		return err
+	}
+	if text == original {
+		fmt.Fprintln(e.stderr, "No changes")
+		return nil
+	}
+	if note.Title, note.Content, err = parseEditorText(text); err != nil || note.Title == "" {
+		return errors.New("the note has no title, nothing was saved")
+	}
+
+	updated, err := c.UpdateNote(ctx, *note)
+	if err != nil {
+		return err
+	}
+	if e.output == "json" {
+		return printJSON(e.stdout, updated)
+	}
+	fmt.Fprintln(e.stdout, updated.NoteID)
+	return nil
+}
+
+func runRemove(ctx context.Context, e *env, args []string) error {
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if e.flags.NArg() == 0 {
+		return errors.New("rm takes the IDs of the notes to delete")
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	for _, noteID := range e.flags.Args() {
+		if err := c.DeleteNote(ctx, noteID); err != nil {
+			return fmt.Errorf("deleting %s: %w", noteID, err)
+		}
+		if e.output == "table" {
+			fmt.Fprintln(e.stdout, noteID)
+		}
+	}
+	if e.output == "json" {
+		return printJSON(e.stdout, e.flags.Args())
+	}
+	return nil
+}
+
+func runSearch(ctx context.Context, e *env, args []string) error {
+	limit := e.flags.Int("limit", 0, "results to return, 1 to 100, 20 when zero")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	query := strings.Join(e.flags.Args(), " ")
+	if query == "" {
+		return errors.New("search takes a query")
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	results, err := c.SearchNotes(ctx, query, *limit)
+	if err != nil {
+		return err
+	}
+	if e.output == "json" {
+		return printJSON(e.stdout, results)
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tSNIPPET")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.Note.NoteID, truncate(result.Note.Title, 40), truncate(unmark(result.Snippet), 60))
+	}
+	return w.Flush()
+}
+
+func runExport(ctx context.Context, e *env, args []string) error {
+	dir := e.flags.String("dir", ".", "directory to write the files to")
+	var options client.ListOptions
+	e.flags.StringVar(&options.NotebookID, "notebook", "", "only the notes of a notebook")
+	e.flags.BoolVar(&options.Archived, "archived", false, "include archived notes")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	notes, err := c.ListNotes(ctx, options)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+
+	type exported struct {
+		NoteID string `json:"noteId"`
+		Path   string `json:"path"`
+	}
+	files := make([]exported, 0, len(notes))
+	for _, note := range notes {
+		// The server cannot read end-to-end encrypted notes, nor can we
+		if note.Encrypted != nil {
+			fmt.Fprintf(e.stderr, "Skipped %s, it is end-to-end encrypted\n", note.NoteID)
+			continue
+		}
+		path := filepath.Join(*dir, fileName(note))
+		if err := os.WriteFile(path, []byte(markdown(note)), 0644); err != nil {
+			return err
+		}
+		files = append(files, exported{NoteID: note.NoteID, Path: path})
+		if e.output == "table" {
+			fmt.Fprintln(e.stdout, path)
+		}
+	}
+	if e.output == "json" {
+		return printJSON(e.stdout, files)
+	}
+	return nil
+}
+
+// printJSON writes a value as indented JSON
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// flags sums up the flags of a note for the table, P for pinned, F for
+// favorite and A for archived
+func flags(note models.Note) string {
+	var s string
+	for _, flag := range []struct {
+		letter string
+		set    bool
+	}{{"P", note.Pinned}, {"F", note.Favorite}, {"A", note.Archived}} {
+		if flag.set {
+			s += flag.letter
+		}
+	}
+	return s
+}
+
+// unmark turns a highlighted, HTML-escaped search snippet into plain text
+func unmark(snippet string) string {
+	snippet = strings.NewReplacer("<mark>", "", "</mark>", "").Replace(snippet)
+	return html.UnescapeString(snippet)
+}
+
+// truncate shortens text to n characters on one line
+func truncate(text string, n int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// interactive reports whether input comes from a terminal
+func interactive(input io.Reader) bool {
+	file, ok := input.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// readLine reads a line without its line ending
+func readLine(input *bufio.Reader) (string, error) {
+	line, err := input.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readPassword reads a line, without echoing it when stdin is a terminal
+// that stty can configure
+func readPassword(input *bufio.Reader) (string, error) {
+	if interactive(os.Stdin) && stty("-echo") == nil {
+		defer func() {
+			stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	return readLine(input)
+}
+
+// stty sets a mode of the terminal on stdin
+func stty(mode string) error {
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
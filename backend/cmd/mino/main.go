@@ -0,0 +1,152 @@
+// Command mino works with notes from the terminal through the REST API.
+//
+//	mino login --api https://abc123.execute-api.us-east-1.amazonaws.com/prod
+//	mino ls
+//	mino new --title "Groceries"
+//
+// The API URL and token are kept in mino/config.json under the user's config
+// directory after login. MINO_API_URL and MINO_TOKEN override them.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/omidiyanto/mino/pkg/client"
+)
+
+const usage = `Usage: mino <command> [flags] [arguments]
+
+Commands:
+  login               log in and cache the token
+  logout              forget the cached token
+  ls                  list notes
+  cat <id>            print a note
+  new                 write a new note in $EDITOR, or from stdin
+  edit <id>           edit a note in $EDITOR
+  rm <id>...          delete notes
+  search <query>      search notes
+  export              write notes to Markdown files
+
+Every command accepts --api to set the API URL and -o table|json to choose
+the output. Run mino <command> -h for the flags of a command.
+`
+
+// command is a subcommand, run with its own flag set
+type command struct {
+	run  func(ctx context.Context, env *env, args []string) error
+	args string // Synopsis of the arguments, for -h
+}
+
+var commands = map[string]command{
+	"login":  {runLogin, ""},
+	"logout": {runLogout, ""},
+	"ls":     {runList, ""},
+	"cat":    {runCat, "<id>"},
+	"new":    {runNew, ""},
+	"edit":   {runEdit, "<id>"},
+	"rm":     {runRemove, "<id>..."},
+	"search": {runSearch, "<query>"},
+	"export": {runExport, ""},
+}
+
+// env is what every command runs with
+type env struct {
+	flags  *flag.FlagSet
+	apiURL string
+	output string
+	config *config
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "mino: unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fail(err)
+	}
+	e := &env{
+		flags:  flag.NewFlagSet("mino "+name, flag.ExitOnError),
+		config: cfg,
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+	e.flags.StringVar(&e.apiURL, "api", "", "API URL, such as http://192.168.0.250:4566/restapis/<id>/dev/_user_request_")
+	e.flags.StringVar(&e.output, "o", "table", "output format, table or json")
+	e.flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: mino %s [flags] %s\n\nFlags:\n", name, cmd.args)
+		e.flags.PrintDefaults()
+	}
+
+	if err := cmd.run(ctx, e, os.Args[2:]); err != nil {
+		fail(err)
+	}
+}
+
+// parse parses the flags of a command and checks the common ones
+func (e *env) parse(args []string) error {
+	if err := e.flags.Parse(args); err != nil {
+		return err
+	}
+	if e.output != "table" && e.output != "json" {
+		return fmt.Errorf("unknown output %q, use table or json", e.output)
+	}
+	return nil
+}
+
+// api returns the API URL, from the flag, the environment or the config
+func (e *env) api() (string, error) {
+	for _, url := range []string{e.apiURL, os.Getenv("MINO_API_URL"), e.config.APIURL} {
+		if url != "" {
+			return strings.TrimSuffix(url, "/"), nil
+		}
+	}
+	return "", errors.New("no API URL, pass --api or set MINO_API_URL")
+}
+
+// client returns a client authenticated with the cached token
+func (e *env) client() (*client.Client, error) {
+	apiURL, err := e.api()
+	if err != nil {
+		return nil, err
+	}
+	token := os.Getenv("MINO_TOKEN")
+	if token == "" {
+		token = e.config.Token
+	}
+	if token == "" {
+		return nil, errors.New("not logged in, run mino login")
+	}
+	return client.New(apiURL, client.WithToken(token)), nil
+}
+
+// fail prints an error and exits, with a hint for an expired session
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "mino: %s\n", strings.TrimPrefix(err.Error(), "mino: "))
+	if errors.Is(err, client.ErrUnauthorized) {
+		fmt.Fprintln(os.Stderr, "Your session has expired, run mino login")
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// editorText is a note as it is edited: the title as a heading, a blank line
+// and the content
+func editorText(title, content string) string {
+	return "# " + title + "\n\n" + content
+}
+
+// parseEditorText splits edited text into a title and content. The title is
+// the first non-empty line, without its heading marker.
+func parseEditorText(text string) (string, string, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		title := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		content := strings.TrimLeft(strings.Join(lines[i+1:], "\n"), "\n")
+		return title, strings.TrimRight(content, "\n") + "\n", nil
+	}
+	return "", "", errors.New("the note is empty")
+}
+
+// editText opens text in the user's editor and returns what they saved
+func editText(text string) (string, error) {
+	file, err := os.CreateTemp("", "mino-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	// The editor may come with arguments, such as code --wait
+	args := strings.Fields(editor())
+	cmd := exec.Command(args[0], append(args[1:], file.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s: %w", args[0], err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	return string(edited), err
+}
+
+// editor returns the user's editor command
+func editor() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+			return value
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// markdown renders a note as a Markdown file with YAML front matter
+func markdown(note models.Note) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %s\n", strconv.Quote(note.Title))
+	fmt.Fprintf(&b, "noteId: %s\n", note.NoteID)
+	if note.NotebookID != "" {
+		fmt.Fprintf(&b, "notebookId: %s\n", note.NotebookID)
+	}
+	fmt.Fprintf(&b, "createdAt: %s\n", note.CreatedAt)
+	fmt.Fprintf(&b, "updatedAt: %s\n", note.UpdatedAt)
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{{"pinned", note.Pinned}, {"archived", note.Archived}, {"favorite", note.Favorite}} {
+		if flag.set {
+			fmt.Fprintf(&b, "%s: true\n", flag.name)
+		}
+	}
+	if note.RemindAt != "" {
+		fmt.Fprintf(&b, "remindAt: %s\n", note.RemindAt)
+	}
+	b.WriteString("---\n\n")
+
+	if note.Type == "checklist" {
+		b.WriteString(checklist(note.Items))
+		return b.String()
+	}
+	b.WriteString(strings.TrimRight(note.Content, "\n") + "\n")
+	return b.String()
+}
+
+// checklist renders checklist items as Markdown task list items
+func checklist(items []models.ChecklistItem) string {
+	var b strings.Builder
+	for _, item := range items {
+		box := "[ ]"
+		if item.Checked {
+			box = "[x]"
+		}
+		fmt.Fprintf(&b, "- %s %s\n", box, item.Text)
+	}
+	return b.String()
+}
+
+// fileName returns the export file name of a note: its title as a slug and
+// the start of its ID, which keeps notes of the same title apart
+func fileName(note models.Note) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(note.Title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			slug.WriteRune(r)
+			dash = false
+		} else if !dash && slug.Len() > 0 {
+			slug.WriteByte('-')
+			dash = true
+		}
+		if slug.Len() >= 50 {
+			break
+		}
+	}
+	name := strings.Trim(slug.String(), "-")
+	if name == "" {
+		name = "note"
+	}
+	id := note.NoteID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return name + "-" + id + ".md"
+}
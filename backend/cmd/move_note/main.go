@@ -0,0 +1,11 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/adapter"
+	"github.com/omidiyanto/mino/pkg/handlers/movenote"
+)
+
+func main() {
+	lambda.Start(adapter.Handler(movenote.Handler))
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/realtime"
+	"github.com/omidiyanto/mino/pkg/storage"
+	"github.com/omidiyanto/mino/pkg/stream"
+	"github.com/omidiyanto/mino/pkg/webhook"
+)
+
+// deadLetterTable stores dead letters in DynamoDB
+type deadLetterTable struct{}
+
+// SendDeadLetter saves a dead letter to the dead letters table
+func (deadLetterTable) SendDeadLetter(ctx context.Context, letter stream.DeadLetter) error {
+	return db.SaveDeadLetter(letter)
+}
+
+// searchIndexProjector keeps the search index in sync with note titles and
+// contents or checklist items
+var searchIndexProjector = stream.ProjectorFunc{
+	ProjectorName: "search-index",
+	Func: func(ctx context.Context, change stream.NoteChange) error {
+		// Pinning, moving or archiving a note does not change its terms
+		if change.OldNote != nil && change.NewNote != nil &&
+			change.OldNote.Title == change.NewNote.Title && change.OldNote.Text() == change.NewNote.Text() &&
+			(change.OldNote.Encrypted == nil) == (change.NewNote.Encrypted == nil) {
+			return nil
+		}
+
+		// Encrypted notes are never indexed, the server cannot read them
+		if change.OldNote != nil && change.OldNote.Encrypted == nil {
+			if err := db.RemoveNoteFromIndex(*change.OldNote); err != nil {
+				return err
+			}
+		}
+		if change.NewNote != nil && change.NewNote.Encrypted == nil {
+			return db.IndexNote(*change.NewNote)
+		}
+		return nil
+	},
+}
+
+// sharesCleanupProjector revokes the shares and share links of deleted notes
+var sharesCleanupProjector = stream.ProjectorFunc{
+	ProjectorName: "shares-cleanup",
+	Func: func(ctx context.Context, change stream.NoteChange) error {
+		if change.EventName != stream.EventRemove {
+			return nil
+		}
+		if err := db.RevokeAllShares(change.OldNote.NoteID, change.OldNote.UserID); err != nil {
+			return err
+		}
+		return db.RevokeShareLinksForNote(change.OldNote.NoteID, change.OldNote.UserID)
+	},
+}
+
+// attachmentsCleanupProjector deletes the files of deleted notes and gives
+// their size back to the owner's storage quota
+var attachmentsCleanupProjector = stream.ProjectorFunc{
+	ProjectorName: "attachments-cleanup",
+	Func: func(ctx context.Context, change stream.NoteChange) error {
+		if change.EventName != stream.EventRemove {
+			return nil
+		}
+
+		attachments, err := db.GetAttachments(change.OldNote.NoteID, change.OldNote.UserID)
+		if err != nil {
+			return err
+		}
+		for _, attachment := range attachments {
+			if err := storage.DeleteObject(ctx, attachment.Key); err != nil {
+				return err
+			}
+			// A replayed record finds the attachment gone and gives nothing back twice
+			if err := db.DeleteAttachment(attachment); err != nil && !errors.Is(err, db.ErrAttachmentNotFound) {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// remindersProjector keeps the reminders index in sync with note reminder times
+var remindersProjector = stream.ProjectorFunc{
+	ProjectorName: "reminders",
+	Func: func(ctx context.Context, change stream.NoteChange) error {
+		var oldRemindAt, newRemindAt string
+		if change.OldNote != nil {
+			oldRemindAt = change.OldNote.RemindAt
+		}
+		if change.NewNote != nil {
+			newRemindAt = change.NewNote.RemindAt
+		}
+		if oldRemindAt == newRemindAt {
+			return nil
+		}
+
+		if oldRemindAt != "" {
+			if err := db.DeleteReminder(*change.OldNote); err != nil {
+				return err
+			}
+		}
+		if newRemindAt != "" {
+			return db.PutReminder(*change.NewNote)
+		}
+		return nil
+	},
+}
+
+// syncLogProjector records every note change in the owner's change log,
+// which delta sync clients pull from
+var syncLogProjector = stream.ProjectorFunc{
+	ProjectorName: "sync-log",
+	Func: func(ctx context.Context, change stream.NoteChange) error {
+		if change.Rewritten() {
+			return nil
+		}
+		return db.LogNoteChange(*change.Note(), change.EventName == stream.EventRemove)
+	},
+}
+
+// webhookEvents maps stream events to webhook and WebSocket events
+var webhookEvents = map[string]string{
+	stream.EventInsert: webhook.EventNoteCreated,
+	stream.EventModify: webhook.EventNoteUpdated,
+	stream.EventRemove: webhook.EventNoteDeleted,
+}
+
+// webhooksProjector queues a delivery of each note change to the owner's
+// subscribed webhooks. They are sent by the deliver_webhooks function, so that
+// a slow webhook does not hold up the stream.
+var webhooksProjector = stream.ProjectorFunc{
+	ProjectorName: "webhooks",
+	Func: func(ctx context.Context, change stream.NoteChange) error {
+		event, ok := webhookEvents[change.EventName]
+		if !ok || change.Rewritten() {
+			return nil
+		}
+		note := change.Note()
+
+		webhooks, err := db.GetWebhooksByUserID(note.UserID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, hook := range webhooks {
+			if !hook.Active || !webhook.Subscribed(hook, event) {
+				continue
+			}
+
+			delivery, err := webhook.NewDelivery(hook, change.EventID, event, *note, now)
+			if err != nil {
+				return err
+			}
+
+			// A replayed record was queued already
+			err = db.EnqueueDelivery(delivery)
+			if err != nil && !errors.Is(err, db.ErrDeliveryExists) {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// realtimeClient pushes note changes to WebSocket connections
+var realtimeClient = realtime.NewClient()
+
+// realtimeProjector tells the live WebSocket connections of the owner and of
+// the users the note is shared with of each note change, so that other tabs
+// and devices fetch it without polling. It runs before the shares
+// of a deleted note are revoked, so that their users hear of the deletion.
+var realtimeProjector = stream.ProjectorFunc{
+	ProjectorName: "realtime",
+	Func: func(ctx context.Context, change stream.NoteChange) error {
+		event, ok := webhookEvents[change.EventName]
+		if !ok || change.Rewritten() {
+			return nil
+		}
+		note := change.Note()
+
+		shares, err := db.GetSharesByNoteID(note.NoteID, note.UserID)
+		if err != nil {
+			return err
+		}
+
+		message := realtime.Message{Type: event, NoteID: note.NoteID, Version: note.Version}
+		if err := realtimeClient.Broadcast(ctx, note.UserID, message); err != nil {
+			return err
+		}
+		for _, share := range shares {
+			if share.SharedWithUserID == note.UserID {
+				continue
+			}
+			if err := realtimeClient.Broadcast(ctx, share.SharedWithUserID, message); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func main() {
+	dispatcher := stream.NewDispatcher(deadLetterTable{})
+	dispatcher.Register(searchIndexProjector)
+	dispatcher.Register(realtimeProjector)
+	dispatcher.Register(sharesCleanupProjector)
+	dispatcher.Register(attachmentsCleanupProjector)
+	dispatcher.Register(remindersProjector)
+	dispatcher.Register(syncLogProjector)
+	dispatcher.Register(webhooksProjector)
+
+	lambda.Start(dispatcher.Handle)
+}
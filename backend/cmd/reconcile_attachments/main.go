@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/storage"
+)
+
+// Handler is the Lambda function handler, invoked every few minutes by a
+// schedule. Attachments are charged to the owner's quota when their upload is
+// presigned, a run finds the ones whose upload window has passed and keeps
+// those whose file arrived or deletes the others, which gives their size back.
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	attachments, err := db.GetExpiredUploads(time.Now())
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, attachment := range attachments {
+		if err := reconcile(ctx, attachment); err != nil {
+			logging.FromContext(ctx).Error("failed to reconcile upload", "noteId", attachment.NoteID, "attachmentId", attachment.AttachmentID, "error", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		logging.FromContext(ctx).Warn("uploads failed to reconcile, they are retried on the next run", "failed", failed)
+	}
+	return nil
+}
+
+// reconcile keeps an attachment whose file was uploaded and deletes one whose
+// file never was
+func reconcile(ctx context.Context, attachment models.Attachment) error {
+	exists, err := storage.ObjectExists(ctx, attachment.Key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return db.ConfirmUpload(attachment)
+	}
+
+	// The attachment may have been deleted since it was listed
+	err = db.DeleteAttachment(attachment)
+	if err != nil && !errors.Is(err, db.ErrAttachmentNotFound) {
+		return err
+	}
+
+	// A PUT that outlived the window would leave an object nothing points to
+	return storage.DeleteObject(ctx, attachment.Key)
+}
+
+func main() {
+	lambda.Start(Handler)
+}
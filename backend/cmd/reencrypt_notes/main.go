@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/envelope"
+	"github.com/omidiyanto/mino/pkg/logging"
+)
+
+// reserve is the time left to a run when it stops starting new pages
+const reserve = 30 * time.Second
+
+// Request is the input of a run, invoked by hand after a master key rotation
+type Request struct {
+	StartKey map[string]string `json:"startKey,omitempty"` // nextKey of the previous run, empty to start from the beginning
+	DryRun   bool              `json:"dryRun"`             // Count the notes to re-encrypt without writing
+	Reindex  bool              `json:"reindex"`            // Rebuild the search index entries of every note too
+}
+
+// Response reports a run
+type Response struct {
+	db.ReencryptResult
+	KeyVersion string            `json:"keyVersion"`        // Master key version notes were re-encrypted with
+	NextKey    map[string]string `json:"nextKey,omitempty"` // Set when the run stopped before the end, pass it as startKey
+}
+
+// Handler re-encrypts notes with the current master key version until the
+// table is done or the invocation is about to time out
+func Handler(ctx context.Context, request Request) (Response, error) {
+	response := Response{KeyVersion: envelope.Default.CurrentVersion()}
+
+	startKey := request.StartKey
+	for {
+		nextKey, err := db.ReencryptNotes(ctx, startKey, request.DryRun, request.Reindex, &response.ReencryptResult)
+		if err != nil {
+			response.NextKey = startKey
+			return response, err
+		}
+		if nextKey == nil {
+			break
+		}
+		startKey = nextKey
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < reserve {
+			response.NextKey = nextKey
+			break
+		}
+	}
+
+	logging.FromContext(ctx).Info("re-encryption run completed",
+		"scanned", response.Scanned, "reencrypted", response.Reencrypted, "skipped", response.Skipped,
+		"failed", response.Failed, "reindexed", response.Reindexed, "dryRun", request.DryRun)
+	return response, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
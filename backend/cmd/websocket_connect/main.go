@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/realtime"
+)
+
+// Handler is the Lambda function handler for the $connect route
+func Handler(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	authHeader, protocol := realtime.ConnectToken(request)
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Remember the connection so that note changes reach it
+	now := time.Now().UTC()
+	err = db.SaveConnection(models.Connection{
+		UserID:       claims.UserID,
+		ConnectionID: request.RequestContext.ConnectionID,
+		CallbackURL:  realtime.CallbackURL(request),
+		ConnectedAt:  now.Format(time.RFC3339),
+		TTL:          now.Add(realtime.ConnectionLifetime).Unix(),
+	})
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       `{"success":false,"message":"Failed to save connection"}`,
+		}, nil
+	}
+
+	// A browser drops the connection unless the offered subprotocol is selected
+	var headers map[string]string
+	if protocol != "" {
+		headers = map[string]string{"Sec-WebSocket-Protocol": protocol}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       `{"success":true,"message":"Connected"}`,
+	}, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/db"
+)
+
+// Handler is the Lambda function handler for the $disconnect route
+func Handler(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The disconnect carries no token, the connection is looked up by its ID
+	if err := db.DeleteConnectionByID(request.RequestContext.ConnectionID); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       `{"success":false,"message":"Failed to remove connection"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Body:       `{"success":true,"message":"Disconnected"}`,
+	}, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/realtime"
+)
+
+// clientMessage is a frame sent by a client, routed on its action
+type clientMessage struct {
+	Action string `json:"action"`
+}
+
+// client posts replies back to the connection
+var client = realtime.NewClient()
+
+// Handler is the Lambda function handler for the $default route. Clients only
+// receive note changes, the single action they can send is ping, which keeps
+// an idle connection open.
+func Handler(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	connection := models.Connection{
+		ConnectionID: request.RequestContext.ConnectionID,
+		CallbackURL:  realtime.CallbackURL(request),
+	}
+
+	reply := realtime.Message{Type: "pong"}
+	var message clientMessage
+	if err := json.Unmarshal([]byte(request.Body), &message); err != nil {
+		reply = realtime.Message{Type: "error", Message: "Invalid message: " + err.Error()}
+	} else if message.Action != "ping" {
+		reply = realtime.Message{Type: "error", Message: "Unknown action: " + message.Action}
+	}
+
+	if err := client.Send(ctx, connection, reply); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       `{"success":false,"message":"Failed to reply"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -1,31 +1,44 @@
 module github.com/omidiyanto/mino
 
-go 1.20
+go 1.21
 
 require (
         github.com/aws/aws-lambda-go v1.41.0
-        github.com/aws/aws-sdk-go-v2 v1.18.0
+        github.com/aws/aws-sdk-go-v2 v1.21.2
         github.com/aws/aws-sdk-go-v2/config v1.18.25
         github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.10.25
+        github.com/aws/aws-sdk-go-v2/service/apigatewaymanagementapi v1.14.0
         github.com/aws/aws-sdk-go-v2/service/dynamodb v1.19.7
+        github.com/aws/aws-sdk-go-v2/service/kms v1.22.2
+        github.com/aws/aws-sdk-go-v2/service/s3 v1.27.11
         github.com/golang-jwt/jwt v3.2.2+incompatible
         github.com/google/uuid v1.3.0
-        golang.org/x/crypto v0.9.0
+        github.com/microcosm-cc/bluemonday v1.0.24
+        github.com/yuin/goldmark v1.5.4
+        golang.org/x/crypto v0.11.0
+        golang.org/x/net v0.12.0
 )
 
 require (
+        github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.4.8 // indirect
         github.com/aws/aws-sdk-go-v2/credentials v1.13.24 // indirect
         github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.13.3 // indirect
-        github.com/aws/aws-sdk-go-v2/internal/configsources v1.1.33 // indirect
-        github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.4.27 // indirect
+        github.com/aws/aws-sdk-go-v2/internal/configsources v1.1.43 // indirect
+        github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.4.37 // indirect
         github.com/aws/aws-sdk-go-v2/internal/ini v1.3.34 // indirect
+        github.com/aws/aws-sdk-go-v2/internal/v4a v1.0.14 // indirect
         github.com/aws/aws-sdk-go-v2/service/dynamodbstreams v1.14.11 // indirect
         github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.9.11 // indirect
+        github.com/aws/aws-sdk-go-v2/service/internal/checksum v1.1.18 // indirect
         github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.7.27 // indirect
         github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.9.27 // indirect
+        github.com/aws/aws-sdk-go-v2/service/internal/s3shared v1.13.17 // indirect
         github.com/aws/aws-sdk-go-v2/service/sso v1.12.10 // indirect
         github.com/aws/aws-sdk-go-v2/service/ssooidc v1.14.10 // indirect
         github.com/aws/aws-sdk-go-v2/service/sts v1.19.0 // indirect
-        github.com/aws/smithy-go v1.13.5 // indirect
+        github.com/aws/smithy-go v1.15.0 // indirect
+        github.com/aymerick/douceur v0.2.0 // indirect
+        github.com/gorilla/css v1.0.0 // indirect
         github.com/jmespath/go-jmespath v0.4.0 // indirect
+        golang.org/x/sys v0.10.0 // indirect
 )
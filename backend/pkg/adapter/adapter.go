@@ -0,0 +1,380 @@
+// Package adapter lets the REST handlers serve every HTTP event source of
+// Lambda: API Gateway REST APIs (payload v1), HTTP APIs (payload v2),
+// function URLs and Application Load Balancers.
+//
+// Each event is normalised into the internal request type, an
+// events.APIGatewayProxyRequest in which
+//
+//   - header names are canonical (http.CanonicalHeaderKey), so that
+//     Headers["Authorization"] finds the header whatever case the client or
+//     the event source used, and MultiValueHeaders holds every value
+//   - QueryStringParameters holds the last value of each parameter and
+//     MultiValueQueryStringParameters every value, decoded
+//   - a base64 encoded body is decoded
+//   - Resource is the route template when the event source matched one, and
+//     empty otherwise, in which case the router matches Path
+//
+// and the handler's response is rendered as the response type of the event
+// source.
+package adapter
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/router"
+)
+
+// ErrUnsupportedEvent is returned for an event of no known HTTP source
+var ErrUnsupportedEvent = errors.New("unsupported event, expected an API Gateway, function URL or ALB request")
+
+// Event sources
+const (
+	SourceRESTAPI     = "rest-api"
+	SourceHTTPAPI     = "http-api"
+	SourceFunctionURL = "function-url"
+	SourceALB         = "alb"
+)
+
+// Handler adapts a handler to every event source, for lambda.Start. Each
+// request is logged through logging.Middleware.
+func Handler(handler router.HandlerFunc) func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+	handler = logging.Middleware(handler)
+	return func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		source, err := Detect(event)
+		if err != nil {
+			return nil, err
+		}
+
+		switch source {
+		case SourceHTTPAPI:
+			var request events.APIGatewayV2HTTPRequest
+			if err := json.Unmarshal(event, &request); err != nil {
+				return nil, err
+			}
+			response, err := handler(ctx, FromHTTPAPI(request))
+			if err != nil {
+				return nil, err
+			}
+			return ToHTTPAPI(response), nil
+
+		case SourceFunctionURL:
+			var request events.LambdaFunctionURLRequest
+			if err := json.Unmarshal(event, &request); err != nil {
+				return nil, err
+			}
+			response, err := handler(ctx, FromFunctionURL(request))
+			if err != nil {
+				return nil, err
+			}
+			return ToFunctionURL(response), nil
+
+		case SourceALB:
+			var request events.ALBTargetGroupRequest
+			if err := json.Unmarshal(event, &request); err != nil {
+				return nil, err
+			}
+			response, err := handler(ctx, FromALB(request))
+			if err != nil {
+				return nil, err
+			}
+			return ToALB(response, request.MultiValueHeaders != nil), nil
+
+		default:
+			var request events.APIGatewayProxyRequest
+			if err := json.Unmarshal(event, &request); err != nil {
+				return nil, err
+			}
+			return handler(ctx, FromRESTAPI(request))
+		}
+	}
+}
+
+// Detect tells the source of an event from its shape
+func Detect(event json.RawMessage) (string, error) {
+	var probe struct {
+		Version        string `json:"version"`
+		HTTPMethod     string `json:"httpMethod"`
+		RequestContext struct {
+			ELB        *json.RawMessage `json:"elb"`
+			DomainName string           `json:"domainName"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(event, &probe); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedEvent, err)
+	}
+
+	switch {
+	case probe.RequestContext.ELB != nil:
+		return SourceALB, nil
+	case probe.Version == "2.0" && strings.Contains(probe.RequestContext.DomainName, ".lambda-url."):
+		return SourceFunctionURL, nil
+	case probe.Version == "2.0":
+		return SourceHTTPAPI, nil
+	case probe.HTTPMethod != "":
+		return SourceRESTAPI, nil
+	default:
+		return "", ErrUnsupportedEvent
+	}
+}
+
+// FromRESTAPI normalises a REST API request, which keeps header names as
+// the client sent them
+func FromRESTAPI(request events.APIGatewayProxyRequest) events.APIGatewayProxyRequest {
+	request.Headers, request.MultiValueHeaders = canonicalHeaders(request.Headers, request.MultiValueHeaders)
+	if request.MultiValueQueryStringParameters == nil {
+		request.MultiValueQueryStringParameters = multiValues(request.QueryStringParameters)
+	}
+	request.Body, request.IsBase64Encoded = decodeBody(request.Body, request.IsBase64Encoded)
+	return request
+}
+
+// FromHTTPAPI normalises an HTTP API request. HTTP APIs lowercase header
+// names and join repeated headers and query parameters with commas.
+func FromHTTPAPI(request events.APIGatewayV2HTTPRequest) events.APIGatewayProxyRequest {
+	normalised := events.APIGatewayProxyRequest{
+		Resource:       routeResource(request.RouteKey),
+		Path:           stripStage(request.RawPath, request.RequestContext.Stage),
+		HTTPMethod:     request.RequestContext.HTTP.Method,
+		PathParameters: request.PathParameters,
+		StageVariables: request.StageVariables,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:  request.RequestContext.AccountID,
+			RequestID:  request.RequestContext.RequestID,
+			Stage:      request.RequestContext.Stage,
+			DomainName: request.RequestContext.DomainName,
+			APIID:      request.RequestContext.APIID,
+			HTTPMethod: request.RequestContext.HTTP.Method,
+			Path:       request.RequestContext.HTTP.Path,
+		},
+	}
+	normalised.Headers, normalised.MultiValueHeaders = canonicalHeaders(withCookies(request.Headers, request.Cookies), nil)
+	normalised.QueryStringParameters, normalised.MultiValueQueryStringParameters = parseQuery(request.RawQueryString, request.QueryStringParameters)
+	normalised.Body, normalised.IsBase64Encoded = decodeBody(request.Body, request.IsBase64Encoded)
+	return normalised
+}
+
+// FromFunctionURL normalises a function URL request, which has the shape of
+// an HTTP API one without routes
+func FromFunctionURL(request events.LambdaFunctionURLRequest) events.APIGatewayProxyRequest {
+	normalised := events.APIGatewayProxyRequest{
+		Path:       request.RawPath,
+		HTTPMethod: request.RequestContext.HTTP.Method,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:  request.RequestContext.AccountID,
+			RequestID:  request.RequestContext.RequestID,
+			DomainName: request.RequestContext.DomainName,
+			HTTPMethod: request.RequestContext.HTTP.Method,
+			Path:       request.RequestContext.HTTP.Path,
+		},
+	}
+	normalised.Headers, normalised.MultiValueHeaders = canonicalHeaders(withCookies(request.Headers, request.Cookies), nil)
+	normalised.QueryStringParameters, normalised.MultiValueQueryStringParameters = parseQuery(request.RawQueryString, request.QueryStringParameters)
+	normalised.Body, normalised.IsBase64Encoded = decodeBody(request.Body, request.IsBase64Encoded)
+	return normalised
+}
+
+// FromALB normalises an ALB request. ALBs lowercase header names and pass
+// query parameters on without decoding them. Headers and parameters are in
+// the multi-value fields when the target group has multi-value headers on.
+func FromALB(request events.ALBTargetGroupRequest) events.APIGatewayProxyRequest {
+	normalised := events.APIGatewayProxyRequest{
+		Path:       request.Path,
+		HTTPMethod: request.HTTPMethod,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod: request.HTTPMethod,
+			Path:       request.Path,
+		},
+	}
+	normalised.Headers, normalised.MultiValueHeaders = canonicalHeaders(request.Headers, request.MultiValueHeaders)
+
+	query := request.MultiValueQueryStringParameters
+	if query == nil {
+		query = multiValues(request.QueryStringParameters)
+	}
+	normalised.QueryStringParameters = make(map[string]string)
+	normalised.MultiValueQueryStringParameters = make(map[string][]string)
+	for name, values := range query {
+		name = unescape(name)
+		for _, value := range values {
+			value = unescape(value)
+			normalised.MultiValueQueryStringParameters[name] = append(normalised.MultiValueQueryStringParameters[name], value)
+			normalised.QueryStringParameters[name] = value
+		}
+	}
+
+	normalised.Body, normalised.IsBase64Encoded = decodeBody(request.Body, request.IsBase64Encoded)
+	return normalised
+}
+
+// ToHTTPAPI renders a response for an HTTP API
+func ToHTTPAPI(response events.APIGatewayProxyResponse) events.APIGatewayV2HTTPResponse {
+	headers, cookies := joinedHeaders(response)
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode:      response.StatusCode,
+		Headers:         headers,
+		Body:            response.Body,
+		IsBase64Encoded: response.IsBase64Encoded,
+		Cookies:         cookies,
+	}
+}
+
+// ToFunctionURL renders a response for a function URL
+func ToFunctionURL(response events.APIGatewayProxyResponse) events.LambdaFunctionURLResponse {
+	headers, cookies := joinedHeaders(response)
+	return events.LambdaFunctionURLResponse{
+		StatusCode:      response.StatusCode,
+		Headers:         headers,
+		Body:            response.Body,
+		IsBase64Encoded: response.IsBase64Encoded,
+		Cookies:         cookies,
+	}
+}
+
+// ToALB renders a response for an ALB. A target group with multi-value
+// headers on only reads the multi-value fields, one without only the others.
+func ToALB(response events.APIGatewayProxyResponse, multiValue bool) events.ALBTargetGroupResponse {
+	rendered := events.ALBTargetGroupResponse{
+		StatusCode:        response.StatusCode,
+		StatusDescription: fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		Body:              response.Body,
+		IsBase64Encoded:   response.IsBase64Encoded,
+	}
+
+	if multiValue {
+		_, rendered.MultiValueHeaders = canonicalHeaders(response.Headers, response.MultiValueHeaders)
+		return rendered
+	}
+	rendered.Headers, _ = joinedHeaders(response)
+	if cookies := response.MultiValueHeaders["Set-Cookie"]; len(cookies) > 0 {
+		// Without multi-value headers only one cookie can be set
+		rendered.Headers["Set-Cookie"] = cookies[len(cookies)-1]
+	}
+	return rendered
+}
+
+// canonicalHeaders merges single and multi-value headers under canonical
+// names. The single-value map holds the last value of each header, as in
+// REST API events.
+func canonicalHeaders(single map[string]string, multi map[string][]string) (map[string]string, map[string][]string) {
+	headers := make(map[string]string, len(single))
+	multiValueHeaders := make(map[string][]string, len(single))
+	for name, values := range multi {
+		name = http.CanonicalHeaderKey(name)
+		multiValueHeaders[name] = append(multiValueHeaders[name], values...)
+		if len(values) > 0 {
+			headers[name] = values[len(values)-1]
+		}
+	}
+	for name, value := range single {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := multiValueHeaders[name]; !ok {
+			multiValueHeaders[name] = []string{value}
+			headers[name] = value
+		}
+	}
+	return headers, multiValueHeaders
+}
+
+// joinedHeaders flattens the headers of a response for the event sources
+// without multi-value headers: values are joined with commas, and cookies
+// are returned apart since they cannot be joined
+func joinedHeaders(response events.APIGatewayProxyResponse) (map[string]string, []string) {
+	_, multi := canonicalHeaders(response.Headers, response.MultiValueHeaders)
+	headers := make(map[string]string, len(multi))
+	var cookies []string
+	for name, values := range multi {
+		if name == "Set-Cookie" {
+			cookies = append(cookies, values...)
+			continue
+		}
+		headers[name] = strings.Join(values, ",")
+	}
+	return headers, cookies
+}
+
+// withCookies adds the cookies payload v2 sends apart back as a header
+func withCookies(headers map[string]string, cookies []string) map[string]string {
+	if len(cookies) == 0 {
+		return headers
+	}
+	merged := make(map[string]string, len(headers)+1)
+	for name, value := range headers {
+		merged[name] = value
+	}
+	merged["Cookie"] = strings.Join(cookies, "; ")
+	return merged
+}
+
+// parseQuery parses a raw query string, falling back to the parsed
+// parameters of the event when it is malformed
+func parseQuery(rawQuery string, parsed map[string]string) (map[string]string, map[string][]string) {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return parsed, multiValues(parsed)
+	}
+	single := make(map[string]string, len(values))
+	for name, list := range values {
+		single[name] = list[len(list)-1]
+	}
+	return single, values
+}
+
+// multiValues turns single-value parameters into multi-value ones
+func multiValues(single map[string]string) map[string][]string {
+	multi := make(map[string][]string, len(single))
+	for name, value := range single {
+		multi[name] = []string{value}
+	}
+	return multi
+}
+
+// unescape decodes a query string part, keeping it as is when malformed
+func unescape(value string) string {
+	if unescaped, err := url.QueryUnescape(value); err == nil {
+		return unescaped
+	}
+	return value
+}
+
+// decodeBody decodes a base64 encoded body, handlers read bodies as text
+func decodeBody(body string, isBase64Encoded bool) (string, bool) {
+	if !isBase64Encoded {
+		return body, false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return body, true
+	}
+	return string(decoded), false
+}
+
+// routeResource is the resource template of an HTTP API route key such as
+// "GET /notes/{noteId}", empty for the $default route
+func routeResource(routeKey string) string {
+	_, resource, ok := strings.Cut(routeKey, " ")
+	if !ok {
+		return ""
+	}
+	return resource
+}
+
+// stripStage removes the stage name HTTP APIs keep at the start of the path
+// of a stage other than $default
+func stripStage(path string, stage string) string {
+	if stage == "" || stage == "$default" {
+		return path
+	}
+	if stripped := strings.TrimPrefix(path, "/"+stage); stripped != path && (stripped == "" || strings.HasPrefix(stripped, "/")) {
+		return stripped
+	}
+	return path
+}
@@ -0,0 +1,149 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// echo answers with what a handler sees of the request
+func echo(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	seen := map[string]interface{}{
+		"method":        request.HTTPMethod,
+		"resource":      request.Resource,
+		"path":          request.Path,
+		"authorization": request.Headers["Authorization"],
+		"cookie":        request.Headers["Cookie"],
+		"tag":           request.QueryStringParameters["tag"],
+		"tags":          request.MultiValueQueryStringParameters["tag"],
+		"body":          request.Body,
+	}
+	body, _ := json.Marshal(seen)
+	return events.APIGatewayProxyResponse{
+		StatusCode:        201,
+		Headers:           map[string]string{"Content-Type": "application/json"},
+		MultiValueHeaders: map[string][]string{"Set-Cookie": {"a=1", "b=2"}},
+		Body:              string(body),
+	}, nil
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    string
+		source   string
+		seen     map[string]interface{}
+		response interface{}
+	}{
+		{
+			name: "REST API",
+			event: `{"resource":"/notes","path":"/notes","httpMethod":"POST",
+				"headers":{"authorization":"Bearer t"},
+				"multiValueQueryStringParameters":{"tag":["a","b"]},"queryStringParameters":{"tag":"b"},
+				"body":"e30=","isBase64Encoded":true,"requestContext":{"stage":"prod"}}`,
+			source: SourceRESTAPI,
+			seen: map[string]interface{}{
+				"method": "POST", "resource": "/notes", "path": "/notes", "authorization": "Bearer t",
+				"cookie": "", "tag": "b", "tags": []interface{}{"a", "b"}, "body": "{}",
+			},
+			response: events.APIGatewayProxyResponse{},
+		},
+		{
+			name: "HTTP API",
+			event: `{"version":"2.0","routeKey":"POST /notes","rawPath":"/prod/notes","rawQueryString":"tag=a&tag=b%20c",
+				"cookies":["s=1","t=2"],"headers":{"authorization":"Bearer t"},"queryStringParameters":{"tag":"a,b c"},
+				"requestContext":{"stage":"prod","domainName":"abc.execute-api.us-east-1.amazonaws.com","http":{"method":"POST","path":"/prod/notes"}},
+				"body":"{}","isBase64Encoded":false}`,
+			source: SourceHTTPAPI,
+			seen: map[string]interface{}{
+				"method": "POST", "resource": "/notes", "path": "/notes", "authorization": "Bearer t",
+				"cookie": "s=1; t=2", "tag": "b c", "tags": []interface{}{"a", "b c"}, "body": "{}",
+			},
+			response: events.APIGatewayV2HTTPResponse{},
+		},
+		{
+			name: "function URL",
+			event: `{"version":"2.0","rawPath":"/notes","rawQueryString":"tag=a",
+				"headers":{"authorization":"Bearer t"},
+				"requestContext":{"domainName":"abc.lambda-url.us-east-1.on.aws","http":{"method":"POST","path":"/notes"}},
+				"body":"e30=","isBase64Encoded":true}`,
+			source: SourceFunctionURL,
+			seen: map[string]interface{}{
+				"method": "POST", "resource": "", "path": "/notes", "authorization": "Bearer t",
+				"cookie": "", "tag": "a", "tags": []interface{}{"a"}, "body": "{}",
+			},
+			response: events.LambdaFunctionURLResponse{},
+		},
+		{
+			name: "ALB with multi-value headers",
+			event: `{"httpMethod":"POST","path":"/notes",
+				"multiValueHeaders":{"authorization":["Bearer t"]},
+				"multiValueQueryStringParameters":{"tag":["a","b%20c"]},
+				"requestContext":{"elb":{"targetGroupArn":"arn:aws:elasticloadbalancing:us-east-1:000000000000:targetgroup/mino"}},
+				"body":"{}","isBase64Encoded":false}`,
+			source: SourceALB,
+			seen: map[string]interface{}{
+				"method": "POST", "resource": "", "path": "/notes", "authorization": "Bearer t",
+				"cookie": "", "tag": "b c", "tags": []interface{}{"a", "b c"}, "body": "{}",
+			},
+			response: events.ALBTargetGroupResponse{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := Detect(json.RawMessage(tt.event))
+			if err != nil || source != tt.source {
+				t.Fatalf("detected %q, %v, want %q", source, err, tt.source)
+			}
+
+			response, err := Handler(echo)(context.Background(), json.RawMessage(tt.event))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.TypeOf(response) != reflect.TypeOf(tt.response) {
+				t.Fatalf("got a %T response, want %T", response, tt.response)
+			}
+
+			var body string
+			switch r := response.(type) {
+			case events.APIGatewayProxyResponse:
+				body = r.Body
+			case events.APIGatewayV2HTTPResponse:
+				body = r.Body
+				if !reflect.DeepEqual(r.Cookies, []string{"a=1", "b=2"}) {
+					t.Errorf("got cookies %v", r.Cookies)
+				}
+			case events.LambdaFunctionURLResponse:
+				body = r.Body
+				if r.Headers["Content-Type"] != "application/json" {
+					t.Errorf("got headers %v", r.Headers)
+				}
+			case events.ALBTargetGroupResponse:
+				body = r.Body
+				if r.StatusDescription != "201 Created" || r.Headers != nil || len(r.MultiValueHeaders["Set-Cookie"]) != 2 {
+					t.Errorf("got %+v", r)
+				}
+			}
+
+			var seen map[string]interface{}
+			if err := json.Unmarshal([]byte(body), &seen); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(seen, tt.seen) {
+				t.Errorf("handler saw %v\nwant %v", seen, tt.seen)
+			}
+		})
+	}
+}
+
+func TestDetectRejectsOtherEvents(t *testing.T) {
+	for _, event := range []string{`{"Records":[]}`, `[]`, `{}`} {
+		if _, err := Detect(json.RawMessage(event)); err == nil {
+			t.Errorf("%s: got no error", event)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+// Package api is the route table of the REST API, served by one Lambda
+// function per route or by cmd/api alone, and described by its OpenAPI
+// document
+package api
+
+import (
+	"net/http"
+
+	"github.com/omidiyanto/mino/pkg/handlers/addchecklistitem"
+	"github.com/omidiyanto/mino/pkg/handlers/auth"
+	"github.com/omidiyanto/mino/pkg/handlers/createattachment"
+	"github.com/omidiyanto/mino/pkg/handlers/createkey"
+	"github.com/omidiyanto/mino/pkg/handlers/createnote"
+	"github.com/omidiyanto/mino/pkg/handlers/createnotebook"
+	"github.com/omidiyanto/mino/pkg/handlers/createsharelink"
+	"github.com/omidiyanto/mino/pkg/handlers/createwebhook"
+	"github.com/omidiyanto/mino/pkg/handlers/deleteattachment"
+	"github.com/omidiyanto/mino/pkg/handlers/deletenote"
+	"github.com/omidiyanto/mino/pkg/handlers/deletenotebook"
+	"github.com/omidiyanto/mino/pkg/handlers/deletewebhook"
+	"github.com/omidiyanto/mino/pkg/handlers/getattachments"
+	"github.com/omidiyanto/mino/pkg/handlers/getkeys"
+	"github.com/omidiyanto/mino/pkg/handlers/getnote"
+	"github.com/omidiyanto/mino/pkg/handlers/getnotebooks"
+	"github.com/omidiyanto/mino/pkg/handlers/getnotes"
+	"github.com/omidiyanto/mino/pkg/handlers/getnoteshares"
+	"github.com/omidiyanto/mino/pkg/handlers/getopenapi"
+	"github.com/omidiyanto/mino/pkg/handlers/getpublicnote"
+	"github.com/omidiyanto/mino/pkg/handlers/getsharednotes"
+	"github.com/omidiyanto/mino/pkg/handlers/getsharelinks"
+	"github.com/omidiyanto/mino/pkg/handlers/getwebhookdeliveries"
+	"github.com/omidiyanto/mino/pkg/handlers/getwebhooks"
+	"github.com/omidiyanto/mino/pkg/handlers/movenote"
+	"github.com/omidiyanto/mino/pkg/handlers/patchnote"
+	"github.com/omidiyanto/mino/pkg/handlers/pullchanges"
+	"github.com/omidiyanto/mino/pkg/handlers/pushchanges"
+	"github.com/omidiyanto/mino/pkg/handlers/register"
+	"github.com/omidiyanto/mino/pkg/handlers/removechecklistitem"
+	"github.com/omidiyanto/mino/pkg/handlers/reorderchecklistitems"
+	"github.com/omidiyanto/mino/pkg/handlers/revokeshare"
+	"github.com/omidiyanto/mino/pkg/handlers/revokesharelink"
+	"github.com/omidiyanto/mino/pkg/handlers/searchnotes"
+	"github.com/omidiyanto/mino/pkg/handlers/sharenote"
+	"github.com/omidiyanto/mino/pkg/handlers/updatechecklistitem"
+	"github.com/omidiyanto/mino/pkg/handlers/updatekey"
+	"github.com/omidiyanto/mino/pkg/handlers/updatenote"
+	"github.com/omidiyanto/mino/pkg/handlers/updatenotebook"
+	"github.com/omidiyanto/mino/pkg/handlers/updatenoteflags"
+	"github.com/omidiyanto/mino/pkg/handlers/updatewebhook"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/openapi"
+	"github.com/omidiyanto/mino/pkg/patch"
+	"github.com/omidiyanto/mino/pkg/router"
+)
+
+// Route is a route of the table, its handler and what the OpenAPI document
+// says about it
+type Route struct {
+	Handler router.HandlerFunc
+	openapi.Operation
+}
+
+// Table lists every route, in the order of the API Gateway resources
+func Table() []Route {
+	return []Route{
+		{auth.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/auth", ID: "auth",
+			Summary:  "Log in with an email and password",
+			Public:   true,
+			Request:  models.UserCredentials{},
+			Response: models.AuthResponse{}, Bare: true, Status: http.StatusOK,
+		}},
+		{register.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/register", ID: "register",
+			Summary:  "Create an account",
+			Public:   true,
+			Request:  models.UserRegistration{},
+			Response: models.User{}, Status: http.StatusCreated,
+		}},
+		{getnotes.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/notes", ID: "getNotes",
+			Summary: "List notes, pinned first",
+			Query: []openapi.Parameter{
+				{Name: "notebookId", Description: "Only the notes of a notebook"},
+				{Name: "archived", Type: "boolean", Description: "Include archived notes"},
+				{Name: "favorite", Type: "boolean", Description: "Only favorite notes"},
+				{Name: "render", Enum: []string{"html"}, Description: "Add the sanitized HTML rendering of the content"},
+				{Name: "limit", Type: "integer", Description: "Notes per page, 1 to 100, every note without it"},
+				{Name: "cursor", Description: "nextCursor of the previous page"},
+			},
+			Response: []models.Note{}, Status: http.StatusOK,
+		}},
+		{createnote.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/notes", ID: "createNote",
+			Summary:  "Create a note",
+			Request:  models.Note{},
+			Response: models.Note{}, Status: http.StatusCreated,
+		}},
+		{updatenote.Handler, openapi.Operation{
+			Method: http.MethodPut, Path: "/notes/{noteId}", ID: "updateNote",
+			Summary:  "Replace a note",
+			Request:  models.Note{},
+			Response: models.Note{}, Status: http.StatusOK,
+		}},
+		{deletenote.Handler, openapi.Operation{
+			Method: http.MethodDelete, Path: "/notes/{noteId}", ID: "deleteNote",
+			Summary: "Delete a note",
+			Status:  http.StatusOK,
+		}},
+		{getnotebooks.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/notebooks", ID: "getNotebooks",
+			Summary:  "List notebooks",
+			Response: []models.Notebook{}, Status: http.StatusOK,
+		}},
+		{createnotebook.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/notebooks", ID: "createNotebook",
+			Summary:  "Create a notebook",
+			Request:  models.Notebook{},
+			Response: models.Notebook{}, Status: http.StatusCreated,
+		}},
+		{updatenotebook.Handler, openapi.Operation{
+			Method: http.MethodPut, Path: "/notebooks/{notebookId}", ID: "updateNotebook",
+			Summary:  "Rename a notebook",
+			Request:  models.Notebook{},
+			Response: models.Notebook{}, Status: http.StatusOK,
+		}},
+		{deletenotebook.Handler, openapi.Operation{
+			Method: http.MethodDelete, Path: "/notebooks/{notebookId}", ID: "deleteNotebook",
+			Summary: "Delete a notebook and move its notes to the default notebook",
+			Status:  http.StatusOK,
+		}},
+		{movenote.Handler, openapi.Operation{
+			Method: http.MethodPut, Path: "/notes/{noteId}/notebook", ID: "moveNote",
+			Summary:  "Move a note to another notebook",
+			Request:  movenote.MoveRequest{},
+			Response: models.Note{}, Status: http.StatusOK,
+		}},
+		{updatenoteflags.Handler, openapi.Operation{
+			Method: http.MethodPut, Path: "/notes/{noteId}/flags", ID: "updateNoteFlags",
+			Summary:  "Pin, archive or favorite a note",
+			Request:  models.NoteFlags{},
+			Response: models.Note{}, Status: http.StatusOK,
+		}},
+		{searchnotes.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/notes/search", ID: "searchNotes",
+			Summary: "Search notes by relevance",
+			Query: []openapi.Parameter{
+				{Name: "q", Description: "Search query, required"},
+				{Name: "limit", Type: "integer", Description: "Results to return, 1 to 100, 20 by default"},
+			},
+			Response: []models.SearchResult{}, Status: http.StatusOK,
+		}},
+		{patchnote.Handler, openapi.Operation{
+			Method: http.MethodPatch, Path: "/notes/{noteId}", ID: "patchNote",
+			Summary: "Update some fields of a note with a JSON merge patch, or a JSON patch sent as application/json-patch+json",
+			Request: models.Note{}, RequestType: patch.MergePatchType,
+			Response: models.Note{}, Status: http.StatusOK,
+		}},
+		{getnote.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/notes/{noteId}", ID: "getNote",
+			Summary: "Get a note, or 304 when If-None-Match or If-Modified-Since match",
+			Query: []openapi.Parameter{
+				{Name: "render", Enum: []string{"html"}, Description: "Add the sanitized HTML rendering of the content"},
+			},
+			Response: models.Note{}, Status: http.StatusOK,
+		}},
+		{getsharednotes.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/notes/shared-with-me", ID: "getSharedNotes",
+			Summary:  "List the notes other users shared with the caller",
+			Response: []models.SharedNote{}, Status: http.StatusOK,
+		}},
+		{sharenote.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/notes/{noteId}/shares", ID: "shareNote",
+			Summary:  "Share a note with another user",
+			Request:  models.ShareRequest{},
+			Response: models.Share{}, Status: http.StatusCreated,
+		}},
+		{getnoteshares.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/notes/{noteId}/shares", ID: "getNoteShares",
+			Summary:  "List the users a note is shared with",
+			Response: []models.Share{}, Status: http.StatusOK,
+		}},
+		{revokeshare.Handler, openapi.Operation{
+			Method: http.MethodDelete, Path: "/notes/{noteId}/shares/{userId}", ID: "revokeShare",
+			Summary: "Stop sharing a note with a user",
+			Status:  http.StatusOK,
+		}},
+		{createsharelink.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/notes/{noteId}/share-link", ID: "createShareLink",
+			Summary:  "Create a public link to a note. Its token is only returned here, links are listed and revoked by their ID",
+			Request:  models.ShareLinkRequest{},
+			Response: models.ShareLink{}, Status: http.StatusCreated,
+		}},
+		{getsharelinks.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/share-links", ID: "getShareLinks",
+			Summary: "List share links",
+			Query: []openapi.Parameter{
+				{Name: "noteId", Description: "Only the links of a note"},
+			},
+			Response: []models.ShareLink{}, Status: http.StatusOK,
+		}},
+		{revokesharelink.Handler, openapi.Operation{
+			Method: http.MethodDelete, Path: "/share-links/{linkId}", ID: "revokeShareLink",
+			Summary: "Revoke a share link",
+			Status:  http.StatusOK,
+		}},
+		{getpublicnote.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/public/{token}", ID: "getPublicNote",
+			Summary: "Get a note through a share link, the password of a protected link goes in X-Share-Password. Five wrong passwords in a row lock the link for 15 minutes",
+			Public:  true,
+			Query: []openapi.Parameter{
+				{Name: "format", Enum: []string{"html", "json"}, Description: "Answer with an HTML page or JSON, by the Accept header by default"},
+			},
+			Response: models.PublicNote{}, HTML: true, Status: http.StatusOK,
+		}},
+		{addchecklistitem.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/notes/{noteId}/items", ID: "addChecklistItem",
+			Summary:  "Add an item to a checklist",
+			Request:  models.ChecklistItem{},
+			Response: models.Note{}, Status: http.StatusCreated,
+		}},
+		{reorderchecklistitems.Handler, openapi.Operation{
+			Method: http.MethodPut, Path: "/notes/{noteId}/items/order", ID: "reorderChecklistItems",
+			Summary:  "Reorder the items of a checklist",
+			Request:  models.ChecklistOrder{},
+			Response: models.Note{}, Status: http.StatusOK,
+		}},
+		{updatechecklistitem.Handler, openapi.Operation{
+			Method: http.MethodPut, Path: "/notes/{noteId}/items/{itemId}", ID: "updateChecklistItem",
+			Summary:  "Update or check a checklist item",
+			Request:  models.ChecklistItemUpdate{},
+			Response: models.Note{}, Status: http.StatusOK,
+		}},
+		{removechecklistitem.Handler, openapi.Operation{
+			Method: http.MethodDelete, Path: "/notes/{noteId}/items/{itemId}", ID: "removeChecklistItem",
+			Summary:  "Remove a checklist item",
+			Response: models.Note{}, Status: http.StatusOK,
+		}},
+		{createwebhook.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/webhooks", ID: "createWebhook",
+			Summary:  "Register a webhook, the response holds its signing secret",
+			Request:  models.WebhookRequest{},
+			Response: models.Webhook{}, Status: http.StatusCreated,
+		}},
+		{getwebhooks.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/webhooks", ID: "getWebhooks",
+			Summary:  "List webhooks",
+			Response: []models.Webhook{}, Status: http.StatusOK,
+		}},
+		{updatewebhook.Handler, openapi.Operation{
+			Method: http.MethodPut, Path: "/webhooks/{webhookId}", ID: "updateWebhook",
+			Summary:  "Update or re-enable a webhook",
+			Request:  models.WebhookUpdate{},
+			Response: models.Webhook{}, Status: http.StatusOK,
+		}},
+		{deletewebhook.Handler, openapi.Operation{
+			Method: http.MethodDelete, Path: "/webhooks/{webhookId}", ID: "deleteWebhook",
+			Summary: "Delete a webhook",
+			Status:  http.StatusOK,
+		}},
+		{getwebhookdeliveries.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/webhooks/{webhookId}/deliveries", ID: "getWebhookDeliveries",
+			Summary: "List the latest deliveries of a webhook",
+			Query: []openapi.Parameter{
+				{Name: "limit", Type: "integer", Description: "Deliveries to return, 1 to 100, 50 by default"},
+			},
+			Response: []models.WebhookDelivery{}, Status: http.StatusOK,
+		}},
+		{pullchanges.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/sync", ID: "pullChanges",
+			Summary: "Pull the changes since a sync token",
+			Query: []openapi.Parameter{
+				{Name: "since", Description: "Sync token of the last pull, every note is returned without one"},
+			},
+			Response: models.SyncChanges{}, Status: http.StatusOK,
+		}},
+		{pushchanges.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/sync", ID: "pushChanges",
+			Summary:  "Push a batch of changes, each with its own result",
+			Request:  models.SyncPush{},
+			Response: []models.SyncResult{}, Status: http.StatusOK,
+		}},
+		{createattachment.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/notes/{noteId}/attachments", ID: "createAttachment",
+			Summary:  "Attach a file to a note, the response holds the request uploading it",
+			Request:  models.AttachmentRequest{},
+			Response: models.AttachmentUpload{}, Status: http.StatusCreated,
+		}},
+		{getattachments.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/notes/{noteId}/attachments", ID: "getAttachments",
+			Summary:  "List the attachments of a note with their download requests",
+			Response: []models.Attachment{}, Status: http.StatusOK,
+		}},
+		{deleteattachment.Handler, openapi.Operation{
+			Method: http.MethodDelete, Path: "/notes/{noteId}/attachments/{attachmentId}", ID: "deleteAttachment",
+			Summary: "Delete an attachment",
+			Status:  http.StatusOK,
+		}},
+		{createkey.Handler, openapi.Operation{
+			Method: http.MethodPost, Path: "/keys", ID: "createKey",
+			Summary:  "Store a wrapped end-to-end encryption key",
+			Request:  models.EncryptionKey{},
+			Response: models.EncryptionKey{}, Status: http.StatusCreated,
+		}},
+		{getkeys.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/keys", ID: "getKeys",
+			Summary:  "List wrapped encryption keys",
+			Response: []models.EncryptionKey{}, Status: http.StatusOK,
+		}},
+		{updatekey.Handler, openapi.Operation{
+			Method: http.MethodPut, Path: "/keys/{keyId}", ID: "updateKey",
+			Summary: "Rewrap an encryption key after a passphrase change",
+			Request: models.EncryptionKey{},
+			Status:  http.StatusOK,
+		}},
+		{getopenapi.Handler, openapi.Operation{
+			Method: http.MethodGet, Path: "/openapi.json", ID: "getOpenAPI",
+			Summary:  "Get this document",
+			Public:   true,
+			Response: map[string]interface{}{}, Bare: true, Status: http.StatusOK,
+		}},
+	}
+}
+
+// Routes lists every route with its handler, for the router
+func Routes() []router.Route {
+	table := Table()
+	routes := make([]router.Route, len(table))
+	for i, route := range table {
+		routes[i] = router.Route{Method: route.Method, Resource: route.Path, Handler: route.Handler}
+	}
+	return routes
+}
+
+// Spec returns the OpenAPI document of the route table
+func Spec() *openapi.Document {
+	table := Table()
+	operations := make([]openapi.Operation, len(table))
+	for i, route := range table {
+		operations[i] = route.Operation
+	}
+	return openapi.New(openapi.Info{
+		Title:       "MiNo API",
+		Version:     "1.0.0",
+		Description: "REST API of MiNo, the serverless note-taking app. Responses are APIResponse envelopes with the result in data, errors carry a code and, for rejected requests, every invalid field.",
+	}, operations)
+}
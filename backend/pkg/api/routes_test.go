@@ -0,0 +1,241 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the OpenAPI document served by getopenapi")
+
+const specFile = "../handlers/getopenapi/openapi.json"
+
+// TestSpec fails when the served document is not the one the route table
+// generates. Run go test ./pkg/api -update to regenerate it.
+func TestSpec(t *testing.T) {
+	spec, err := json.MarshalIndent(Spec(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec = append(spec, '\n')
+
+	if *update {
+		if err := os.WriteFile(specFile, spec, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	served, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(served, spec) {
+		t.Errorf("%s is out of date, run go test ./pkg/api -update", specFile)
+	}
+}
+
+// handlerFacts is what a handler's source says about the requests it takes
+// and the responses it makes
+type handlerFacts struct {
+	pkg      string
+	body     string          // Type the body is decoded into
+	statuses map[int]bool    // Successful status codes
+	query    map[string]bool // Query string parameters read
+}
+
+// TestHandlersMatchSpec checks every route's description against the source
+// of its handler
+func TestHandlersMatchSpec(t *testing.T) {
+	ids := make(map[string]bool)
+	for _, route := range Table() {
+		name := route.Method + " " + route.Path
+		facts := inspect(t, route)
+
+		if strings.ToLower(route.ID) != facts.pkg || ids[route.ID] {
+			t.Errorf("%s: operation ID %q does not name handler %s or is taken", name, route.ID, facts.pkg)
+		}
+		ids[route.ID] = true
+
+		if facts.body != "" {
+			if route.Request == nil {
+				t.Errorf("%s: handler decodes a %s body, none documented", name, facts.body)
+			} else if documented := reflect.TypeOf(route.Request).String(); documented != facts.body {
+				t.Errorf("%s: handler decodes a %s body, %s documented", name, facts.body, documented)
+			}
+		}
+
+		if len(facts.statuses) != 1 || !facts.statuses[route.Status] {
+			t.Errorf("%s: handler succeeds with %v, %d documented", name, facts.statuses, route.Status)
+		}
+
+		documented := make(map[string]bool)
+		for _, param := range route.Query {
+			documented[param.Name] = true
+		}
+		if !reflect.DeepEqual(documented, facts.query) {
+			t.Errorf("%s: handler reads query parameters %v, %v documented", name, facts.query, documented)
+		}
+	}
+}
+
+// inspect parses the source of a route's handler package
+func inspect(t *testing.T, route Route) handlerFacts {
+	t.Helper()
+
+	// github.com/omidiyanto/mino/pkg/handlers/auth.Handler
+	function := runtime.FuncForPC(reflect.ValueOf(route.Handler).Pointer()).Name()
+	importPath := strings.TrimSuffix(function, ".Handler")
+	dir := filepath.Join("..", "handlers", filepath.Base(importPath))
+	facts := handlerFacts{
+		pkg:      filepath.Base(importPath),
+		statuses: make(map[int]bool),
+		query:    make(map[string]bool),
+	}
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil || len(pkgs) != 1 {
+		t.Fatalf("%s: parsing %s: %v", route.ID, dir, err)
+	}
+
+	for _, pkg := range pkgs {
+		vars := make(map[string]string) // Declared type by variable name
+		var decoded []string
+		ast.Inspect(pkg, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.ValueSpec:
+				if n.Type != nil {
+					for _, name := range n.Names {
+						vars[name.Name] = typeString(n.Type, facts.pkg)
+					}
+				}
+			case *ast.CallExpr:
+				if isBodyDecode(n) {
+					if unary, ok := n.Args[len(n.Args)-1].(*ast.UnaryExpr); ok {
+						if ident, ok := unary.X.(*ast.Ident); ok {
+							decoded = append(decoded, ident.Name)
+						}
+					}
+				}
+			case *ast.KeyValueExpr:
+				if key, ok := n.Key.(*ast.Ident); ok && key.Name == "StatusCode" {
+					if lit, ok := n.Value.(*ast.BasicLit); ok && lit.Kind == token.INT {
+						if status, _ := strconv.Atoi(lit.Value); status >= 200 && status < 300 {
+							facts.statuses[status] = true
+						}
+					}
+				}
+			case *ast.IndexExpr:
+				if sel, ok := n.X.(*ast.SelectorExpr); ok && sel.Sel.Name == "QueryStringParameters" {
+					if lit, ok := n.Index.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						name, _ := strconv.Unquote(lit.Value)
+						facts.query[name] = true
+					}
+				}
+			}
+			return true
+		})
+		for _, name := range decoded {
+			facts.body = vars[name]
+		}
+	}
+	return facts
+}
+
+// isBodyDecode reports whether a call decodes the request body, with
+// validate.Decode or json.Unmarshal
+func isBodyDecode(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || len(call.Args) != 2 {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || !(pkg.Name == "validate" && sel.Sel.Name == "Decode" || pkg.Name == "json" && sel.Sel.Name == "Unmarshal") {
+		return false
+	}
+	found := false
+	ast.Inspect(call.Args[0], func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok && sel.Sel.Name == "Body" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// typeString names a declared type as reflect does, qualifying the types of
+// the handler package with its name
+func typeString(expr ast.Expr, pkg string) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return pkg + "." + e.Name
+	case *ast.SelectorExpr:
+		return e.X.(*ast.Ident).Name + "." + e.Sel.Name
+	case *ast.ArrayType:
+		return "[]" + typeString(e.Elt, pkg)
+	case *ast.StarExpr:
+		return "*" + typeString(e.X, pkg)
+	}
+	return ""
+}
+
+var (
+	gatewayResource = regexp.MustCompile(`(?s)resource "aws_api_gateway_resource" "(\w+)" \{.*?parent_id\s*=\s*(\S+).*?path_part\s*=\s*"([^"]+)"`)
+	gatewayMethod   = regexp.MustCompile(`(?s)resource "aws_api_gateway_method" "\w+" \{.*?resource_id\s*=\s*aws_api_gateway_resource\.(\w+)\.id.*?http_method\s*=\s*"(\w+)"`)
+	gatewayParent   = regexp.MustCompile(`^aws_api_gateway_resource\.(\w+)\.id$`)
+)
+
+// TestGatewayRoutes checks that API Gateway defines the routes of the table,
+// and only those
+func TestGatewayRoutes(t *testing.T) {
+	terraform, err := os.ReadFile("../../../infrastructure/modules/apigateway/main.tf")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	parents := make(map[string]string)
+	parts := make(map[string]string)
+	for _, match := range gatewayResource.FindAllStringSubmatch(string(terraform), -1) {
+		parts[match[1]] = match[3]
+		if parent := gatewayParent.FindStringSubmatch(match[2]); parent != nil {
+			parents[match[1]] = parent[1]
+		}
+	}
+	resourcePath := func(name string) string {
+		path := ""
+		for ; name != ""; name = parents[name] {
+			path = "/" + parts[name] + path
+		}
+		return path
+	}
+
+	var gateway []string
+	for _, match := range gatewayMethod.FindAllStringSubmatch(string(terraform), -1) {
+		if match[2] != "OPTIONS" {
+			gateway = append(gateway, match[2]+" "+resourcePath(match[1]))
+		}
+	}
+	var table []string
+	for _, route := range Table() {
+		table = append(table, route.Method+" "+route.Path)
+	}
+
+	sort.Strings(gateway)
+	sort.Strings(table)
+	if !reflect.DeepEqual(gateway, table) {
+		t.Errorf("API Gateway routes %v\ndo not match the table %v", gateway, table)
+	}
+}
@@ -0,0 +1,469 @@
+// Package client is a Go client of the MiNo REST API, for services that call
+// it rather than share its database.
+//
+// A Client logs in once and keeps the token fresh: it logs in again with the
+// same credentials shortly before the token expires, and once more when a
+// request is answered 401. Requests answered 429 are retried with
+// exponential backoff, honouring Retry-After, as are 5xx answers and network
+// errors of idempotent requests. Every call stops when its context is done.
+// Error responses are returned as *Error, which errors.Is matches against
+// ErrNotFound, ErrQuotaExceeded and the other kinds.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Defaults of the options
+const (
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff = 10 * time.Second
+	DefaultPageSize   = 100
+)
+
+// refreshMargin is how long before its expiry a token is replaced
+const refreshMargin = time.Minute
+
+// Client calls the MiNo API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	refreshMu   sync.Mutex // Held while logging in again, so that one request does it
+	mu          sync.Mutex // Guards the fields below
+	token       string
+	expiresAt   time.Time // Zero when unknown
+	credentials *models.UserCredentials
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are made with,
+// http.DefaultClient by default
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken sets a token obtained elsewhere. Without credentials it cannot
+// be refreshed.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.setToken(token)
+	}
+}
+
+// WithCredentials sets the credentials the client logs in with on its first
+// request and whenever its token expires
+func WithCredentials(email, password string) Option {
+	return func(c *Client) {
+		c.credentials = &models.UserCredentials{Email: email, Password: password}
+	}
+}
+
+// WithRetries sets how many times a request is retried and the bounds of the
+// delay between attempts, which doubles from min up to max. Zero retries
+// disables them.
+func WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// New returns a client of the API at baseURL, the API Gateway stage URL
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: DefaultMaxRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Token returns the current token, empty before logging in
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// Login logs in and keeps the credentials to log in again when the token
+// expires
+func (c *Client) Login(ctx context.Context, email, password string) (*models.AuthResponse, error) {
+	credentials := &models.UserCredentials{Email: email, Password: password}
+	auth, err := c.login(ctx, credentials)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.credentials = credentials
+	c.mu.Unlock()
+	return auth, nil
+}
+
+// Register creates an account. It does not log in.
+func (c *Client) Register(ctx context.Context, email, password string) (*models.User, error) {
+	var user models.User
+	registration := models.UserRegistration{Email: email, Password: password}
+	if _, err := c.call(ctx, http.MethodPost, "/register", nil, registration, false, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListOptions filters the notes listed
+type ListOptions struct {
+	NotebookID string // Only the notes of a notebook
+	Archived   bool   // Include archived notes
+	Favorite   bool   // Only favorite notes
+	PageSize   int    // Notes per request, DefaultPageSize when zero
+}
+
+// ListNotes returns every note matching the options, pinned first, fetching
+// as many pages as needed
+func (c *Client) ListNotes(ctx context.Context, options ListOptions) ([]models.Note, error) {
+	var notes []models.Note
+	cursor := ""
+	for {
+		page, next, err := c.ListNotesPage(ctx, options, cursor)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, page...)
+		if next == "" {
+			return notes, nil
+		}
+		cursor = next
+	}
+}
+
+// ListNotesPage returns one page of notes, starting after a cursor, and the
+// cursor of the next page, empty on the last one
+func (c *Client) ListNotesPage(ctx context.Context, options ListOptions, cursor string) ([]models.Note, string, error) {
+	pageSize := options.PageSize
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	query := url.Values{"limit": {strconv.Itoa(pageSize)}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if options.NotebookID != "" {
+		query.Set("notebookId", options.NotebookID)
+	}
+	if options.Archived {
+		query.Set("archived", "true")
+	}
+	if options.Favorite {
+		query.Set("favorite", "true")
+	}
+
+	var notes []models.Note
+	response, err := c.call(ctx, http.MethodGet, "/notes", query, nil, true, &notes)
+	if err != nil {
+		return nil, "", err
+	}
+	return notes, response.NextCursor, nil
+}
+
+// SearchNotes returns the notes matching a query, best first, at most limit
+// of them or as many as the server returns by default when zero
+func (c *Client) SearchNotes(ctx context.Context, query string, limit int) ([]models.SearchResult, error) {
+	params := url.Values{"q": {query}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	var results []models.SearchResult
+	if _, err := c.call(ctx, http.MethodGet, "/notes/search", params, nil, true, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetNote returns a note
+func (c *Client) GetNote(ctx context.Context, noteID string) (*models.Note, error) {
+	var note models.Note
+	if _, err := c.call(ctx, http.MethodGet, "/notes/"+url.PathEscape(noteID), nil, nil, true, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// CreateNote creates a note and returns it as stored
+func (c *Client) CreateNote(ctx context.Context, note models.Note) (*models.Note, error) {
+	var created models.Note
+	if _, err := c.call(ctx, http.MethodPost, "/notes", nil, note, true, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateNote replaces the note of note.NoteID and returns it as stored
+func (c *Client) UpdateNote(ctx context.Context, note models.Note) (*models.Note, error) {
+	if note.NoteID == "" {
+		return nil, errors.New("mino: updating a note without an ID")
+	}
+	var updated models.Note
+	path := "/notes/" + url.PathEscape(note.NoteID)
+	if _, err := c.call(ctx, http.MethodPut, path, nil, note, true, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteNote deletes a note
+func (c *Client) DeleteNote(ctx context.Context, noteID string) error {
+	_, err := c.call(ctx, http.MethodDelete, "/notes/"+url.PathEscape(noteID), nil, nil, true, nil)
+	return err
+}
+
+// login exchanges credentials for a token and sets it
+func (c *Client) login(ctx context.Context, credentials *models.UserCredentials) (*models.AuthResponse, error) {
+	var auth models.AuthResponse
+	if _, err := c.call(ctx, http.MethodPost, "/auth", nil, credentials, false, &auth); err != nil {
+		return nil, err
+	}
+	c.setToken(auth.Token)
+	return &auth, nil
+}
+
+// setToken sets the token and reads its expiry
+func (c *Client) setToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.expiresAt = expiry(token)
+}
+
+// authorize returns a token for a request, logging in first when there is
+// none or it is about to expire. The stale token, one the server rejected, is
+// replaced even if it has not expired.
+func (c *Client) authorize(ctx context.Context, stale string) (string, error) {
+	token, usable, credentials := c.current(stale)
+	if usable || credentials == nil {
+		return token, nil
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	// Another request may have logged in while this one waited
+	if token, usable, _ = c.current(stale); usable {
+		return token, nil
+	}
+
+	if _, err := c.login(ctx, credentials); err != nil {
+		return "", err
+	}
+	return c.Token(), nil
+}
+
+// current returns the token, whether it can be used rather than replaced,
+// and the credentials to replace it with
+func (c *Client) current(stale string) (string, bool, *models.UserCredentials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	usable := c.token != "" && c.token != stale &&
+		(c.expiresAt.IsZero() || time.Until(c.expiresAt) > refreshMargin)
+	return c.token, usable, c.credentials
+}
+
+// expiry reads the exp claim of a JWT without verifying it, the zero time
+// when there is none
+func expiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.ExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.ExpiresAt, 0)
+}
+
+// call makes a request and decodes the data of the response into out. The
+// response of /auth is the data itself rather than an APIResponse.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body interface{}, authenticated bool, out interface{}) (*models.APIResponse, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	token := ""
+	if authenticated {
+		var err error
+		if token, err = c.authorize(ctx, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	status, responseBody, err := c.send(ctx, method, path, query, payload, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token was revoked or expired early, log in again once
+	if _, _, credentials := c.current(""); status == http.StatusUnauthorized && authenticated && credentials != nil {
+		if token, err = c.authorize(ctx, token); err != nil {
+			return nil, err
+		}
+		if status, responseBody, err = c.send(ctx, method, path, query, payload, token); err != nil {
+			return nil, err
+		}
+	}
+
+	response := &models.APIResponse{Data: out}
+	if status >= 200 && status < 300 {
+		if path == "/auth" {
+			return response, decode(responseBody, out)
+		}
+		if out == nil {
+			response.Data = nil
+		}
+		return response, decode(responseBody, response)
+	}
+
+	apiErr := &Error{StatusCode: status, Message: http.StatusText(status)}
+	var failure models.APIResponse
+	if json.Unmarshal(responseBody, &failure) == nil && failure.Message != "" {
+		apiErr.Code = failure.Code
+		apiErr.Message = failure.Message
+		apiErr.Errors = failure.Errors
+	}
+	return nil, apiErr
+}
+
+// decode decodes a response body, which may be empty
+func decode(body []byte, v interface{}) error {
+	if v == nil || len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("mino: decoding response: %w", err)
+	}
+	return nil
+}
+
+// send makes a request, retrying it as the package documents, and returns
+// the last response read in full
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, payload []byte, token string) (int, []byte, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	idempotent := method != http.MethodPost && method != http.MethodPatch
+
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return 0, nil, err
+		}
+		if payload != nil {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		request.Header.Set("Accept", "application/json")
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		status, header, body, err := c.roundTrip(request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, nil, ctx.Err()
+			}
+			if !idempotent || attempt >= c.maxRetries {
+				return 0, nil, err
+			}
+		} else {
+			retry := status == http.StatusTooManyRequests || status >= 500 && idempotent
+			if !retry || attempt >= c.maxRetries {
+				return status, body, nil
+			}
+		}
+
+		if err := sleep(ctx, c.backoff(attempt, header)); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// roundTrip makes one attempt of a request
+func (c *Client) roundTrip(request *http.Request) (int, http.Header, []byte, error) {
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return response.StatusCode, response.Header, body, nil
+}
+
+// backoff returns the delay before the next attempt: the Retry-After of the
+// response if it has one, otherwise an exponential delay with full jitter
+func (c *Client) backoff(attempt int, header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds >= 0 {
+		if delay := time.Duration(seconds) * time.Second; delay < c.maxBackoff {
+			return delay
+		}
+		return c.maxBackoff
+	}
+
+	delay := c.minBackoff << attempt
+	if delay > c.maxBackoff || delay <= 0 {
+		delay = c.maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// sleep waits for a delay or until the context is done
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/validate"
+)
+
+// token returns an unsigned JWT expiring at a time, as much as the client
+// reads of one
+func token(name string, expiresAt time.Time) string {
+	payload := fmt.Sprintf(`{"sub":%q,"exp":%d}`, name, expiresAt.Unix())
+	return "e30." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + name
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, options ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	options = append([]Option{WithRetries(3, time.Millisecond, 5*time.Millisecond)}, options...)
+	return New(server.URL+"/prod/", options...)
+}
+
+func TestCodesMirrorAPI(t *testing.T) {
+	codes := map[string]string{
+		CodeValidationFailed:        validate.CodeValidationFailed,
+		CodeBodyTooLarge:            validate.CodeBodyTooLarge,
+		CodeInvalidJSON:             validate.CodeInvalidJSON,
+		CodeTitleTooLong:            db.CodeTitleTooLong,
+		CodeBodyTooLong:             db.CodeBodyTooLong,
+		CodeNoteQuotaExceeded:       db.CodeNoteQuotaExceeded,
+		CodeStorageQuotaExceeded:    db.CodeStorageQuotaExceeded,
+		CodeAttachmentQuotaExceeded: db.CodeAttachmentQuotaExceeded,
+		CodeAttachmentTooLarge:      db.CodeAttachmentTooLarge,
+		CodeWebhookQuotaExceeded:    db.CodeWebhookQuotaExceeded,
+	}
+	for mirrored, code := range codes {
+		if mirrored != code {
+			t.Errorf("client has %q for %q", mirrored, code)
+		}
+	}
+}
+
+func TestListNotesFollowsPages(t *testing.T) {
+	var requests []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		if r.URL.Path != "/prod/notes" || r.Header.Get("Authorization") != "Bearer t" {
+			t.Errorf("got %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"success":true,"message":"","data":[{"noteId":"n1"},{"noteId":"n2"}],"nextCursor":"c2"}`)
+		case "c2":
+			fmt.Fprint(w, `{"success":true,"message":"","data":[{"noteId":"n3"}]}`)
+		}
+	}, WithToken("t"))
+
+	notes, err := c.ListNotes(context.Background(), ListOptions{NotebookID: "b1", Archived: true, PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, note := range notes {
+		ids = append(ids, note.NoteID)
+	}
+	if strings.Join(ids, " ") != "n1 n2 n3" {
+		t.Errorf("got notes %v", ids)
+	}
+	want := []string{"archived=true&limit=2&notebookId=b1", "archived=true&cursor=c2&limit=2&notebookId=b1"}
+	if strings.Join(requests, " ") != strings.Join(want, " ") {
+		t.Errorf("got requests %v, want %v", requests, want)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		attempts int32
+		err      error
+	}{
+		{"server errors then success", "GET", []int{503, 500, 200}, 3, nil},
+		{"server errors throughout", "GET", []int{503, 503, 503, 503, 503}, 4, ErrServer},
+		{"rate limited create", "POST", []int{429, 201}, 2, nil},
+		{"create not retried on a server error", "POST", []int{502, 201}, 1, ErrServer},
+		{"client error not retried", "GET", []int{404, 200}, 1, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[atomic.AddInt32(&attempts, 1)-1]
+				if status == 429 {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+				if status < 300 {
+					fmt.Fprint(w, `{"success":true,"message":"","data":{"noteId":"n1","title":"Groceries"}}`)
+				} else {
+					fmt.Fprint(w, `{"success":false,"message":"Failed"}`)
+				}
+			}, WithToken("t"))
+
+			var err error
+			if tt.method == "GET" {
+				_, err = c.GetNote(context.Background(), "n1")
+			} else {
+				_, err = c.CreateNote(context.Background(), models.Note{Title: "Groceries"})
+			}
+			if attempts != tt.attempts {
+				t.Errorf("made %d attempts, want %d", attempts, tt.attempts)
+			}
+			if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	var logins int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/prod/auth":
+			// The first token is about to expire, the second is revoked
+			// server side, the third is good
+			n := atomic.AddInt32(&logins, 1)
+			expiresAt := time.Now().Add(time.Hour)
+			if n == 1 {
+				expiresAt = time.Now().Add(time.Second)
+			}
+			fmt.Fprintf(w, `{"token":%q,"user":{"userId":"u1","email":"ada@example.com"}}`, token(fmt.Sprint("t", n), expiresAt))
+		case "/prod/notes/n1":
+			if !strings.HasSuffix(r.Header.Get("Authorization"), ".t3") {
+				w.WriteHeader(401)
+				fmt.Fprint(w, `{"success":false,"message":"Invalid token"}`)
+				return
+			}
+			fmt.Fprint(w, `{"success":true,"message":"","data":{"noteId":"n1"}}`)
+		}
+	})
+
+	auth, err := c.Login(context.Background(), "ada@example.com", "correct horse")
+	if err != nil || auth.User.UserID != "u1" {
+		t.Fatalf("got %+v, %v", auth, err)
+	}
+
+	note, err := c.GetNote(context.Background(), "n1")
+	if err != nil || note.NoteID != "n1" {
+		t.Fatalf("got %+v, %v", note, err)
+	}
+	if logins != 3 {
+		t.Errorf("logged in %d times, want 3", logins)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		is     []error
+		isNot  []error
+	}{
+		{422, `{"success":false,"message":"Invalid request","code":"validation_failed","errors":[{"field":"title","rule":"required","message":"is required"}]}`,
+			[]error{ErrInvalid}, []error{ErrTooLarge, ErrNotFound}},
+		{413, `{"success":false,"message":"Title too long","code":"title_too_long"}`,
+			[]error{ErrTooLarge}, []error{ErrInvalid, ErrQuotaExceeded}},
+		{403, `{"success":false,"message":"Note limit reached","code":"note_quota_exceeded"}`,
+			[]error{ErrQuotaExceeded, ErrForbidden}, []error{ErrTooLarge}},
+		{401, `{"success":false,"message":"Invalid token"}`,
+			[]error{ErrUnauthorized}, []error{ErrForbidden}},
+		{400, `not JSON`,
+			[]error{ErrInvalid}, []error{ErrServer}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}, WithToken("t"))
+
+			_, err := c.CreateNote(context.Background(), models.Note{})
+			var apiErr *Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Fatalf("got error %v, want an *Error", err)
+			}
+			for _, kind := range tt.is {
+				if !errors.Is(err, kind) {
+					t.Errorf("%v is not %v", err, kind)
+				}
+			}
+			for _, kind := range tt.isNot {
+				if errors.Is(err, kind) {
+					t.Errorf("%v is %v", err, kind)
+				}
+			}
+		})
+	}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(422)
+		fmt.Fprint(w, tests[0].body)
+	}, WithToken("t"))
+	_, err := c.CreateNote(context.Background(), models.Note{})
+	if apiErr := (*Error)(nil); !errors.As(err, &apiErr) || len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "title" {
+		t.Errorf("got error %v, want the title field", err)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}, WithToken("t"), WithRetries(10, time.Hour, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.GetNote(ctx, "n1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want the context's", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Error codes the API returns in the code field, as declared by pkg/validate
+// and pkg/db
+const (
+	CodeValidationFailed        = "validation_failed"
+	CodeBodyTooLarge            = "body_too_large"
+	CodeInvalidJSON             = "invalid_json"
+	CodeTitleTooLong            = "title_too_long"
+	CodeBodyTooLong             = "body_too_long"
+	CodeNoteQuotaExceeded       = "note_quota_exceeded"
+	CodeStorageQuotaExceeded    = "storage_quota_exceeded"
+	CodeAttachmentQuotaExceeded = "attachment_quota_exceeded"
+	CodeAttachmentTooLarge      = "attachment_too_large"
+	CodeWebhookQuotaExceeded    = "webhook_quota_exceeded"
+)
+
+// Kinds of errors, for errors.Is. Every error the API answers with is an
+// *Error, which matches the kinds of its status and code.
+var (
+	ErrUnauthorized  = errors.New("unauthorized")    // 401, the token is missing, invalid or expired
+	ErrForbidden     = errors.New("forbidden")       // 403
+	ErrNotFound      = errors.New("not found")       // 404
+	ErrConflict      = errors.New("conflict")        // 409, such as a version mismatch
+	ErrRateLimited   = errors.New("rate limited")    // 429, after every retry
+	ErrServer        = errors.New("server error")    // 5xx, after every retry
+	ErrInvalid       = errors.New("invalid request") // Rejected body, see Error.Errors
+	ErrTooLarge      = errors.New("too large")       // A title, body or file over the plan limit
+	ErrQuotaExceeded = errors.New("quota exceeded")  // Too many notes or bytes for the plan
+)
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int
+	Code       string // Empty for errors without one
+	Message    string
+	Errors     []models.FieldError // Every invalid field of a rejected request
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("mino: %d %s", e.StatusCode, e.Message)
+	if e.Code != "" {
+		message += " (" + e.Code + ")"
+	}
+	for _, fieldErr := range e.Errors {
+		message += "; " + fieldErr.Field + " " + fieldErr.Message
+	}
+	return message
+}
+
+// Is reports whether the error is of a kind
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	case ErrInvalid:
+		return e.Code == CodeValidationFailed || e.Code == CodeInvalidJSON ||
+			e.Code == "" && e.StatusCode == http.StatusBadRequest
+	case ErrTooLarge:
+		return strings.HasSuffix(e.Code, "_too_large") || strings.HasSuffix(e.Code, "_too_long")
+	case ErrQuotaExceeded:
+		return strings.HasSuffix(e.Code, "_quota_exceeded")
+	}
+	return false
+}
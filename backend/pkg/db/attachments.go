@@ -0,0 +1,245 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// MaxAttachmentSize is the largest single file, the total is limited by plan
+const MaxAttachmentSize = 25 << 20
+
+// uploadPending is the upload value of the attachments waiting for their file
+const uploadPending = "pending"
+
+// Errors returned by the attachment functions
+var (
+	ErrAttachmentNotFound      = errors.New("attachment not found")
+	ErrAttachmentQuotaExceeded = errors.New("attachment storage quota exceeded")
+)
+
+// CreateAttachment records an attachment and charges its size to the owner's
+// storage in one transaction. It fails with ErrNoteNotFound when the note is
+// gone and with ErrAttachmentQuotaExceeded when the file does not fit the quota.
+// The attachment waits for its file until uploadDeadline, after which
+// ReconcileUpload keeps it or gives its size back.
+func CreateAttachment(attachment models.Attachment, quota int64, uploadDeadline time.Time) error {
+	attachment.Upload = uploadPending
+	attachment.UploadDeadline = uploadDeadline.UTC().Format(time.RFC3339)
+
+	item, err := attributevalue.MarshalMap(attachment)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				ConditionCheck: &types.ConditionCheck{
+					TableName: aws.String(os.Getenv("NOTES_TABLE")),
+					Key: map[string]types.AttributeValue{
+						"noteId": &types.AttributeValueMemberS{Value: attachment.NoteID},
+						"userId": &types.AttributeValueMemberS{Value: attachment.UserID},
+					},
+					ConditionExpression: aws.String("attribute_exists(noteId)"),
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName:           aws.String(os.Getenv("ATTACHMENTS_TABLE")),
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(attachmentId)"),
+				},
+			},
+			{
+				// Conditions cannot add, so the size is taken off the quota instead
+				Update: &types.Update{
+					TableName:           aws.String(os.Getenv("USERS_TABLE")),
+					Key:                 userKey(attachment.UserID),
+					UpdateExpression:    aws.String("ADD storageUsed :size"),
+					ConditionExpression: aws.String("attribute_exists(userId) AND (attribute_not_exists(storageUsed) OR storageUsed <= :remaining)"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":size":      &types.AttributeValueMemberN{Value: strconv.FormatInt(attachment.Size, 10)},
+						":remaining": &types.AttributeValueMemberN{Value: strconv.FormatInt(quota-attachment.Size, 10)},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) && len(canceled.CancellationReasons) == 3 {
+			if aws.ToString(canceled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+				return ErrNoteNotFound
+			}
+			if aws.ToString(canceled.CancellationReasons[2].Code) == "ConditionalCheckFailed" {
+				return ErrAttachmentQuotaExceeded
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetAttachments gets the attachments of the note of an owner
+func GetAttachments(noteID string, userID string) ([]models.Attachment, error) {
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("ATTACHMENTS_TABLE")),
+		KeyConditionExpression: aws.String("noteId = :noteId"),
+		FilterExpression:       aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":noteId": &types.AttributeValueMemberS{Value: noteID},
+			":userId": &types.AttributeValueMemberS{Value: userID},
+		},
+	}
+
+	attachments := []models.Attachment{}
+	paginator := dynamodb.NewQueryPaginator(dynamoClient, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var pageAttachments []models.Attachment
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageAttachments); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, pageAttachments...)
+	}
+
+	return attachments, nil
+}
+
+// GetAttachment gets an attachment of the note of an owner by ID
+func GetAttachment(noteID string, attachmentID string, userID string) (*models.Attachment, error) {
+	result, err := dynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(os.Getenv("ATTACHMENTS_TABLE")),
+		Key:       attachmentKey(noteID, attachmentID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, ErrAttachmentNotFound
+	}
+
+	var attachment models.Attachment
+	if err := attributevalue.UnmarshalMap(result.Item, &attachment); err != nil {
+		return nil, err
+	}
+	if attachment.UserID != userID {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return &attachment, nil
+}
+
+// GetExpiredUploads returns the attachments whose file was still not found
+// at their upload deadline, as of now
+func GetExpiredUploads(now time.Time) ([]models.Attachment, error) {
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("ATTACHMENTS_TABLE")),
+		IndexName:              aws.String("UploadIndex"),
+		KeyConditionExpression: aws.String("upload = :pending AND uploadDeadline <= :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: uploadPending},
+			":now":     &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+		},
+	}
+
+	var attachments []models.Attachment
+	paginator := dynamodb.NewQueryPaginator(dynamoClient, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var pageAttachments []models.Attachment
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageAttachments); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, pageAttachments...)
+	}
+
+	return attachments, nil
+}
+
+// ConfirmUpload records that the file of an attachment was uploaded, which
+// takes it off the UploadIndex
+func ConfirmUpload(attachment models.Attachment) error {
+	_, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(os.Getenv("ATTACHMENTS_TABLE")),
+		Key:                 attachmentKey(attachment.NoteID, attachment.AttachmentID),
+		UpdateExpression:    aws.String("REMOVE upload, uploadDeadline"),
+		ConditionExpression: aws.String("attribute_exists(attachmentId)"),
+	})
+	return ignoreConditionFailure(err)
+}
+
+// DeleteAttachment removes an attachment record and gives its size back to
+// the owner's storage in one transaction. It fails with ErrAttachmentNotFound
+// when the attachment was removed already, so that the size is only given
+// back once.
+func DeleteAttachment(attachment models.Attachment) error {
+	_, err := dynamoClient.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Delete: &types.Delete{
+					TableName:           aws.String(os.Getenv("ATTACHMENTS_TABLE")),
+					Key:                 attachmentKey(attachment.NoteID, attachment.AttachmentID),
+					ConditionExpression: aws.String("attribute_exists(attachmentId) AND userId = :userId"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":userId": &types.AttributeValueMemberS{Value: attachment.UserID},
+					},
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName:        aws.String(os.Getenv("USERS_TABLE")),
+					Key:              userKey(attachment.UserID),
+					UpdateExpression: aws.String("ADD storageUsed :size"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":size": &types.AttributeValueMemberN{Value: strconv.FormatInt(-attachment.Size, 10)},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) && len(canceled.CancellationReasons) > 0 &&
+			aws.ToString(canceled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+			return ErrAttachmentNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// attachmentKey builds the primary key of an attachment
+func attachmentKey(noteID string, attachmentID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"noteId":       &types.AttributeValueMemberS{Value: noteID},
+		"attachmentId": &types.AttributeValueMemberS{Value: attachmentID},
+	}
+}
+
+// userKey builds the primary key of a user
+func userKey(userID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"userId": &types.AttributeValueMemberS{Value: userID},
+	}
+}
@@ -0,0 +1,380 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/omidiyanto/mino/pkg/e2ee"
+	"github.com/omidiyanto/mino/pkg/envelope"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Note types
+const (
+	NoteTypeText      = "text"
+	NoteTypeChecklist = "checklist"
+)
+
+// Checklist limits, they keep a checklist well below the DynamoDB item size limit
+const (
+	MaxChecklistItems    = 500
+	MaxChecklistItemText = 1000
+)
+
+// Errors returned by the checklist functions
+var (
+	ErrNotChecklist          = errors.New("note is not a checklist")
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+	ErrChecklistConflict     = errors.New("checklist was changed concurrently")
+	ErrChecklistFull         = fmt.Errorf("checklist cannot hold more than %d items", MaxChecklistItems)
+	ErrInvalidItemOrder      = errors.New("itemIds must list every item of the checklist exactly once")
+)
+
+// ChecklistFailure maps an error of the checklist functions to the status
+// code and body of the response
+func ChecklistFailure(err error) (int, models.APIResponse) {
+	var limitErr *LimitError
+	switch {
+	case errors.Is(err, ErrNoteNotFound):
+		return 404, models.APIResponse{Success: false, Message: "Note not found"}
+	case errors.Is(err, ErrChecklistItemNotFound):
+		return 404, models.APIResponse{Success: false, Message: "Item not found"}
+	case errors.Is(err, ErrNotChecklist), errors.Is(err, ErrChecklistFull), errors.Is(err, ErrInvalidItemOrder):
+		return 400, models.APIResponse{Success: false, Message: err.Error()}
+	case errors.Is(err, ErrChecklistConflict):
+		return 409, models.APIResponse{Success: false, Message: "The checklist was changed by another request, reload it and try again"}
+	case errors.As(err, &limitErr):
+		return limitErr.StatusCode(), models.APIResponse{Success: false, Message: limitErr.Message, Code: limitErr.Code}
+	default:
+		return 500, models.APIResponse{Success: false, Message: "Failed to update checklist"}
+	}
+}
+
+// ValidateNoteBody checks that a note holds content, checklist items or an
+// encrypted envelope as its type requires
+func ValidateNoteBody(note models.Note) error {
+	if note.Encrypted != nil {
+		if note.Type == NoteTypeChecklist {
+			return errors.New("checklist notes cannot be encrypted")
+		}
+		if note.Content != "" {
+			return errors.New("encrypted notes hold ciphertext instead of content")
+		}
+		if err := e2ee.ValidateEnvelope(*note.Encrypted); err != nil {
+			return err
+		}
+	}
+
+	if note.Type != NoteTypeChecklist {
+		if len(note.Items) > 0 {
+			return errors.New("only checklist notes can have items")
+		}
+		return nil
+	}
+
+	if note.Content != "" {
+		return errors.New("checklist notes hold items instead of content")
+	}
+	if len(note.Items) > MaxChecklistItems {
+		return ErrChecklistFull
+	}
+	for _, item := range note.Items {
+		if err := ValidateChecklistItemText(item.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateChecklistItemText checks the text of a checklist item
+func ValidateChecklistItemText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("item text is required")
+	}
+	if len(text) > MaxChecklistItemText {
+		return fmt.Errorf("item text cannot be longer than %d characters", MaxChecklistItemText)
+	}
+	return nil
+}
+
+// prepareChecklist gives items without an ID a new one and numbers the items
+// in their current order
+func prepareChecklist(items []models.ChecklistItem) []models.ChecklistItem {
+	prepared := make([]models.ChecklistItem, len(items))
+	for i, item := range items {
+		if item.ItemID == "" {
+			item.ItemID = uuid.New().String()
+		}
+		item.Order = i
+		prepared[i] = item
+	}
+	return prepared
+}
+
+// AddChecklistItem appends an item to a checklist note. The note is the
+// current state the caller loaded, the new item is ordered after its last item.
+// The item limit is part of the write's condition, so concurrent appends cannot
+// grow a checklist past it.
+func AddChecklistItem(note models.Note, text string, checked bool) (*models.Note, error) {
+	if note.Type != NoteTypeChecklist {
+		return nil, ErrNotChecklist
+	}
+	if len(note.Items) >= MaxChecklistItems {
+		return nil, ErrChecklistFull
+	}
+
+	order := 0
+	if len(note.Items) > 0 {
+		order = note.Items[len(note.Items)-1].Order + 1
+	}
+	items, err := envelope.Default.EncryptItems(context.TODO(), note.NoteID, []models.ChecklistItem{{
+		ItemID:  uuid.New().String(),
+		Text:    text,
+		Checked: checked,
+		Order:   order,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	item, err := attributevalue.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := updateChecklist(note, int64(len(text)), "SET #items = list_append(if_not_exists(#items, :empty), :item)",
+		"(attribute_not_exists(#items) OR size(#items) < :maxItems)",
+		map[string]string{"#items": "items"},
+		map[string]types.AttributeValue{
+			":item":     item,
+			":empty":    &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+			":maxItems": &types.AttributeValueMemberN{Value: strconv.Itoa(MaxChecklistItems)},
+		})
+	if errors.Is(err, ErrChecklistConflict) {
+		// The only condition an existing checklist fails here is the item limit
+		current, getErr := GetNoteByID(note.NoteID, note.UserID)
+		if getErr == nil && len(current.Items) >= MaxChecklistItems {
+			return nil, ErrChecklistFull
+		}
+	}
+	return updated, err
+}
+
+// UpdateChecklistItem changes the text or checked state of an item in place.
+// The update only applies if the item is still at the position it had in note,
+// and a new text only if the note did not change since, so that the change in
+// size charged to the owner is exact.
+func UpdateChecklistItem(note models.Note, itemID string, update models.ChecklistItemUpdate) (*models.Note, error) {
+	position, err := itemPosition(note, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{"#items": "items", "#itemId": "itemId"}
+	values := map[string]types.AttributeValue{
+		":itemId": &types.AttributeValueMemberS{Value: itemID},
+	}
+	condition := fmt.Sprintf("#items[%d].#itemId = :itemId", position)
+
+	var sets []string
+	var growth int64
+	if update.Text != nil {
+		growth = int64(len(*update.Text) - len(note.Items[position].Text))
+		condition += " AND " + noteVersionCondition(note, values)
+		text, err := envelope.Default.EncryptItemText(context.TODO(), note.NoteID, itemID, *update.Text)
+		if err != nil {
+			return nil, err
+		}
+		names["#text"] = "text"
+		values[":text"] = &types.AttributeValueMemberS{Value: text}
+		sets = append(sets, fmt.Sprintf("#items[%d].#text = :text", position))
+	}
+	if update.Checked != nil {
+		names["#checked"] = "checked"
+		values[":checked"] = &types.AttributeValueMemberBOOL{Value: *update.Checked}
+		sets = append(sets, fmt.Sprintf("#items[%d].#checked = :checked", position))
+	}
+	if len(sets) == 0 {
+		return &note, nil
+	}
+
+	return updateChecklist(note, growth, "SET "+strings.Join(sets, ", "), condition, names, values)
+}
+
+// RemoveChecklistItem removes an item from a checklist note. The removal only
+// applies if the note did not change since it was loaded.
+func RemoveChecklistItem(note models.Note, itemID string) (*models.Note, error) {
+	position, err := itemPosition(note, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]types.AttributeValue{
+		":itemId": &types.AttributeValueMemberS{Value: itemID},
+	}
+	condition := fmt.Sprintf("#items[%d].#itemId = :itemId AND %s", position, noteVersionCondition(note, values))
+	return updateChecklist(note, -int64(len(note.Items[position].Text)), fmt.Sprintf("REMOVE #items[%d]", position),
+		condition, map[string]string{"#items": "items", "#itemId": "itemId"}, values)
+}
+
+// ReorderChecklistItems rewrites the item list in the order of itemIDs, which
+// must name every item of the checklist exactly once. The write only applies
+// if the note was not changed since it was loaded.
+func ReorderChecklistItems(note models.Note, itemIDs []string) (*models.Note, error) {
+	if note.Type != NoteTypeChecklist {
+		return nil, ErrNotChecklist
+	}
+
+	byID := make(map[string]models.ChecklistItem, len(note.Items))
+	for _, item := range note.Items {
+		byID[item.ItemID] = item
+	}
+	if len(itemIDs) != len(byID) {
+		return nil, ErrInvalidItemOrder
+	}
+
+	reordered := make([]models.ChecklistItem, 0, len(itemIDs))
+	for _, itemID := range itemIDs {
+		item, ok := byID[itemID]
+		if !ok {
+			return nil, ErrInvalidItemOrder
+		}
+		delete(byID, itemID)
+		reordered = append(reordered, item)
+	}
+
+	encrypted, err := envelope.Default.EncryptItems(context.TODO(), note.NoteID, prepareChecklist(reordered))
+	if err != nil {
+		return nil, err
+	}
+	items, err := attributevalue.Marshal(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return updateChecklist(note, 0, "SET #items = :items", "#updatedAt = :loadedAt",
+		map[string]string{"#items": "items"},
+		map[string]types.AttributeValue{
+			":items":    items,
+			":loadedAt": &types.AttributeValueMemberS{Value: note.UpdatedAt},
+		})
+}
+
+// itemPosition returns the list index of an item of a checklist note
+func itemPosition(note models.Note, itemID string) (int, error) {
+	if note.Type != NoteTypeChecklist {
+		return 0, ErrNotChecklist
+	}
+	for i, item := range note.Items {
+		if item.ItemID == itemID {
+			return i, nil
+		}
+	}
+	return 0, ErrChecklistItemNotFound
+}
+
+// noteVersionCondition is the condition that a note is still at the version
+// it was loaded at, its values are added to values
+func noteVersionCondition(note models.Note, values map[string]types.AttributeValue) string {
+	condition, versionValues := versionCondition(note.Version)
+	for placeholder, value := range versionValues {
+		values[placeholder] = value
+	}
+	return *condition
+}
+
+// updateChecklist runs a single UpdateItem on a checklist note. The update is
+// conditional on the note still existing as a checklist and on condition. An
+// update that changes the size of the note by growth is made in one
+// transaction with the change to the owner's usage, and fails with a
+// LimitError when the note grows past the owner's quota.
+func updateChecklist(note models.Note, growth int64, updateExpression string, condition string, names map[string]string, values map[string]types.AttributeValue) (*models.Note, error) {
+	notesTable := os.Getenv("NOTES_TABLE")
+
+	names["#noteId"] = "noteId"
+	names["#type"] = "type"
+	names["#updatedAt"] = "updatedAt"
+	names["#version"] = "version"
+	values[":checklist"] = &types.AttributeValueMemberS{Value: NoteTypeChecklist}
+	values[":updatedAt"] = &types.AttributeValueMemberS{Value: models.GetTimeNow()}
+	values[":one"] = &types.AttributeValueMemberN{Value: "1"}
+
+	// Every change bumps updatedAt and the version
+	if strings.HasPrefix(updateExpression, "SET ") {
+		updateExpression = "SET #updatedAt = :updatedAt, " + strings.TrimPrefix(updateExpression, "SET ")
+	} else {
+		updateExpression = "SET #updatedAt = :updatedAt " + updateExpression
+	}
+	updateExpression += " ADD #version :one"
+
+	conditions := []string{"attribute_exists(#noteId)", "#type = :checklist"}
+	if condition != "" {
+		conditions = append(conditions, condition)
+	}
+
+	key := map[string]types.AttributeValue{
+		"noteId": &types.AttributeValueMemberS{Value: note.NoteID},
+		"userId": &types.AttributeValueMemberS{Value: note.UserID},
+	}
+
+	if growth != 0 {
+		err := writeNote(types.TransactWriteItem{
+			Update: &types.Update{
+				TableName:                 aws.String(notesTable),
+				Key:                       key,
+				UpdateExpression:          aws.String(updateExpression),
+				ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			},
+		}, note.UserID, 0, growth)
+		if errors.Is(err, errNoteCondition) {
+			return nil, checklistConflict(note)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return getNote(note.NoteID, note.UserID, true)
+	}
+
+	result, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(notesTable),
+		Key:                       key,
+		UpdateExpression:          aws.String(updateExpression),
+		ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ReturnValues:              types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionErr) {
+			return nil, err
+		}
+		return nil, checklistConflict(note)
+	}
+
+	var updated models.Note
+	if err := unmarshalNote(result.Attributes, &updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// checklistConflict tells a deleted note apart from a concurrent change, after
+// the condition of a checklist update failed
+func checklistConflict(note models.Note) error {
+	if _, err := GetNoteByID(note.NoteID, note.UserID); err != nil {
+		return ErrNoteNotFound
+	}
+	return ErrChecklistConflict
+}
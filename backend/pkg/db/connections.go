@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// SaveConnection stores a live WebSocket connection of a user
+func SaveConnection(connection models.Connection) error {
+	item, err := attributevalue.MarshalMap(connection)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(os.Getenv("CONNECTIONS_TABLE")),
+		Item:      item,
+	})
+
+	return err
+}
+
+// GetConnectionsByUserID gets the live WebSocket connections of a user
+func GetConnectionsByUserID(userID string) ([]models.Connection, error) {
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("CONNECTIONS_TABLE")),
+		KeyConditionExpression: aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userID},
+		},
+	}
+
+	var connections []models.Connection
+	paginator := dynamodb.NewQueryPaginator(dynamoClient, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var pageConnections []models.Connection
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageConnections); err != nil {
+			return nil, err
+		}
+		connections = append(connections, pageConnections...)
+	}
+
+	return connections, nil
+}
+
+// DeleteConnection removes a connection of a user
+func DeleteConnection(userID string, connectionID string) error {
+	_, err := dynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: aws.String(os.Getenv("CONNECTIONS_TABLE")),
+		Key: map[string]types.AttributeValue{
+			"userId":       &types.AttributeValueMemberS{Value: userID},
+			"connectionId": &types.AttributeValueMemberS{Value: connectionID},
+		},
+	})
+
+	return err
+}
+
+// DeleteConnectionByID removes a connection when only its ID is known, as on
+// a disconnect. Removing an unknown connection is not an error.
+func DeleteConnectionByID(connectionID string) error {
+	result, err := dynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("CONNECTIONS_TABLE")),
+		IndexName:              aws.String("ConnectionIdIndex"),
+		KeyConditionExpression: aws.String("connectionId = :connectionId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":connectionId": &types.AttributeValueMemberS{Value: connectionID},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var connections []models.Connection
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &connections); err != nil {
+		return err
+	}
+
+	for _, connection := range connections {
+		if err := DeleteConnection(connection.UserID, connection.ConnectionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/omidiyanto/mino/pkg/stream"
+)
+
+// SaveDeadLetter stores a stream record a projector failed to process
+func SaveDeadLetter(letter stream.DeadLetter) error {
+	deadLettersTable := os.Getenv("DEAD_LETTERS_TABLE")
+
+	item, err := attributevalue.MarshalMap(letter)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(deadLettersTable),
+		Item:      item,
+	})
+
+	return err
+}
@@ -2,9 +2,11 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -160,15 +162,19 @@ func GetNotesByUserID(userID string) ([]models.Note, error) {
 		ScanIndexForward: aws.Bool(false), // Descending order by sort key (createdAt)
 	}
 
-	result, err := dynamoClient.Query(context.TODO(), params)
-	if err != nil {
-		return nil, err
-	}
-
 	var notes []models.Note
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &notes)
-	if err != nil {
-		return nil, err
+	paginator := dynamodb.NewQueryPaginator(dynamoClient, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var pageNotes []models.Note
+		if err := unmarshalNotes(page.Items, &pageNotes); err != nil {
+			return nil, err
+		}
+		notes = append(notes, pageNotes...)
 	}
 
 	return notes, nil
@@ -176,6 +182,12 @@ func GetNotesByUserID(userID string) ([]models.Note, error) {
 
 // GetNoteByID gets a note by its ID and userID
 func GetNoteByID(noteID string, userID string) (*models.Note, error) {
+	return getNote(noteID, userID, false)
+}
+
+// getNote gets a note, with a strongly consistent read to see a write that
+// just succeeded
+func getNote(noteID string, userID string, consistent bool) (*models.Note, error) {
 	notesTable := os.Getenv("NOTES_TABLE")
 
 	params := &dynamodb.GetItemInput{
@@ -184,6 +196,7 @@ func GetNoteByID(noteID string, userID string) (*models.Note, error) {
 			"noteId": &types.AttributeValueMemberS{Value: noteID},
 			"userId": &types.AttributeValueMemberS{Value: userID},
 		},
+		ConsistentRead: aws.Bool(consistent),
 	}
 
 	result, err := dynamoClient.GetItem(context.TODO(), params)
@@ -192,11 +205,11 @@ func GetNoteByID(noteID string, userID string) (*models.Note, error) {
 	}
 
 	if result.Item == nil {
-		return nil, fmt.Errorf("note not found")
+		return nil, ErrNoteNotFound
 	}
 
 	var note models.Note
-	err = attributevalue.UnmarshalMap(result.Item, &note)
+	err = unmarshalNote(result.Item, &note)
 	if err != nil {
 		return nil, err
 	}
@@ -204,65 +217,205 @@ func GetNoteByID(noteID string, userID string) (*models.Note, error) {
 	return &note, nil
 }
 
-// CreateNote creates a new note
+// CreateNote creates a new note and counts it towards the owner's quotas. It
+// fails with a LimitError when the owner is out of quota.
 func CreateNote(note models.Note) error {
 	notesTable := os.Getenv("NOTES_TABLE")
 
 	note.NoteID = uuid.New().String()
 	note.CreatedAt = models.GetTimeNow()
 	note.UpdatedAt = note.CreatedAt
+	note.Version = 1
+	if note.Type == NoteTypeChecklist {
+		note.Items = prepareChecklist(note.Items)
+	}
 
-	item, err := attributevalue.MarshalMap(note)
+	item, err := marshalNote(note)
 	if err != nil {
 		return err
 	}
 
-	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(notesTable),
-		Item:      item,
-	})
-
-	return err
+	return writeNote(types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(notesTable),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(noteId)"),
+		},
+	}, note.UserID, 1, NoteSize(note))
 }
 
-// UpdateNote updates an existing note
+// UpdateNote updates an existing note. It fails with a LimitError when the
+// note grows past the owner's quota.
 func UpdateNote(note models.Note) error {
+	for attempt := 1; ; attempt++ {
+		_, err := updateNote(note, nil)
+		if errors.Is(err, ErrVersionConflict) && attempt < maxWriteAttempts {
+			continue // Changed meanwhile, the change in size is computed again
+		}
+		return err
+	}
+}
+
+// UpdateNoteAtVersion updates a note only if it is still at version. It
+// fails with ErrVersionConflict when the note changed since.
+func UpdateNoteAtVersion(note models.Note, version int64) (*models.Note, error) {
+	return updateNote(note, &version)
+}
+
+// updateNote replaces the editable fields of a note, optionally conditional
+// on the version of the stored note. The write is conditional on the version
+// it was read at either way, so that the change in size charged to the owner
+// is exact.
+func updateNote(note models.Note, version *int64) (*models.Note, error) {
 	notesTable := os.Getenv("NOTES_TABLE")
 
 	// Check if note exists
 	existingNote, err := GetNoteByID(note.NoteID, note.UserID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if version != nil && existingNote.Version != *version {
+		return nil, ErrVersionConflict
 	}
 
-	// Update note fields
+	// Update note fields, notebook and state change through MoveNote and UpdateNoteFlags only
+	note.NotebookID = existingNote.NotebookID
+	note.Pinned = existingNote.Pinned
+	note.Archived = existingNote.Archived
+	note.Favorite = existingNote.Favorite
 	note.CreatedAt = existingNote.CreatedAt
 	note.UpdatedAt = models.GetTimeNow()
+	note.Version = existingNote.Version + 1
+
+	// Clients unaware of formats, types and reminders keep what the note has
+	if note.Format == "" {
+		note.Format = existingNote.Format
+	}
+	if note.Type == "" {
+		note.Type = existingNote.Type
+	}
+	if note.RemindAt == "" {
+		note.RemindAt = existingNote.RemindAt
+	}
+
+	// Checklist items are kept unless the update replaces them
+	if note.Type == NoteTypeChecklist {
+		if note.Items == nil {
+			note.Items = existingNote.Items
+		}
+		note.Items = prepareChecklist(note.Items)
+	}
 
-	item, err := attributevalue.MarshalMap(note)
+	item, err := marshalNote(note)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+	put := &types.Put{
 		TableName: aws.String(notesTable),
 		Item:      item,
-	})
+	}
+	put.ConditionExpression, put.ExpressionAttributeValues = versionCondition(existingNote.Version)
+
+	err = writeNote(types.TransactWriteItem{Put: put}, note.UserID, 0, NoteSize(note)-NoteSize(*existingNote))
+	if errors.Is(err, errNoteCondition) {
+		return nil, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	return &note, nil
 }
 
-// DeleteNote deletes a note
+// DeleteNote deletes a note and gives its size back to the owner's quotas,
+// deleting a note that is gone already succeeds
 func DeleteNote(noteID string, userID string) error {
+	for attempt := 1; ; attempt++ {
+		note, err := GetNoteByID(noteID, userID)
+		if errors.Is(err, ErrNoteNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = deleteNote(*note)
+		if errors.Is(err, errNoteCondition) && attempt < maxWriteAttempts {
+			continue // Changed meanwhile, its size is read again
+		}
+		if errors.Is(err, errNoteCondition) {
+			return ErrVersionConflict
+		}
+		return err
+	}
+}
+
+// deleteNote deletes a note if it is still at the version it was read at,
+// in one transaction with taking it off the owner's usage
+func deleteNote(note models.Note) error {
+	condition, values := versionCondition(note.Version)
+	return writeNote(types.TransactWriteItem{
+		Delete: &types.Delete{
+			TableName: aws.String(os.Getenv("NOTES_TABLE")),
+			Key: map[string]types.AttributeValue{
+				"noteId": &types.AttributeValueMemberS{Value: note.NoteID},
+				"userId": &types.AttributeValueMemberS{Value: note.UserID},
+			},
+			ConditionExpression:       condition,
+			ExpressionAttributeValues: values,
+		},
+	}, note.UserID, -1, -NoteSize(note))
+}
+
+// UpdateNoteFlags sets the pinned, archived and favorite state of a note.
+// Only the flags present in flags are written.
+func UpdateNoteFlags(noteID string, userID string, flags models.NoteFlags) (*models.Note, error) {
 	notesTable := os.Getenv("NOTES_TABLE")
 
-	_, err := dynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+	sets := []string{"updatedAt = :updatedAt"}
+	values := map[string]types.AttributeValue{
+		":updatedAt": &types.AttributeValueMemberS{Value: models.GetTimeNow()},
+		":one":       &types.AttributeValueMemberN{Value: "1"},
+	}
+
+	if flags.Pinned != nil {
+		sets = append(sets, "pinned = :pinned")
+		values[":pinned"] = &types.AttributeValueMemberBOOL{Value: *flags.Pinned}
+	}
+	if flags.Archived != nil {
+		sets = append(sets, "archived = :archived")
+		values[":archived"] = &types.AttributeValueMemberBOOL{Value: *flags.Archived}
+	}
+	if flags.Favorite != nil {
+		sets = append(sets, "favorite = :favorite")
+		values[":favorite"] = &types.AttributeValueMemberBOOL{Value: *flags.Favorite}
+	}
+
+	result, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
 		TableName: aws.String(notesTable),
 		Key: map[string]types.AttributeValue{
 			"noteId": &types.AttributeValueMemberS{Value: noteID},
 			"userId": &types.AttributeValueMemberS{Value: userID},
 		},
+		UpdateExpression:          aws.String("SET " + strings.Join(sets, ", ") + " ADD version :one"),
+		ConditionExpression:       aws.String("attribute_exists(noteId)"),
+		ExpressionAttributeValues: values,
+		ReturnValues:              types.ReturnValueAllNew,
 	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil, fmt.Errorf("note not found")
+		}
+		return nil, err
+	}
+
+	var note models.Note
+	err = unmarshalNote(result.Attributes, &note)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	return &note, nil
 }
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/envelope"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// marshalNote marshals a note for the notes table with its title, content and
+// checklist items encrypted. The note passed in is left in plaintext.
+func marshalNote(note models.Note) (map[string]types.AttributeValue, error) {
+	if err := envelope.Default.EncryptNote(context.TODO(), &note); err != nil {
+		return nil, err
+	}
+	return attributevalue.MarshalMap(note)
+}
+
+// unmarshalNote unmarshals an item of the notes table and decrypts its title,
+// content and checklist items
+func unmarshalNote(item map[string]types.AttributeValue, note *models.Note) error {
+	if err := attributevalue.UnmarshalMap(item, note); err != nil {
+		return err
+	}
+	return envelope.Default.DecryptNote(context.TODO(), note)
+}
+
+// unmarshalNotes unmarshals items of the notes table and decrypts their
+// titles, contents and checklist items
+func unmarshalNotes(items []map[string]types.AttributeValue, notes *[]models.Note) error {
+	if err := attributevalue.UnmarshalListOfMaps(items, notes); err != nil {
+		return err
+	}
+	for i := range *notes {
+		if err := envelope.Default.DecryptNote(context.TODO(), &(*notes)[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalDelivery marshals a webhook delivery with its payload encrypted. The
+// delivery passed in is left in plaintext.
+func marshalDelivery(delivery models.WebhookDelivery) (map[string]types.AttributeValue, error) {
+	if err := envelope.Default.EncryptDelivery(context.TODO(), &delivery); err != nil {
+		return nil, err
+	}
+	return attributevalue.MarshalMap(delivery)
+}
+
+// unmarshalDeliveries unmarshals items of the deliveries table and decrypts
+// their payloads
+func unmarshalDeliveries(items []map[string]types.AttributeValue, deliveries *[]models.WebhookDelivery) error {
+	if err := attributevalue.UnmarshalListOfMaps(items, deliveries); err != nil {
+		return err
+	}
+	for i := range *deliveries {
+		if err := envelope.Default.DecryptDelivery(context.TODO(), &(*deliveries)[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
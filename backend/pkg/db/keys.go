@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Errors returned by the encryption key functions
+var (
+	ErrKeyNotFound = errors.New("encryption key not found")
+	ErrKeyExists   = errors.New("encryption key already exists")
+)
+
+// CreateKey stores a wrapped data key, the client chooses the key ID because
+// it is bound into the wrapped key
+func CreateKey(key models.EncryptionKey) error {
+	item, err := attributevalue.MarshalMap(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           aws.String(os.Getenv("KEYS_TABLE")),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(keyId)"),
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return ErrKeyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetKeys returns a user's encryption keys
+func GetKeys(userID string) ([]models.EncryptionKey, error) {
+	result, err := dynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("KEYS_TABLE")),
+		KeyConditionExpression: aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []models.EncryptionKey{}
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetKey returns one encryption key of a user
+func GetKey(userID string, keyID string) (*models.EncryptionKey, error) {
+	result, err := dynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(os.Getenv("KEYS_TABLE")),
+		Key: map[string]types.AttributeValue{
+			"userId": &types.AttributeValueMemberS{Value: userID},
+			"keyId":  &types.AttributeValueMemberS{Value: keyID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	var key models.EncryptionKey
+	if err := attributevalue.UnmarshalMap(result.Item, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RewrapKey replaces the wrapping of an existing key after a passphrase
+// change. The data key, and so every note encrypted with it, stays the same.
+func RewrapKey(key models.EncryptionKey) error {
+	kdf, err := attributevalue.Marshal(key.KDF)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(os.Getenv("KEYS_TABLE")),
+		Key: map[string]types.AttributeValue{
+			"userId": &types.AttributeValueMemberS{Value: key.UserID},
+			"keyId":  &types.AttributeValueMemberS{Value: key.KeyID},
+		},
+		UpdateExpression:    aws.String("SET kdf = :kdf, #algorithm = :algorithm, nonce = :nonce, wrappedKey = :wrappedKey, updatedAt = :updatedAt"),
+		ConditionExpression: aws.String("attribute_exists(keyId)"),
+		ExpressionAttributeNames: map[string]string{
+			"#algorithm": "algorithm",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":kdf":        kdf,
+			":algorithm":  &types.AttributeValueMemberS{Value: key.Algorithm},
+			":nonce":      &types.AttributeValueMemberS{Value: key.Nonce},
+			":wrappedKey": &types.AttributeValueMemberS{Value: key.WrappedKey},
+			":updatedAt":  &types.AttributeValueMemberS{Value: key.UpdatedAt},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// CheckNoteKey makes sure the key an encrypted note names belongs to the
+// note's owner, so that its clients can decrypt it
+func CheckNoteKey(note models.Note, ownerID string) error {
+	if note.Encrypted == nil {
+		return nil
+	}
+	_, err := GetKey(ownerID, note.Encrypted.KeyID)
+	if errors.Is(err, ErrKeyNotFound) {
+		return fmt.Errorf("encryption key %s not found", note.Encrypted.KeyID)
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// ErrInvalidCursor is returned by GetNotesPage for a cursor it did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// NoteFilter selects the notes of a listing
+type NoteFilter struct {
+	NotebookID      string // Empty lists the notes of every notebook
+	IncludeArchived bool
+	FavoritesOnly   bool
+}
+
+// noteCursor is the position of the last note of a page, the next page
+// starts after it. Being a position rather than an offset, notes created or
+// deleted between pages do not shift the pages.
+type noteCursor struct {
+	Pinned    bool   `json:"p"`
+	CreatedAt string `json:"c"`
+	NoteID    string `json:"i"`
+}
+
+// GetNotesPage returns at most limit notes of a user that match filter,
+// pinned notes first and then newest first, starting after an encoded
+// cursor. It also returns the cursor of the next page, empty on the last one.
+//
+// Notes are read in createdAt order from UserIdIndex, or NotebookIdIndex for
+// a notebook, pinned ones in a pass of their own. The flags are filtered by
+// DynamoDB and only the notes of the page are decrypted.
+func GetNotesPage(userID string, filter NoteFilter, encoded string, limit int) ([]models.Note, string, error) {
+	var after *noteCursor
+	if encoded != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		after = &noteCursor{}
+		if err := json.Unmarshal(raw, after); err != nil || after.NoteID == "" || after.CreatedAt == "" {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+
+	// One note more than the page tells whether there is a next page
+	var notes []models.Note
+	for _, pinned := range []bool{true, false} {
+		if pinned && after != nil && !after.Pinned {
+			// The cursor is past the pinned notes
+			continue
+		}
+		var start *noteCursor
+		if after != nil && after.Pinned == pinned {
+			start = after
+		}
+		found, err := queryNotesPage(userID, filter, pinned, start, limit+1-len(notes))
+		if err != nil {
+			return nil, "", err
+		}
+		notes = append(notes, found...)
+		if len(notes) > limit {
+			break
+		}
+	}
+
+	if len(notes) <= limit {
+		return notes, "", nil
+	}
+	notes = notes[:limit]
+	last := notes[limit-1]
+	raw, _ := json.Marshal(noteCursor{Pinned: last.Pinned, CreatedAt: last.CreatedAt, NoteID: last.NoteID})
+	return notes, base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// queryNotesPage reads up to want pinned or unpinned notes matching filter,
+// newest first, after start when it is set
+func queryNotesPage(userID string, filter NoteFilter, pinned bool, start *noteCursor, want int) ([]models.Note, error) {
+	values := map[string]types.AttributeValue{
+		":userId": &types.AttributeValueMemberS{Value: userID},
+		":true":   &types.AttributeValueMemberBOOL{Value: true},
+	}
+	conditions := []string{flagCondition("pinned", pinned)}
+	if !filter.IncludeArchived {
+		conditions = append(conditions, flagCondition("archived", false))
+	}
+	if filter.FavoritesOnly {
+		conditions = append(conditions, flagCondition("favorite", true))
+	}
+
+	params := &dynamodb.QueryInput{
+		TableName:        aws.String(os.Getenv("NOTES_TABLE")),
+		IndexName:        aws.String("UserIdIndex"),
+		ScanIndexForward: aws.Bool(false), // Descending order by sort key (createdAt)
+	}
+	keyAttribute, keyValue := "userId", userID
+	if filter.NotebookID != "" {
+		params.IndexName = aws.String("NotebookIdIndex")
+		keyAttribute, keyValue = "notebookId", filter.NotebookID
+		conditions = append(conditions, "userId = :userId")
+		values[":notebookId"] = &types.AttributeValueMemberS{Value: filter.NotebookID}
+	}
+	params.KeyConditionExpression = aws.String(keyAttribute + " = :" + keyAttribute)
+	params.FilterExpression = aws.String(strings.Join(conditions, " AND "))
+	params.ExpressionAttributeValues = values
+
+	if start != nil {
+		params.ExclusiveStartKey = map[string]types.AttributeValue{
+			"noteId":     &types.AttributeValueMemberS{Value: start.NoteID},
+			"userId":     &types.AttributeValueMemberS{Value: userID},
+			"createdAt":  &types.AttributeValueMemberS{Value: start.CreatedAt},
+			keyAttribute: &types.AttributeValueMemberS{Value: keyValue},
+		}
+	}
+
+	// The limit applies before the filter, so read on until enough notes
+	// matched or the index is exhausted
+	var items []map[string]types.AttributeValue
+	for len(items) < want {
+		params.Limit = aws.Int32(int32(want - len(items)))
+		result, err := dynamoClient.Query(context.TODO(), params)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		params.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	var notes []models.Note
+	if err := unmarshalNotes(items, &notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// flagCondition matches notes with a boolean attribute set to value. Notes
+// written before the attribute existed count as false.
+func flagCondition(name string, value bool) string {
+	if value {
+		return name + " = :true"
+	}
+	return "(attribute_not_exists(" + name + ") OR " + name + " <> :true)"
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// GetNotebooksByUserID gets all notebooks for a user
+func GetNotebooksByUserID(userID string) ([]models.Notebook, error) {
+	notebooksTable := os.Getenv("NOTEBOOKS_TABLE")
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(notebooksTable),
+		IndexName:              aws.String("UserIdIndex"),
+		KeyConditionExpression: aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userID},
+		},
+		ScanIndexForward: aws.Bool(true), // Oldest notebook first
+	}
+
+	result, err := dynamoClient.Query(context.TODO(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	var notebooks []models.Notebook
+	err = attributevalue.UnmarshalListOfMaps(result.Items, &notebooks)
+	if err != nil {
+		return nil, err
+	}
+
+	return notebooks, nil
+}
+
+// GetNotebookByID gets a notebook by its ID and userID
+func GetNotebookByID(notebookID string, userID string) (*models.Notebook, error) {
+	notebooksTable := os.Getenv("NOTEBOOKS_TABLE")
+
+	params := &dynamodb.GetItemInput{
+		TableName: aws.String(notebooksTable),
+		Key: map[string]types.AttributeValue{
+			"notebookId": &types.AttributeValueMemberS{Value: notebookID},
+			"userId":     &types.AttributeValueMemberS{Value: userID},
+		},
+	}
+
+	result, err := dynamoClient.GetItem(context.TODO(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, fmt.Errorf("notebook not found")
+	}
+
+	var notebook models.Notebook
+	err = attributevalue.UnmarshalMap(result.Item, &notebook)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notebook, nil
+}
+
+// CreateNotebook creates a new notebook
+func CreateNotebook(notebook *models.Notebook) error {
+	notebooksTable := os.Getenv("NOTEBOOKS_TABLE")
+
+	notebook.NotebookID = uuid.New().String()
+	notebook.CreatedAt = models.GetTimeNow()
+	notebook.UpdatedAt = notebook.CreatedAt
+
+	item, err := attributevalue.MarshalMap(notebook)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(notebooksTable),
+		Item:      item,
+	})
+
+	return err
+}
+
+// UpdateNotebook renames an existing notebook
+func UpdateNotebook(notebook *models.Notebook) error {
+	notebooksTable := os.Getenv("NOTEBOOKS_TABLE")
+
+	// Check if notebook exists
+	existingNotebook, err := GetNotebookByID(notebook.NotebookID, notebook.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Update notebook fields
+	notebook.CreatedAt = existingNotebook.CreatedAt
+	notebook.UpdatedAt = models.GetTimeNow()
+
+	item, err := attributevalue.MarshalMap(notebook)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(notebooksTable),
+		Item:      item,
+	})
+
+	return err
+}
+
+// DeleteNotebook deletes a notebook and moves the notes it contains to the
+// default notebook
+func DeleteNotebook(notebookID string, userID string) error {
+	notebooksTable := os.Getenv("NOTEBOOKS_TABLE")
+
+	// Check if notebook exists
+	if _, err := GetNotebookByID(notebookID, userID); err != nil {
+		return err
+	}
+
+	notes, err := GetNotesByNotebookID(notebookID, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, note := range notes {
+		if err := MoveNote(note.NoteID, userID, ""); err != nil {
+			return err
+		}
+	}
+
+	_, err = dynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: aws.String(notebooksTable),
+		Key: map[string]types.AttributeValue{
+			"notebookId": &types.AttributeValueMemberS{Value: notebookID},
+			"userId":     &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+
+	return err
+}
+
+// GetNotesByNotebookID gets all notes a user keeps in a notebook
+func GetNotesByNotebookID(notebookID string, userID string) ([]models.Note, error) {
+	notesTable := os.Getenv("NOTES_TABLE")
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(notesTable),
+		IndexName:              aws.String("NotebookIdIndex"),
+		KeyConditionExpression: aws.String("notebookId = :notebookId"),
+		FilterExpression:       aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":notebookId": &types.AttributeValueMemberS{Value: notebookID},
+			":userId":     &types.AttributeValueMemberS{Value: userID},
+		},
+		ScanIndexForward: aws.Bool(false), // Descending order by sort key (createdAt)
+	}
+
+	var notes []models.Note
+	paginator := dynamodb.NewQueryPaginator(dynamoClient, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var pageNotes []models.Note
+		if err := unmarshalNotes(page.Items, &pageNotes); err != nil {
+			return nil, err
+		}
+		notes = append(notes, pageNotes...)
+	}
+
+	return notes, nil
+}
+
+// MoveNote moves a note into another notebook. An empty notebookID moves the
+// note back to the default notebook.
+func MoveNote(noteID string, userID string, notebookID string) error {
+	notesTable := os.Getenv("NOTES_TABLE")
+
+	params := &dynamodb.UpdateItemInput{
+		TableName: aws.String(notesTable),
+		Key: map[string]types.AttributeValue{
+			"noteId": &types.AttributeValueMemberS{Value: noteID},
+			"userId": &types.AttributeValueMemberS{Value: userID},
+		},
+		ConditionExpression: aws.String("attribute_exists(noteId)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":updatedAt": &types.AttributeValueMemberS{Value: models.GetTimeNow()},
+			":one":       &types.AttributeValueMemberN{Value: "1"},
+		},
+	}
+
+	if notebookID == "" {
+		params.UpdateExpression = aws.String("SET updatedAt = :updatedAt REMOVE notebookId ADD version :one")
+	} else {
+		params.UpdateExpression = aws.String("SET updatedAt = :updatedAt, notebookId = :notebookId ADD version :one")
+		params.ExpressionAttributeValues[":notebookId"] = &types.AttributeValueMemberS{Value: notebookID}
+	}
+
+	_, err := dynamoClient.UpdateItem(context.TODO(), params)
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return fmt.Errorf("note not found")
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,269 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/envelope"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/patch"
+)
+
+// Errors returned by PatchNote
+var (
+	ErrNoteNotFound    = errors.New("note not found")
+	ErrPatchTestFailed = errors.New("patch test operation failed")
+)
+
+// PatchNote applies a partial update to a note with a single UpdateItem call.
+// Only the fields in the patch are written, the update is conditional on the
+// note existing for userID and on every test value of the patch. A patch of
+// the title or content changes the owner's usage, it fails with a LimitError
+// when the note grows past the owner's quota.
+func PatchNote(noteID string, userID string, p patch.Patch) (*models.Note, error) {
+	for attempt := 1; ; attempt++ {
+		note, err := patchNote(noteID, userID, p)
+		if errors.Is(err, ErrVersionConflict) && attempt < maxWriteAttempts {
+			continue // Changed meanwhile, the change in size is computed again
+		}
+		return note, err
+	}
+}
+
+// patchNote makes one attempt at PatchNote. When the patch changes the size
+// of the note, the update is made in one transaction with the change to the
+// owner's usage, conditional on the version the change was computed from.
+func patchNote(noteID string, userID string, p patch.Patch) (*models.Note, error) {
+	notesTable := os.Getenv("NOTES_TABLE")
+
+	names := map[string]string{
+		"#noteId":    "noteId",
+		"#userId":    "userId",
+		"#updatedAt": "updatedAt",
+		"#version":   "version",
+	}
+	values := map[string]types.AttributeValue{
+		":userId":    &types.AttributeValueMemberS{Value: userID},
+		":updatedAt": &types.AttributeValueMemberS{Value: models.GetTimeNow()},
+		":one":       &types.AttributeValueMemberN{Value: "1"},
+	}
+
+	sets := []string{"#updatedAt = :updatedAt"}
+	for i, field := range sortedKeys(p.Set) {
+		setValue := p.Set[field]
+		if text, ok := setValue.(string); ok && envelope.IsEncryptedNoteField(field) {
+			encrypted, err := envelope.Default.EncryptNoteField(context.TODO(), noteID, field, text)
+			if err != nil {
+				return nil, err
+			}
+			setValue = encrypted
+		}
+		value, err := attributevalue.Marshal(setValue)
+		if err != nil {
+			return nil, err
+		}
+		name, placeholder := fmt.Sprintf("#set%d", i), fmt.Sprintf(":set%d", i)
+		names[name] = field
+		values[placeholder] = value
+		sets = append(sets, name+" = "+placeholder)
+	}
+
+	var removes []string
+	for i, field := range p.Remove {
+		name := fmt.Sprintf("#remove%d", i)
+		names[name] = field
+		removes = append(removes, name)
+	}
+
+	// Encrypted fields never compare equal in an expression, their tests are
+	// checked here and the update is conditional on the values tested
+	stored, err := storedEncryptedFields(noteID, userID, p.Tests)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"attribute_exists(#noteId)", "#userId = :userId"}
+	for i, field := range sortedKeys(p.Tests) {
+		value, ok := stored[field]
+		if !ok {
+			value, err = attributevalue.Marshal(p.Tests[field])
+			if err != nil {
+				return nil, err
+			}
+		}
+		name, placeholder := fmt.Sprintf("#test%d", i), fmt.Sprintf(":test%d", i)
+		names[name] = field
+		values[placeholder] = value
+		conditions = append(conditions, name+" = "+placeholder)
+	}
+
+	updateExpression := "SET " + strings.Join(sets, ", ")
+	if len(removes) > 0 {
+		updateExpression += " REMOVE " + strings.Join(removes, ", ")
+	}
+	updateExpression += " ADD #version :one"
+
+	if resizes(p) {
+		current, err := getNote(noteID, userID, true)
+		if err != nil {
+			return nil, err
+		}
+		patched := *current
+		if title, ok := p.Set["title"].(string); ok {
+			patched.Title = title
+		}
+		if content, ok := p.Set["content"].(string); ok {
+			patched.Content = content
+		}
+
+		condition, versionValues := versionCondition(current.Version)
+		conditions = append(conditions, *condition)
+		for placeholder, value := range versionValues {
+			values[placeholder] = value
+		}
+
+		err = writeNote(types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(notesTable),
+				Key: map[string]types.AttributeValue{
+					"noteId": &types.AttributeValueMemberS{Value: noteID},
+					"userId": &types.AttributeValueMemberS{Value: userID},
+				},
+				UpdateExpression:          aws.String(updateExpression),
+				ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			},
+		}, userID, 0, NoteSize(patched)-NoteSize(*current))
+		if errors.Is(err, errNoteCondition) {
+			return nil, patchFailure(noteID, userID, p, current.Version)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return getNote(noteID, userID, true)
+	}
+
+	result, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(notesTable),
+		Key: map[string]types.AttributeValue{
+			"noteId": &types.AttributeValueMemberS{Value: noteID},
+			"userId": &types.AttributeValueMemberS{Value: userID},
+		},
+		UpdateExpression:          aws.String(updateExpression),
+		ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ReturnValues:              types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionErr) {
+			return nil, err
+		}
+		// Tell a missing note apart from a failed test operation
+		if len(p.Tests) > 0 {
+			if _, getErr := GetNoteByID(noteID, userID); getErr == nil {
+				return nil, ErrPatchTestFailed
+			}
+		}
+		return nil, ErrNoteNotFound
+	}
+
+	var note models.Note
+	err = unmarshalNote(result.Attributes, &note)
+	if err != nil {
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+// resizes reports whether a patch may change the size of a note
+func resizes(p patch.Patch) bool {
+	for _, field := range []string{"title", "content"} {
+		if _, ok := p.Set[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// patchFailure tells why the update of a patch computed at version failed: the
+// note is gone, it changed since, or a test operation failed
+func patchFailure(noteID string, userID string, p patch.Patch, version int64) error {
+	current, err := getNote(noteID, userID, true)
+	if err != nil {
+		return err
+	}
+	if current.Version != version {
+		return ErrVersionConflict
+	}
+	if len(p.Tests) > 0 {
+		return ErrPatchTestFailed
+	}
+	return ErrNoteNotFound
+}
+
+// storedEncryptedFields checks the tests of a patch on encrypted fields
+// against the decrypted note. It returns the stored values of those fields to
+// condition the update on, or ErrPatchTestFailed.
+func storedEncryptedFields(noteID string, userID string, tests map[string]interface{}) (map[string]types.AttributeValue, error) {
+	stored := make(map[string]types.AttributeValue)
+	var item map[string]types.AttributeValue
+	for field, want := range tests {
+		if !envelope.IsEncryptedNoteField(field) {
+			continue
+		}
+
+		if item == nil {
+			result, err := dynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
+				TableName: aws.String(os.Getenv("NOTES_TABLE")),
+				Key: map[string]types.AttributeValue{
+					"noteId": &types.AttributeValueMemberS{Value: noteID},
+					"userId": &types.AttributeValueMemberS{Value: userID},
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if result.Item == nil {
+				return nil, ErrNoteNotFound
+			}
+			item = result.Item
+		}
+
+		value, ok := item[field].(*types.AttributeValueMemberS)
+		if !ok {
+			return nil, ErrPatchTestFailed
+		}
+		plaintext, err := envelope.Default.DecryptNoteField(context.TODO(), noteID, field, value.Value)
+		if err != nil {
+			return nil, err
+		}
+		if text, ok := want.(string); !ok || text != plaintext {
+			return nil, ErrPatchTestFailed
+		}
+		stored[field] = value
+	}
+	return stored, nil
+}
+
+// sortedKeys returns the keys of m in a stable order so that generated
+// expressions are deterministic
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
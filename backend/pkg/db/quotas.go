@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Plans
+const (
+	PlanFree = "free"
+	PlanPro  = "pro"
+)
+
+// MaxBodyBytesCeiling keeps a note within DynamoDB's 400 KB item limit once
+// its title and content are encrypted at rest, which grows them by a third
+const MaxBodyBytesCeiling = 256 << 10
+
+// PlanLimits are the limits of a plan
+type PlanLimits struct {
+	MaxTitleBytes      int   `json:"maxTitleBytes"`
+	MaxBodyBytes       int   `json:"maxBodyBytes"` // Content, checklist item texts or ciphertext of one note
+	MaxNotes           int64 `json:"maxNotes"`
+	MaxNoteBytes       int64 `json:"maxNoteBytes"`       // Titles and bodies of all notes
+	MaxAttachmentBytes int64 `json:"maxAttachmentBytes"` // Attachment files of all notes
+}
+
+// plans are the built-in plans, PLAN_LIMITS overrides them with a JSON object
+// of limits by plan name
+var plans = map[string]PlanLimits{
+	PlanFree: {
+		MaxTitleBytes:      500,
+		MaxBodyBytes:       100 << 10,
+		MaxNotes:           1000,
+		MaxNoteBytes:       20 << 20,
+		MaxAttachmentBytes: 100 << 20,
+	},
+	PlanPro: {
+		MaxTitleBytes:      500,
+		MaxBodyBytes:       MaxBodyBytesCeiling,
+		MaxNotes:           100000,
+		MaxNoteBytes:       2 << 30,
+		MaxAttachmentBytes: 20 << 30,
+	},
+}
+
+func init() {
+	if value := os.Getenv("PLAN_LIMITS"); value != "" {
+		var overrides map[string]PlanLimits
+		if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+			log.Fatalf("Unable to parse PLAN_LIMITS: %v", err)
+		}
+		for plan, limits := range overrides {
+			if limits.MaxBodyBytes > MaxBodyBytesCeiling {
+				log.Fatalf("PLAN_LIMITS: maxBodyBytes of %s cannot exceed %d", plan, MaxBodyBytesCeiling)
+			}
+			plans[plan] = limits
+		}
+	}
+}
+
+// LimitsFor returns the limits of a plan, unknown plans get the free plan's
+func LimitsFor(plan string) PlanLimits {
+	if limits, ok := plans[plan]; ok {
+		return limits
+	}
+	return plans[PlanFree]
+}
+
+// Limit error codes, returned to clients in the code field
+const (
+	CodeTitleTooLong            = "title_too_long"
+	CodeBodyTooLong             = "body_too_long"
+	CodeNoteQuotaExceeded       = "note_quota_exceeded"
+	CodeStorageQuotaExceeded    = "storage_quota_exceeded"
+	CodeAttachmentQuotaExceeded = "attachment_quota_exceeded"
+	CodeAttachmentTooLarge      = "attachment_too_large"
+	CodeWebhookQuotaExceeded    = "webhook_quota_exceeded"
+)
+
+// LimitError is returned when a note is too large or a user is over a quota
+// of their plan
+type LimitError struct {
+	Code    string
+	Message string
+}
+
+func (e *LimitError) Error() string {
+	return e.Message
+}
+
+// StatusCode is 413 when the request itself is too large and 403 when the
+// user is out of quota
+func (e *LimitError) StatusCode() int {
+	switch e.Code {
+	case CodeTitleTooLong, CodeBodyTooLong, CodeAttachmentTooLarge:
+		return 413 // Payload Too Large
+	default:
+		return 403
+	}
+}
+
+// NoteBodySize is the size of a note's content, checklist item texts or
+// encrypted content
+func NoteBodySize(note models.Note) int {
+	size := len(note.Content)
+	for _, item := range note.Items {
+		size += len(item.Text)
+	}
+	if note.Encrypted != nil {
+		size += base64.StdEncoding.DecodedLen(len(note.Encrypted.Ciphertext))
+	}
+	return size
+}
+
+// NoteSize is the size a note counts for in the user's usage
+func NoteSize(note models.Note) int64 {
+	return int64(len(note.Title) + NoteBodySize(note))
+}
+
+// CheckNoteLimits checks a note about to be written against the plan of its
+// owner: the title and body lengths, and that the owner's note count and
+// bytes stay within quota. existing is the stored note for an update, nil for
+// a create. The quotas are checked again by the write, which charges the
+// owner's usage in the same transaction, so that concurrent writes cannot go
+// over them; this check only fails early.
+func CheckNoteLimits(ownerID string, note models.Note, existing *models.Note) error {
+	owner, err := GetUserByID(ownerID)
+	if err != nil {
+		return err
+	}
+	limits := LimitsFor(owner.Plan)
+
+	if len(note.Title) > limits.MaxTitleBytes {
+		return &LimitError{CodeTitleTooLong, fmt.Sprintf("title cannot be longer than %d bytes", limits.MaxTitleBytes)}
+	}
+	if NoteBodySize(note) > limits.MaxBodyBytes {
+		return &LimitError{CodeBodyTooLong, fmt.Sprintf("note content cannot be longer than %d bytes", limits.MaxBodyBytes)}
+	}
+
+	if existing == nil && owner.NoteCount >= limits.MaxNotes {
+		return noteQuotaError(limits)
+	}
+	growth := NoteSize(note)
+	if existing != nil {
+		growth -= NoteSize(*existing)
+	}
+	if growth > 0 && owner.NoteBytes+growth > limits.MaxNoteBytes {
+		return storageQuotaError(limits)
+	}
+	return nil
+}
+
+func noteQuotaError(limits PlanLimits) error {
+	return &LimitError{CodeNoteQuotaExceeded, fmt.Sprintf("your plan allows at most %d notes", limits.MaxNotes)}
+}
+
+func storageQuotaError(limits PlanLimits) error {
+	return &LimitError{CodeStorageQuotaExceeded, fmt.Sprintf("your plan allows at most %d bytes of notes", limits.MaxNoteBytes)}
+}
+
+// maxWriteAttempts bounds the retries of a write that lost a race with
+// another write of the same note
+const maxWriteAttempts = 3
+
+// errNoteCondition is returned by writeNote when the condition of the write
+// to the notes table failed
+var errNoteCondition = errors.New("note condition failed")
+
+// writeNote runs a write to the notes table in one transaction with the
+// change it makes to the owner's note count and bytes. The change must be
+// exact, so the write is conditional on the state it was computed from. When
+// it grows the usage, the transaction is also conditional on the owner staying
+// within the quotas of their plan and fails with a LimitError otherwise.
+func writeNote(write types.TransactWriteItem, userID string, notes int64, bytes int64) error {
+	items := []types.TransactWriteItem{write}
+	var limits PlanLimits
+	if notes != 0 || bytes != 0 {
+		conditions := []string{"attribute_exists(userId)"}
+		values := map[string]types.AttributeValue{
+			":notes": &types.AttributeValueMemberN{Value: strconv.FormatInt(notes, 10)},
+			":bytes": &types.AttributeValueMemberN{Value: strconv.FormatInt(bytes, 10)},
+		}
+		if notes > 0 || bytes > 0 {
+			owner, err := GetUserByID(userID)
+			if err != nil {
+				return err
+			}
+			limits = LimitsFor(owner.Plan)
+		}
+
+		// Conditions cannot add, so the growth is taken off the quota instead
+		if notes > 0 {
+			conditions = append(conditions, "(attribute_not_exists(noteCount) OR noteCount <= :maxNotes)")
+			values[":maxNotes"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(limits.MaxNotes-notes, 10)}
+		}
+		if bytes > 0 {
+			conditions = append(conditions, "(attribute_not_exists(noteBytes) OR noteBytes <= :maxBytes)")
+			values[":maxBytes"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(limits.MaxNoteBytes-bytes, 10)}
+		}
+
+		items = append(items, types.TransactWriteItem{
+			Update: &types.Update{
+				TableName:                 aws.String(os.Getenv("USERS_TABLE")),
+				Key:                       userKey(userID),
+				UpdateExpression:          aws.String("ADD noteCount :notes, noteBytes :bytes"),
+				ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+				ExpressionAttributeValues: values,
+			},
+		})
+	}
+
+	_, err := dynamoClient.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		var canceled *types.TransactionCanceledException
+		if !errors.As(err, &canceled) || len(canceled.CancellationReasons) != len(items) {
+			return err
+		}
+		if aws.ToString(canceled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+			return errNoteCondition
+		}
+		if len(items) == 2 && aws.ToString(canceled.CancellationReasons[1].Code) == "ConditionalCheckFailed" {
+			return usageError(userID, notes, limits)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// usageError tells which quota a write that failed its usage condition
+// would have gone over
+func usageError(userID string, notes int64, limits PlanLimits) error {
+	owner, err := GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if notes > 0 && owner.NoteCount+notes > limits.MaxNotes {
+		return noteQuotaError(limits)
+	}
+	return storageQuotaError(limits)
+}
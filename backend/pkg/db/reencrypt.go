@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/envelope"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// reencryptPageSize is the number of notes scanned per page
+const reencryptPageSize = 100
+
+// ReencryptResult counts what a re-encryption run did
+type ReencryptResult struct {
+	Scanned     int `json:"scanned"`
+	Reencrypted int `json:"reencrypted"`
+	Skipped     int `json:"skipped"` // Changed while being re-encrypted, the change used the current key
+	Failed      int `json:"failed"`
+	Reindexed   int `json:"reindexed"`
+}
+
+// ReencryptNotes scans one page of the notes table from startKey and
+// re-encrypts the title, content and checklist items of notes stored in
+// plaintext or under an older master key version. Each note is rewritten only
+// if it did not change since it was read, and without a new version or update
+// time. With reindex the search index entries of every note are rebuilt too,
+// which removes those written before the index was blind. It returns the key
+// to continue from, nil after the last page. With dryRun nothing is written.
+func ReencryptNotes(ctx context.Context, startKey map[string]string, dryRun bool, reindex bool, result *ReencryptResult) (map[string]string, error) {
+	if !envelope.Default.Enabled() {
+		return nil, errors.New("encryption is not configured, set ENCRYPTION_KEYRING")
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(os.Getenv("NOTES_TABLE")),
+		Limit:     aws.Int32(reencryptPageSize),
+	}
+	if len(startKey) > 0 {
+		input.ExclusiveStartKey = make(map[string]types.AttributeValue, len(startKey))
+		for name, value := range startKey {
+			input.ExclusiveStartKey[name] = &types.AttributeValueMemberS{Value: value}
+		}
+	}
+
+	page, err := dynamoClient.Scan(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range page.Items {
+		result.Scanned++
+		rewritten, err := reencryptNote(ctx, item, dryRun)
+		switch {
+		case errors.Is(err, errNoteChanged):
+			result.Skipped++
+		case err != nil:
+			result.Failed++
+			logging.FromContext(ctx).Error("note not re-encrypted", "noteId", stringAttribute(item, "noteId"), "error", err)
+		case rewritten:
+			result.Reencrypted++
+		}
+
+		if reindex && !dryRun {
+			if err := reindexNote(item); err != nil {
+				result.Failed++
+				logging.FromContext(ctx).Error("note not reindexed", "noteId", stringAttribute(item, "noteId"), "error", err)
+				continue
+			}
+			result.Reindexed++
+		}
+	}
+
+	if len(page.LastEvaluatedKey) == 0 {
+		return nil, nil
+	}
+	nextKey := make(map[string]string, len(page.LastEvaluatedKey))
+	for name := range page.LastEvaluatedKey {
+		nextKey[name] = stringAttribute(page.LastEvaluatedKey, name)
+	}
+	return nextKey, nil
+}
+
+// errNoteChanged is returned when a note changed between the scan and the rewrite
+var errNoteChanged = errors.New("note changed")
+
+// reencryptNote rewrites the encrypted fields of one stored note that need it
+func reencryptNote(ctx context.Context, item map[string]types.AttributeValue, dryRun bool) (bool, error) {
+	noteID := stringAttribute(item, "noteId")
+
+	var sets, conditions []string
+	names := map[string]string{}
+	values := map[string]types.AttributeValue{}
+	for i, field := range envelope.EncryptedNoteFields {
+		stored, ok := item[field].(*types.AttributeValueMemberS)
+		if !ok || !envelope.Default.NeedsReencryption(stored.Value) {
+			continue
+		}
+
+		plaintext, err := envelope.Default.DecryptNoteField(ctx, noteID, field, stored.Value)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", field, err)
+		}
+		encrypted, err := envelope.Default.EncryptNoteField(ctx, noteID, field, plaintext)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", field, err)
+		}
+
+		name := fmt.Sprintf("#field%d", i)
+		names[name] = field
+		values[fmt.Sprintf(":new%d", i)] = &types.AttributeValueMemberS{Value: encrypted}
+		values[fmt.Sprintf(":old%d", i)] = stored
+		sets = append(sets, fmt.Sprintf("%s = :new%d", name, i))
+		conditions = append(conditions, fmt.Sprintf("%s = :old%d", name, i))
+	}
+
+	// Checklist items are rewritten as a whole when any of their texts needs it
+	if stored, ok := item["items"].(*types.AttributeValueMemberL); ok {
+		items, err := reencryptItems(ctx, noteID, stored)
+		if err != nil {
+			return false, fmt.Errorf("items: %w", err)
+		}
+		if items != nil {
+			names["#items"] = "items"
+			values[":newItems"] = items
+			values[":oldItems"] = stored
+			sets = append(sets, "#items = :newItems")
+			conditions = append(conditions, "#items = :oldItems")
+		}
+	}
+
+	if len(sets) == 0 {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	_, err := dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(os.Getenv("NOTES_TABLE")),
+		Key: map[string]types.AttributeValue{
+			"noteId": item["noteId"],
+			"userId": item["userId"],
+		},
+		UpdateExpression:          aws.String("SET " + strings.Join(sets, ", ")),
+		ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return false, errNoteChanged
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// reencryptItems returns the stored checklist items with every text
+// re-encrypted, nil when none of them needs it
+func reencryptItems(ctx context.Context, noteID string, stored *types.AttributeValueMemberL) (types.AttributeValue, error) {
+	var items []models.ChecklistItem
+	if err := attributevalue.Unmarshal(stored, &items); err != nil {
+		return nil, err
+	}
+
+	needed := false
+	for i, item := range items {
+		if !envelope.Default.NeedsReencryption(item.Text) {
+			continue
+		}
+		needed = true
+		text, err := envelope.Default.DecryptItemText(ctx, noteID, item.ItemID, item.Text)
+		if err != nil {
+			return nil, err
+		}
+		if items[i].Text, err = envelope.Default.EncryptItemText(ctx, noteID, item.ItemID, text); err != nil {
+			return nil, err
+		}
+	}
+	if !needed {
+		return nil, nil
+	}
+	return attributevalue.Marshal(items)
+}
+
+// reindexNote rebuilds the search index entries of a note, as it is now
+// rather than as it was scanned
+func reindexNote(item map[string]types.AttributeValue) error {
+	note, err := GetNoteByID(stringAttribute(item, "noteId"), stringAttribute(item, "userId"))
+	if errors.Is(err, ErrNoteNotFound) {
+		return nil // Deleting the note removed its entries
+	}
+	if err != nil {
+		return err
+	}
+	if err := RemoveNoteFromIndex(*note); err != nil {
+		return err
+	}
+	return IndexNote(*note)
+}
+
+// stringAttribute returns a string attribute of an item, empty when it is
+// missing or not a string
+func stringAttribute(item map[string]types.AttributeValue, name string) string {
+	if value, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return value.Value
+	}
+	return ""
+}
@@ -0,0 +1,287 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// reminderBucketLayout formats the one minute bucket of a reminder
+const reminderBucketLayout = "2006-01-02T15:04Z"
+
+// reminderRetention is how long an entry is kept after its reminder time
+const reminderRetention = 7 * 24 * time.Hour
+
+// reminderCursorKey is the key of the entry recording the last bucket the
+// dispatcher processed, kept in the reminders table beside the buckets
+const reminderCursorKey = "#dispatcher"
+
+// ErrReminderClaimed is returned when another dispatcher already handles a reminder
+var ErrReminderClaimed = errors.New("reminder already sent or being sent")
+
+// ReminderBucket returns the bucket of the minute t falls in
+func ReminderBucket(t time.Time) string {
+	return t.UTC().Truncate(time.Minute).Format(reminderBucketLayout)
+}
+
+// ParseRemindAt parses the remindAt time of a note
+func ParseRemindAt(remindAt string) (time.Time, error) {
+	return time.Parse(time.RFC3339, remindAt)
+}
+
+// ValidRemindAt reports whether remindAt is an RFC3339 time in the future
+func ValidRemindAt(remindAt string) bool {
+	t, err := ParseRemindAt(remindAt)
+	return err == nil && t.After(time.Now())
+}
+
+// PutReminder adds the reminder of a note to the reminders index, replacing a
+// sent entry for the same time. An entry of another user's note is left alone.
+func PutReminder(note models.Note) error {
+	remindAt, err := ParseRemindAt(note.RemindAt)
+	if err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(models.Reminder{
+		Bucket:   ReminderBucket(remindAt),
+		NoteID:   note.NoteID,
+		UserID:   note.UserID,
+		RemindAt: note.RemindAt,
+		TTL:      remindAt.Add(reminderRetention).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           aws.String(os.Getenv("REMINDERS_TABLE")),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(noteId) OR userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: note.UserID},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return ErrForbidden
+		}
+	}
+
+	return err
+}
+
+// DeleteReminder removes the reminder of a note from the reminders index,
+// unless the entry belongs to another user's note
+func DeleteReminder(note models.Note) error {
+	remindAt, err := ParseRemindAt(note.RemindAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: aws.String(os.Getenv("REMINDERS_TABLE")),
+		Key: map[string]types.AttributeValue{
+			"bucket": &types.AttributeValueMemberS{Value: ReminderBucket(remindAt)},
+			"noteId": &types.AttributeValueMemberS{Value: note.NoteID},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(noteId) OR userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: note.UserID},
+		},
+	})
+
+	return ignoreConditionFailure(err)
+}
+
+// GetPendingReminders returns the reminders of a bucket that were not sent yet
+func GetPendingReminders(bucket string) ([]models.Reminder, error) {
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("REMINDERS_TABLE")),
+		KeyConditionExpression: aws.String("#bucket = :bucket"),
+		FilterExpression:       aws.String("attribute_not_exists(#sentAt)"),
+		ExpressionAttributeNames: map[string]string{
+			"#bucket": "bucket",
+			"#sentAt": "sentAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":bucket": &types.AttributeValueMemberS{Value: bucket},
+		},
+	}
+
+	var reminders []models.Reminder
+	paginator := dynamodb.NewQueryPaginator(dynamoClient, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var pageReminders []models.Reminder
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageReminders); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, pageReminders...)
+	}
+
+	return reminders, nil
+}
+
+// ClaimReminder marks a reminder as being delivered. It fails with
+// ErrReminderClaimed when the reminder was sent already or another dispatcher
+// claimed it after staleBefore.
+func ClaimReminder(reminder models.Reminder, now time.Time, staleBefore time.Time) error {
+	_, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:        aws.String(os.Getenv("REMINDERS_TABLE")),
+		Key:              reminderKey(reminder),
+		UpdateExpression: aws.String("SET #claimedAt = :now"),
+		ConditionExpression: aws.String("attribute_exists(#noteId) AND #remindAt = :remindAt AND attribute_not_exists(#sentAt) AND " +
+			"(attribute_not_exists(#claimedAt) OR #claimedAt < :staleBefore)"),
+		ExpressionAttributeNames: map[string]string{
+			"#noteId":    "noteId",
+			"#remindAt":  "remindAt",
+			"#sentAt":    "sentAt",
+			"#claimedAt": "claimedAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":         &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+			":remindAt":    &types.AttributeValueMemberS{Value: reminder.RemindAt},
+			":staleBefore": &types.AttributeValueMemberS{Value: staleBefore.UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return ErrReminderClaimed
+		}
+		return err
+	}
+
+	return nil
+}
+
+// MarkReminderSent records that a reminder was delivered. Marking a reminder
+// twice keeps the first time.
+func MarkReminderSent(reminder models.Reminder, sentAt time.Time) error {
+	_, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(os.Getenv("REMINDERS_TABLE")),
+		Key:                 reminderKey(reminder),
+		UpdateExpression:    aws.String("SET #sentAt = if_not_exists(#sentAt, :sentAt) REMOVE #claimedAt"),
+		ConditionExpression: aws.String("attribute_exists(#noteId)"),
+		ExpressionAttributeNames: map[string]string{
+			"#noteId":    "noteId",
+			"#sentAt":    "sentAt",
+			"#claimedAt": "claimedAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":sentAt": &types.AttributeValueMemberS{Value: sentAt.UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		// The note was deleted or rescheduled meanwhile, nothing left to mark
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseReminder drops the claim on a reminder whose delivery failed so that
+// the next dispatch retries it
+func ReleaseReminder(reminder models.Reminder) error {
+	_, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(os.Getenv("REMINDERS_TABLE")),
+		Key:                 reminderKey(reminder),
+		UpdateExpression:    aws.String("REMOVE #claimedAt"),
+		ConditionExpression: aws.String("attribute_exists(#noteId)"),
+		ExpressionAttributeNames: map[string]string{
+			"#noteId":    "noteId",
+			"#claimedAt": "claimedAt",
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetReminderCursor returns the last bucket the dispatcher processed, and
+// false before the first dispatch
+func GetReminderCursor() (time.Time, bool, error) {
+	result, err := dynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName:      aws.String(os.Getenv("REMINDERS_TABLE")),
+		Key:            reminderCursorItemKey(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	processed, ok := result.Item["processed"].(*types.AttributeValueMemberS)
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	bucket, err := time.Parse(reminderBucketLayout, processed.Value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return bucket, true, nil
+}
+
+// SetReminderCursor records the last bucket the dispatcher processed. The
+// cursor only moves forward, so an overlapping run cannot move it back.
+func SetReminderCursor(processed time.Time) error {
+	_, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(os.Getenv("REMINDERS_TABLE")),
+		Key:                 reminderCursorItemKey(),
+		UpdateExpression:    aws.String("SET #processed = :processed"),
+		ConditionExpression: aws.String("attribute_not_exists(#processed) OR #processed < :processed"),
+		ExpressionAttributeNames: map[string]string{
+			"#processed": "processed",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":processed": &types.AttributeValueMemberS{Value: ReminderBucket(processed)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// reminderCursorItemKey builds the primary key of the dispatcher's cursor
+func reminderCursorItemKey() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"bucket": &types.AttributeValueMemberS{Value: reminderCursorKey},
+		"noteId": &types.AttributeValueMemberS{Value: reminderCursorKey},
+	}
+}
+
+// reminderKey builds the primary key of a reminders index entry
+func reminderKey(reminder models.Reminder) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"bucket": &types.AttributeValueMemberS{Value: reminder.Bucket},
+		"noteId": &types.AttributeValueMemberS{Value: reminder.NoteID},
+	}
+}
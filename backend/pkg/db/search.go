@@ -0,0 +1,382 @@
+package db
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/envelope"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/search"
+)
+
+const (
+	batchWriteLimit = 25  // Maximum number of requests in a BatchWriteItem call
+	batchGetLimit   = 100 // Maximum number of keys in a BatchGetItem call
+)
+
+// Kinds of index entries, stems and the words that stemming changes, which
+// are looked up apart
+const (
+	stemKind = "t:"
+	wordKind = "w:"
+)
+
+// wordKeyPrefix started the sort keys of word entries before the index was
+// blind, see legacySearchKeys
+const wordKeyPrefix = "~"
+
+// maxPrefixLength is the number of characters of the longest prefix a term
+// is indexed under, a longer query word is looked up by its first
+// maxPrefixLength characters and the entries found are filtered
+const maxPrefixLength = 6
+
+// searchEntry is one posting of the inverted index: a term found in a note.
+// The index is blind: every prefix of a term up to maxPrefixLength has an
+// entry whose sort key is the token of the prefix, the note ID and the token
+// of the term, see envelope.BlindIndex. Words that stemming changes have
+// entries of their own, keyed by the word. Term and Word are encrypted, they
+// are only read to rank what a lookup found.
+type searchEntry struct {
+	UserID      string `dynamodbav:"userId"`
+	TermNoteID  string `dynamodbav:"termNoteId"`
+	Term        string `dynamodbav:"term"`
+	Word        string `dynamodbav:"word,omitempty"`
+	NoteID      string `dynamodbav:"noteId"`
+	TitleFreq   int    `dynamodbav:"titleFreq"`
+	ContentFreq int    `dynamodbav:"contentFreq"`
+}
+
+// lookupKey is the start of the sort keys of a user's entries under a prefix
+func lookupKey(userID string, kind string, prefix string) string {
+	return envelope.DefaultIndex.Token(userID, kind+prefix) + "#"
+}
+
+// searchKeys builds the sort keys of the entries of a term or word of a note,
+// one under each of its prefixes
+func searchKeys(userID string, noteID string, kind string, value string) []string {
+	token := envelope.DefaultIndex.Token(userID, kind+value)
+	var keys []string
+	for _, prefix := range indexedPrefixes(value) {
+		keys = append(keys, lookupKey(userID, kind, prefix)+noteID+"#"+token)
+	}
+	return keys
+}
+
+// indexedPrefixes returns the prefixes of a term up to maxPrefixLength
+// characters, shortest first
+func indexedPrefixes(value string) []string {
+	var prefixes []string
+	for i := range value {
+		if i > 0 {
+			prefixes = append(prefixes, value[:i])
+		}
+		if len(prefixes) == maxPrefixLength {
+			return prefixes
+		}
+	}
+	return append(prefixes, value)
+}
+
+// lookupPrefix cuts a query term to the longest prefix terms are indexed under
+func lookupPrefix(value string) string {
+	prefixes := indexedPrefixes(value)
+	return prefixes[len(prefixes)-1]
+}
+
+// IndexNote adds the terms of a note's title and content, or checklist items,
+// to the search index
+func IndexNote(note models.Note) error {
+	titleTerms := search.Terms(note.Title)
+	contentTerms := search.Terms(note.Text())
+
+	var entries []searchEntry
+	for term := range mergeTerms(titleTerms, contentTerms) {
+		for _, key := range searchKeys(note.UserID, note.NoteID, stemKind, term) {
+			entries = append(entries, searchEntry{
+				TermNoteID:  key,
+				Term:        term,
+				TitleFreq:   titleTerms[term],
+				ContentFreq: contentTerms[term],
+			})
+		}
+	}
+	for word, term := range noteWords(note) {
+		for _, key := range searchKeys(note.UserID, note.NoteID, wordKind, word) {
+			entries = append(entries, searchEntry{
+				TermNoteID:  key,
+				Term:        term,
+				Word:        word,
+				TitleFreq:   titleTerms[term],
+				ContentFreq: contentTerms[term],
+			})
+		}
+	}
+
+	ctx := context.TODO()
+	requests := make([]types.WriteRequest, 0, len(entries))
+	for _, entry := range entries {
+		entry.UserID = note.UserID
+		entry.NoteID = note.NoteID
+
+		var err error
+		if entry.Term, err = envelope.Default.EncryptIndexTerm(ctx, entry.UserID, entry.TermNoteID, entry.Term); err != nil {
+			return err
+		}
+		if entry.Word != "" {
+			if entry.Word, err = envelope.Default.EncryptIndexTerm(ctx, entry.UserID, entry.TermNoteID, entry.Word); err != nil {
+				return err
+			}
+		}
+
+		item, err := attributevalue.MarshalMap(entry)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+	}
+
+	return batchWrite(os.Getenv("SEARCH_TABLE"), requests)
+}
+
+// RemoveNoteFromIndex removes the terms of a note from the search index,
+// along with the entries the note had before the index was blind
+func RemoveNoteFromIndex(note models.Note) error {
+	var keys []string
+	for term := range mergeTerms(search.Terms(note.Title), search.Terms(note.Text())) {
+		keys = append(keys, searchKeys(note.UserID, note.NoteID, stemKind, term)...)
+	}
+	for word := range noteWords(note) {
+		keys = append(keys, searchKeys(note.UserID, note.NoteID, wordKind, word)...)
+	}
+	keys = append(keys, legacySearchKeys(note)...)
+
+	requests := make([]types.WriteRequest, 0, len(keys))
+	for _, key := range keys {
+		requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+			Key: map[string]types.AttributeValue{
+				"userId":     &types.AttributeValueMemberS{Value: note.UserID},
+				"termNoteId": &types.AttributeValueMemberS{Value: key},
+			},
+		}})
+	}
+
+	return batchWrite(os.Getenv("SEARCH_TABLE"), requests)
+}
+
+// legacySearchKeys builds the sort keys the entries of a note had when the
+// index stored terms in plaintext, the term or the word after wordKeyPrefix
+// followed by the note ID. Rebuilding the index with reencrypt_notes removes
+// those of every note.
+func legacySearchKeys(note models.Note) []string {
+	var keys []string
+	for term := range mergeTerms(search.Terms(note.Title), search.Terms(note.Text())) {
+		keys = append(keys, term+"#"+note.NoteID)
+	}
+	for word := range noteWords(note) {
+		keys = append(keys, wordKeyPrefix+word+"#"+note.NoteID)
+	}
+	return keys
+}
+
+// noteWords returns the words of a note's title and content that stemming
+// changes, with their stem
+func noteWords(note models.Note) map[string]string {
+	words := search.Words(note.Title)
+	for word, term := range search.Words(note.Text()) {
+		words[word] = term
+	}
+	return words
+}
+
+// SearchNotes looks up the notes of a user matching a query. Every query term
+// matches indexed terms exactly or by prefix, and results are ranked by
+// search.Rank.
+func SearchNotes(userID string, query string, limit int) ([]models.SearchResult, error) {
+	queryTerms := search.QueryTerms(query)
+	if len(queryTerms) == 0 {
+		return []models.SearchResult{}, nil
+	}
+
+	// The stems and the words starting like each query term, search.Rank
+	// drops those that only share the looked up prefix
+	postings := make([][]search.Posting, len(queryTerms))
+	for i, term := range queryTerms {
+		var prefixes []string
+		if prefix := term.LookupPrefix(); prefix != "" {
+			prefixes = append(prefixes, lookupKey(userID, stemKind, lookupPrefix(prefix)))
+		}
+		prefixes = append(prefixes, lookupKey(userID, wordKind, lookupPrefix(term.Word)))
+		for _, prefix := range prefixes {
+			found, err := lookupPostings(userID, prefix)
+			if err != nil {
+				return nil, err
+			}
+			postings[i] = append(postings[i], found...)
+		}
+	}
+	noteIDs, scores := search.Rank(queryTerms, postings, limit)
+
+	notes, err := getNotesByIDs(noteIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.SearchResult, 0, len(noteIDs))
+	for _, noteID := range noteIDs {
+		note, ok := notes[noteID]
+		if !ok {
+			continue // Stale index entry for a deleted note
+		}
+		results = append(results, models.SearchResult{
+			Note:           note,
+			Score:          scores[noteID],
+			TitleHighlight: search.Highlight(note.Title, queryTerms),
+			Snippet:        search.Snippet(note.Text(), queryTerms),
+		})
+	}
+
+	return results, nil
+}
+
+// lookupPostings returns the index entries of a user whose sort key starts
+// with a prefix
+func lookupPostings(userID string, prefix string) ([]search.Posting, error) {
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("SEARCH_TABLE")),
+		KeyConditionExpression: aws.String("userId = :userId AND begins_with(termNoteId, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userID},
+			":prefix": &types.AttributeValueMemberS{Value: prefix},
+		},
+	}
+
+	var postings []search.Posting
+	paginator := dynamodb.NewQueryPaginator(dynamoClient, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []searchEntry
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &entries); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Term, err = envelope.Default.DecryptIndexTerm(context.TODO(), userID, entry.TermNoteID, entry.Term); err != nil {
+				return nil, err
+			}
+			if entry.Word != "" {
+				if entry.Word, err = envelope.Default.DecryptIndexTerm(context.TODO(), userID, entry.TermNoteID, entry.Word); err != nil {
+					return nil, err
+				}
+			}
+			postings = append(postings, search.Posting{
+				NoteID:      entry.NoteID,
+				Term:        entry.Term,
+				Word:        entry.Word,
+				TitleFreq:   entry.TitleFreq,
+				ContentFreq: entry.ContentFreq,
+			})
+		}
+	}
+
+	return postings, nil
+}
+
+// mergeTerms returns the union of the terms in a and b
+func mergeTerms(a map[string]int, b map[string]int) map[string]bool {
+	terms := make(map[string]bool, len(a)+len(b))
+	for term := range a {
+		terms[term] = true
+	}
+	for term := range b {
+		terms[term] = true
+	}
+	return terms
+}
+
+// getNotesByIDs loads the notes of a user by ID with BatchGetItem
+func getNotesByIDs(noteIDs []string, userID string) (map[string]models.Note, error) {
+	keys := make([]noteKey, 0, len(noteIDs))
+	for _, noteID := range noteIDs {
+		keys = append(keys, noteKey{NoteID: noteID, UserID: userID})
+	}
+	return getNotesByKeys(keys)
+}
+
+// noteKey is the primary key of a note
+type noteKey struct {
+	NoteID string
+	UserID string
+}
+
+// getNotesByKeys loads notes by primary key with BatchGetItem, keyed by note ID
+func getNotesByKeys(noteKeys []noteKey) (map[string]models.Note, error) {
+	notesTable := os.Getenv("NOTES_TABLE")
+
+	notes := make(map[string]models.Note, len(noteKeys))
+	for start := 0; start < len(noteKeys); start += batchGetLimit {
+		end := start + batchGetLimit
+		if end > len(noteKeys) {
+			end = len(noteKeys)
+		}
+
+		keys := make([]map[string]types.AttributeValue, 0, end-start)
+		for _, key := range noteKeys[start:end] {
+			keys = append(keys, map[string]types.AttributeValue{
+				"noteId": &types.AttributeValueMemberS{Value: key.NoteID},
+				"userId": &types.AttributeValueMemberS{Value: key.UserID},
+			})
+		}
+
+		request := map[string]types.KeysAndAttributes{notesTable: {Keys: keys}}
+		for len(request) > 0 {
+			result, err := dynamoClient.BatchGetItem(context.TODO(), &dynamodb.BatchGetItemInput{
+				RequestItems: request,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			var page []models.Note
+			if err := unmarshalNotes(result.Responses[notesTable], &page); err != nil {
+				return nil, err
+			}
+			for _, note := range page {
+				notes[note.NoteID] = note
+			}
+
+			request = result.UnprocessedKeys
+		}
+	}
+
+	return notes, nil
+}
+
+// batchWrite sends write requests in chunks of 25, retrying unprocessed items
+func batchWrite(table string, requests []types.WriteRequest) error {
+	for start := 0; start < len(requests); start += batchWriteLimit {
+		end := start + batchWriteLimit
+		if end > len(requests) {
+			end = len(requests)
+		}
+
+		pending := map[string][]types.WriteRequest{table: requests[start:end]}
+		for len(pending) > 0 {
+			result, err := dynamoClient.BatchWriteItem(context.TODO(), &dynamodb.BatchWriteItemInput{
+				RequestItems: pending,
+			})
+			if err != nil {
+				return err
+			}
+			pending = result.UnprocessedItems
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,344 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// shareLinkTokenBytes is the amount of randomness in a share link token
+const shareLinkTokenBytes = 32
+
+// Wrong passwords in a row lock a protected link for a while, so that its
+// password cannot be guessed at the speed of the API
+const (
+	ShareLinkMaxFailures = 5
+	ShareLinkLockout     = 15 * time.Minute
+)
+
+// Errors returned when resolving a share link
+var (
+	ErrShareLinkNotFound = errors.New("share link not found")
+	ErrShareLinkExpired  = errors.New("share link expired")
+	ErrShareLinkPassword = errors.New("share link password required or incorrect")
+)
+
+// ShareLinkLockedError is returned for a link locked after too many wrong
+// passwords, even when the password is right
+type ShareLinkLockedError struct {
+	Until time.Time
+}
+
+func (e *ShareLinkLockedError) Error() string {
+	return "share link locked until " + e.Until.UTC().Format(time.RFC3339)
+}
+
+// CreateShareLink creates a share link with an unguessable token for a note.
+// A zero expiresAt creates a link that never expires. Only the SHA-256 of the
+// token is stored, the token itself is in the returned link and nowhere else.
+func CreateShareLink(noteID string, ownerID string, expiresAt time.Time, password string) (*models.ShareLink, error) {
+	shareLinksTable := os.Getenv("SHARE_LINKS_TABLE")
+
+	token, err := newShareLinkToken()
+	if err != nil {
+		return nil, err
+	}
+
+	link := models.ShareLink{
+		ID:        shareLinkID(token),
+		Token:     token,
+		NoteID:    noteID,
+		OwnerID:   ownerID,
+		CreatedAt: models.GetTimeNow(),
+	}
+
+	if !expiresAt.IsZero() {
+		link.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+		link.TTL = expiresAt.Unix()
+	}
+
+	if password != "" {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		link.PasswordHash = string(hashedPassword)
+		link.HasPassword = true
+	}
+
+	item, err := attributevalue.MarshalMap(link)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           aws.String(shareLinksTable),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(linkId)"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// GetShareLink gets a share link by its token
+func GetShareLink(token string) (*models.ShareLink, error) {
+	return getShareLinkByID(shareLinkID(token))
+}
+
+// getShareLinkByID gets a share link by the hash of its token
+func getShareLinkByID(linkID string) (*models.ShareLink, error) {
+	shareLinksTable := os.Getenv("SHARE_LINKS_TABLE")
+
+	result, err := dynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(shareLinksTable),
+		Key: map[string]types.AttributeValue{
+			"linkId": &types.AttributeValueMemberS{Value: linkID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, ErrShareLinkNotFound
+	}
+
+	var link models.ShareLink
+	err = attributevalue.UnmarshalMap(result.Item, &link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// GetSharedNoteByLink resolves a share link to the note it publishes, checking
+// expiry and password. Password attempts are counted before the password is
+// checked, and ShareLinkMaxFailures wrong ones in a row lock the link for
+// ShareLinkLockout.
+func GetSharedNoteByLink(token string, password string) (*models.Note, error) {
+	link, err := GetShareLink(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// DynamoDB TTL deletion is lazy, so check expiry here as well
+	now := time.Now()
+	if link.TTL > 0 && now.Unix() >= link.TTL {
+		return nil, ErrShareLinkExpired
+	}
+
+	if link.HasPassword {
+		if now.Unix() < link.LockedUntil {
+			return nil, &ShareLinkLockedError{Until: time.Unix(link.LockedUntil, 0)}
+		}
+		if password == "" {
+			return nil, ErrShareLinkPassword
+		}
+		attempts, err := reserveShareLinkAttempt(link.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			if attempts >= ShareLinkMaxFailures {
+				if err := lockShareLink(link.ID, now); err != nil {
+					return nil, err
+				}
+			}
+			return nil, ErrShareLinkPassword
+		}
+		if err := resetShareLinkFailures(link.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	note, err := GetNoteByID(link.NoteID, link.OwnerID)
+	if err != nil {
+		return nil, ErrShareLinkNotFound
+	}
+
+	return note, nil
+}
+
+// reserveShareLinkAttempt counts a password attempt on a link before the
+// password is checked, and returns the attempts in a row so far. The count is
+// only added while the link is under ShareLinkMaxFailures and not locked, so
+// that guesses sent in parallel cannot check more passwords than that.
+func reserveShareLinkAttempt(linkID string, now time.Time) (int, error) {
+	result, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(os.Getenv("SHARE_LINKS_TABLE")),
+		Key: map[string]types.AttributeValue{
+			"linkId": &types.AttributeValueMemberS{Value: linkID},
+		},
+		UpdateExpression: aws.String("ADD failedAttempts :one"),
+		ConditionExpression: aws.String("attribute_exists(linkId)" +
+			" AND (attribute_not_exists(failedAttempts) OR failedAttempts < :max)" +
+			" AND (attribute_not_exists(lockedUntil) OR lockedUntil < :now)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one": &types.AttributeValueMemberN{Value: "1"},
+			":max": &types.AttributeValueMemberN{Value: fmt.Sprint(ShareLinkMaxFailures)},
+			":now": &types.AttributeValueMemberN{Value: fmt.Sprint(now.Unix())},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionErr) {
+			return 0, err
+		}
+		// Deleted, locked, or out of attempts with the last ones still
+		// being checked
+		link, err := getShareLinkByID(linkID)
+		if err != nil {
+			return 0, err
+		}
+		if now.Unix() < link.LockedUntil {
+			return 0, &ShareLinkLockedError{Until: time.Unix(link.LockedUntil, 0)}
+		}
+		return 0, &ShareLinkLockedError{Until: now.Add(ShareLinkLockout)}
+	}
+
+	var counted struct {
+		FailedAttempts int `dynamodbav:"failedAttempts"`
+	}
+	if err := attributevalue.UnmarshalMap(result.Attributes, &counted); err != nil {
+		return 0, err
+	}
+	return counted.FailedAttempts, nil
+}
+
+// lockShareLink locks a link for ShareLinkLockout after its last allowed
+// wrong password. The count starts over once the lock is set.
+func lockShareLink(linkID string, now time.Time) error {
+	_, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(os.Getenv("SHARE_LINKS_TABLE")),
+		Key: map[string]types.AttributeValue{
+			"linkId": &types.AttributeValueMemberS{Value: linkID},
+		},
+		UpdateExpression: aws.String("SET lockedUntil = :until REMOVE failedAttempts"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":until": &types.AttributeValueMemberN{Value: fmt.Sprint(now.Add(ShareLinkLockout).Unix())},
+		},
+	})
+	return err
+}
+
+// resetShareLinkFailures clears the attempt count of a link after the right
+// password
+func resetShareLinkFailures(linkID string) error {
+	_, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(os.Getenv("SHARE_LINKS_TABLE")),
+		Key: map[string]types.AttributeValue{
+			"linkId": &types.AttributeValueMemberS{Value: linkID},
+		},
+		UpdateExpression: aws.String("REMOVE failedAttempts, lockedUntil"),
+	})
+	return err
+}
+
+// GetShareLinksByOwner lists the share links of a user, optionally only those of one note
+func GetShareLinksByOwner(ownerID string, noteID string) ([]models.ShareLink, error) {
+	shareLinksTable := os.Getenv("SHARE_LINKS_TABLE")
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(shareLinksTable),
+		IndexName:              aws.String("OwnerIdIndex"),
+		KeyConditionExpression: aws.String("ownerId = :ownerId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ownerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		ScanIndexForward: aws.Bool(false), // Newest link first
+	}
+
+	if noteID != "" {
+		params.FilterExpression = aws.String("noteId = :noteId")
+		params.ExpressionAttributeValues[":noteId"] = &types.AttributeValueMemberS{Value: noteID}
+	}
+
+	result, err := dynamoClient.Query(context.TODO(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	links := []models.ShareLink{}
+	err = attributevalue.UnmarshalListOfMaps(result.Items, &links)
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// RevokeShareLink deletes a share link owned by ownerID, named by its ID
+func RevokeShareLink(linkID string, ownerID string) error {
+	shareLinksTable := os.Getenv("SHARE_LINKS_TABLE")
+
+	_, err := dynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: aws.String(shareLinksTable),
+		Key: map[string]types.AttributeValue{
+			"linkId": &types.AttributeValueMemberS{Value: linkID},
+		},
+		ConditionExpression: aws.String("ownerId = :ownerId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ownerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return ErrShareLinkNotFound
+		}
+	}
+
+	return err
+}
+
+// RevokeShareLinksForNote deletes every share link of a note
+func RevokeShareLinksForNote(noteID string, ownerID string) error {
+	links, err := GetShareLinksByOwner(ownerID, noteID)
+	if err != nil {
+		return err
+	}
+
+	var requests []types.WriteRequest
+	for _, link := range links {
+		requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+			Key: map[string]types.AttributeValue{
+				"linkId": &types.AttributeValueMemberS{Value: link.ID},
+			},
+		}})
+	}
+
+	return batchWrite(os.Getenv("SHARE_LINKS_TABLE"), requests)
+}
+
+// shareLinkID is the stored key of a share link, the SHA-256 of its token, so
+// that the table does not hold the tokens that open the links
+func shareLinkID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// newShareLinkToken returns a random URL-safe token
+func newShareLinkToken() (string, error) {
+	buf := make([]byte, shareLinkTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating share link token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,244 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Permissions a user can hold on a note
+const (
+	PermissionRead  = "read"
+	PermissionEdit  = "edit"
+	PermissionOwner = "owner"
+)
+
+// ErrForbidden is returned when a user can see a note but not change it
+var ErrForbidden = errors.New("permission denied")
+
+// NoteAccess describes what a user may do with a note
+type NoteAccess struct {
+	Note       *models.Note
+	OwnerID    string
+	Permission string
+}
+
+// CanEdit reports whether the note content may be changed
+func (a *NoteAccess) CanEdit() bool {
+	return a.Permission == PermissionOwner || a.Permission == PermissionEdit
+}
+
+// IsOwner reports whether the user owns the note
+func (a *NoteAccess) IsOwner() bool {
+	return a.Permission == PermissionOwner
+}
+
+// GetNoteAccess loads a note on behalf of a user, either as its owner or
+// through a share. ErrNoteNotFound is returned when the user has no access.
+func GetNoteAccess(noteID string, userID string) (*NoteAccess, error) {
+	note, err := GetNoteByID(noteID, userID)
+	if err == nil {
+		return &NoteAccess{Note: note, OwnerID: userID, Permission: PermissionOwner}, nil
+	}
+
+	share, err := GetShare(noteID, userID)
+	if err != nil {
+		return nil, ErrNoteNotFound
+	}
+
+	note, err = GetNoteByID(noteID, share.OwnerID)
+	if err != nil {
+		return nil, ErrNoteNotFound
+	}
+
+	return &NoteAccess{Note: note, OwnerID: share.OwnerID, Permission: share.Permission}, nil
+}
+
+// GetShare gets the share of a note with a user
+func GetShare(noteID string, sharedWithUserID string) (*models.Share, error) {
+	sharesTable := os.Getenv("SHARES_TABLE")
+
+	result, err := dynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(sharesTable),
+		Key: map[string]types.AttributeValue{
+			"noteId":           &types.AttributeValueMemberS{Value: noteID},
+			"sharedWithUserId": &types.AttributeValueMemberS{Value: sharedWithUserID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, fmt.Errorf("share not found")
+	}
+
+	var share models.Share
+	err = attributevalue.UnmarshalMap(result.Item, &share)
+	if err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+// GetSharesByNoteID lists who the note of an owner is shared with
+func GetSharesByNoteID(noteID string, ownerID string) ([]models.Share, error) {
+	sharesTable := os.Getenv("SHARES_TABLE")
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(sharesTable),
+		KeyConditionExpression: aws.String("noteId = :noteId"),
+		FilterExpression:       aws.String("ownerId = :ownerId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":noteId":  &types.AttributeValueMemberS{Value: noteID},
+			":ownerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+	}
+
+	result, err := dynamoClient.Query(context.TODO(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := []models.Share{}
+	err = attributevalue.UnmarshalListOfMaps(result.Items, &shares)
+	if err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+// ShareNote shares a note with another user, replacing any earlier share
+// of the same owner with the same user
+func ShareNote(share *models.Share) error {
+	sharesTable := os.Getenv("SHARES_TABLE")
+
+	share.CreatedAt = models.GetTimeNow()
+
+	item, err := attributevalue.MarshalMap(share)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           aws.String(sharesTable),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(noteId) OR ownerId = :ownerId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ownerId": &types.AttributeValueMemberS{Value: share.OwnerID},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return ErrForbidden
+		}
+	}
+
+	return err
+}
+
+// RevokeShare removes the share of an owner's note with a user
+func RevokeShare(noteID string, sharedWithUserID string, ownerID string) error {
+	sharesTable := os.Getenv("SHARES_TABLE")
+
+	_, err := dynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: aws.String(sharesTable),
+		Key: map[string]types.AttributeValue{
+			"noteId":           &types.AttributeValueMemberS{Value: noteID},
+			"sharedWithUserId": &types.AttributeValueMemberS{Value: sharedWithUserID},
+		},
+		ConditionExpression: aws.String("attribute_exists(noteId) AND ownerId = :ownerId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ownerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return fmt.Errorf("share not found")
+		}
+	}
+
+	return err
+}
+
+// RevokeAllShares removes every share of an owner's note
+func RevokeAllShares(noteID string, ownerID string) error {
+	shares, err := GetSharesByNoteID(noteID, ownerID)
+	if err != nil {
+		return err
+	}
+
+	var requests []types.WriteRequest
+	for _, share := range shares {
+		requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+			Key: map[string]types.AttributeValue{
+				"noteId":           &types.AttributeValueMemberS{Value: share.NoteID},
+				"sharedWithUserId": &types.AttributeValueMemberS{Value: share.SharedWithUserID},
+			},
+		}})
+	}
+
+	return batchWrite(os.Getenv("SHARES_TABLE"), requests)
+}
+
+// GetNotesSharedWithUser lists the notes other users shared with userID
+func GetNotesSharedWithUser(userID string) ([]models.SharedNote, error) {
+	sharesTable := os.Getenv("SHARES_TABLE")
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(sharesTable),
+		IndexName:              aws.String("SharedWithIndex"),
+		KeyConditionExpression: aws.String("sharedWithUserId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userID},
+		},
+		ScanIndexForward: aws.Bool(false), // Most recently shared first
+	}
+
+	result, err := dynamoClient.Query(context.TODO(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []models.Share
+	err = attributevalue.UnmarshalListOfMaps(result.Items, &shares)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]noteKey, 0, len(shares))
+	for _, share := range shares {
+		keys = append(keys, noteKey{NoteID: share.NoteID, UserID: share.OwnerID})
+	}
+
+	notes, err := getNotesByKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedNotes := make([]models.SharedNote, 0, len(shares))
+	for _, share := range shares {
+		note, ok := notes[share.NoteID]
+		if !ok {
+			continue // The owner deleted the note
+		}
+		sharedNotes = append(sharedNotes, models.SharedNote{
+			Note:       note,
+			Permission: share.Permission,
+			SharedAt:   share.CreatedAt,
+		})
+	}
+
+	return sharedNotes, nil
+}
@@ -0,0 +1,300 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Sync settings
+const (
+	syncCursorLayout = "2006-01-02T15:04:05.000000000Z" // Fixed width so that cursors sort as strings
+	syncRetention    = 30 * 24 * time.Hour              // How long the change log is kept, older tokens need a full sync
+	syncSettle       = 5 * time.Second                  // Changes logged this recently are sent again on the next pull
+	syncPageSize     = 500
+)
+
+// Errors returned by the sync functions
+var (
+	ErrVersionConflict  = errors.New("note was changed by another client")
+	ErrNoteExists       = errors.New("note already exists")
+	ErrNoteIDTaken      = errors.New("note ID is used by another note")
+	ErrInvalidSyncToken = errors.New("invalid sync token")
+	ErrSyncTokenExpired = errors.New("sync token expired, a full sync is required")
+)
+
+// LogNoteChange adds a change of a note to its owner's change log
+func LogNoteChange(note models.Note, deleted bool) error {
+	now := time.Now().UTC()
+	item, err := attributevalue.MarshalMap(models.SyncEntry{
+		UserID:  note.UserID,
+		Seq:     now.Format(syncCursorLayout) + "#" + note.NoteID,
+		NoteID:  note.NoteID,
+		Deleted: deleted,
+		Version: note.Version,
+		TTL:     now.Add(syncRetention).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(os.Getenv("SYNC_LOG_TABLE")),
+		Item:      item,
+	})
+
+	return err
+}
+
+// GetChangesSince returns the notes of a user that changed since a sync
+// token, or all of them when the token is empty. Changes logged shortly
+// before the pull are sent again on the next one, clients apply them by
+// version so that a repeated change is a no-op.
+//
+// A full sync replaces the client's copy, so it reads every page of the
+// user's notes. It includes archived notes, like the deltas do, and clients
+// hide them by their flag.
+func GetChangesSince(userID string, token string) (*models.SyncChanges, error) {
+	now := time.Now().UTC()
+	settled := now.Add(-syncSettle).Format(syncCursorLayout)
+
+	if token == "" {
+		notes, err := GetNotesByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if notes == nil {
+			notes = []models.Note{}
+		}
+		return &models.SyncChanges{
+			Notes:     notes,
+			Deleted:   []models.Tombstone{},
+			SyncToken: encodeSyncCursor(settled),
+			Full:      true,
+		}, nil
+	}
+
+	cursor, err := decodeSyncCursor(token)
+	if err != nil {
+		return nil, err
+	}
+	if cursor < now.Add(-syncRetention).Format(syncCursorLayout) {
+		return nil, ErrSyncTokenExpired
+	}
+
+	result, err := dynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("SYNC_LOG_TABLE")),
+		KeyConditionExpression: aws.String("userId = :userId AND seq > :cursor"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userID},
+			":cursor": &types.AttributeValueMemberS{Value: cursor},
+		},
+		ConsistentRead: aws.Bool(true),
+		Limit:          aws.Int32(syncPageSize),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []models.SyncEntry
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &entries); err != nil {
+		return nil, err
+	}
+
+	// Only the latest change of each note counts
+	latest := make(map[string]models.SyncEntry)
+	var order []string
+	for _, entry := range entries {
+		if _, seen := latest[entry.NoteID]; !seen {
+			order = append(order, entry.NoteID)
+		}
+		latest[entry.NoteID] = entry
+	}
+
+	var liveIDs []string
+	for _, noteID := range order {
+		if !latest[noteID].Deleted {
+			liveIDs = append(liveIDs, noteID)
+		}
+	}
+	notes, err := getNotesByIDs(liveIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &models.SyncChanges{
+		Notes:   []models.Note{},
+		Deleted: []models.Tombstone{},
+		HasMore: len(result.LastEvaluatedKey) > 0,
+	}
+	for _, noteID := range order {
+		// A note missing here was deleted after its last change was logged
+		if note, ok := notes[noteID]; ok && !latest[noteID].Deleted {
+			changes.Notes = append(changes.Notes, note)
+			continue
+		}
+		changes.Deleted = append(changes.Deleted, models.Tombstone{
+			NoteID:    noteID,
+			DeletedAt: syncEntryTime(latest[noteID]),
+		})
+	}
+
+	// A full page continues right after its last entry, otherwise the next
+	// pull starts shortly before now so that late log writes are not missed
+	next := cursor
+	if changes.HasMore {
+		next = strings.SplitN(entries[len(entries)-1].Seq, "#", 2)[0]
+	} else if settled > next {
+		next = settled
+	}
+	changes.SyncToken = encodeSyncCursor(next)
+
+	return changes, nil
+}
+
+// CreateSyncedNote creates a note pushed by a client. The client may choose
+// the note ID, so that pushing the same change twice fails with
+// ErrNoteExists instead of creating a copy. An ID another user's note has
+// fails with ErrNoteIDTaken: shares, attachments and reminders are keyed by
+// note ID, and must only ever point at one note. It fails with a LimitError
+// when the owner is out of quota.
+func CreateSyncedNote(note *models.Note) error {
+	if note.NoteID == "" {
+		note.NoteID = uuid.New().String()
+	} else {
+		ownerID, err := noteIDOwner(note.NoteID)
+		if err != nil {
+			return err
+		}
+		if ownerID == note.UserID {
+			return ErrNoteExists
+		}
+		if ownerID != "" {
+			return ErrNoteIDTaken
+		}
+	}
+	note.CreatedAt = models.GetTimeNow()
+	note.UpdatedAt = note.CreatedAt
+	note.Version = 1
+	if note.Type == NoteTypeChecklist {
+		note.Items = prepareChecklist(note.Items)
+	}
+
+	item, err := marshalNote(*note)
+	if err != nil {
+		return err
+	}
+
+	err = writeNote(types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(os.Getenv("NOTES_TABLE")),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(noteId)"),
+		},
+	}, note.UserID, 1, NoteSize(*note))
+	if errors.Is(err, errNoteCondition) {
+		return ErrNoteExists
+	}
+	return err
+}
+
+// noteIDOwner returns the user owning a note with an ID, empty when there is
+// none
+func noteIDOwner(noteID string) (string, error) {
+	result, err := dynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("NOTES_TABLE")),
+		KeyConditionExpression: aws.String("noteId = :noteId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":noteId": &types.AttributeValueMemberS{Value: noteID},
+		},
+		ProjectionExpression: aws.String("userId"),
+		ConsistentRead:       aws.Bool(true),
+		Limit:                aws.Int32(1),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(result.Items) == 0 {
+		return "", nil
+	}
+
+	owner, ok := result.Items[0]["userId"].(*types.AttributeValueMemberS)
+	if !ok {
+		return "", fmt.Errorf("note %s has no owner", noteID)
+	}
+	return owner.Value, nil
+}
+
+// DeleteNoteAtVersion deletes a note only if it is still at version. It fails
+// with ErrVersionConflict when the note changed since, deleting a note that
+// is gone already succeeds. Its size is given back to the owner's quotas.
+func DeleteNoteAtVersion(noteID string, userID string, version int64) error {
+	note, err := GetNoteByID(noteID, userID)
+	if errors.Is(err, ErrNoteNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if note.Version != version {
+		return ErrVersionConflict
+	}
+
+	err = deleteNote(*note)
+	if errors.Is(err, errNoteCondition) {
+		if _, getErr := GetNoteByID(noteID, userID); errors.Is(getErr, ErrNoteNotFound) {
+			return nil
+		}
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// versionCondition builds the condition that a note exists at version.
+// Version zero matches notes written before versioning.
+func versionCondition(version int64) (*string, map[string]types.AttributeValue) {
+	if version == 0 {
+		return aws.String("attribute_exists(noteId) AND attribute_not_exists(version)"), nil
+	}
+	return aws.String("attribute_exists(noteId) AND version = :version"), map[string]types.AttributeValue{
+		":version": &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+	}
+}
+
+// syncEntryTime returns the time a change was logged as RFC3339
+func syncEntryTime(entry models.SyncEntry) string {
+	t, err := time.Parse(syncCursorLayout, strings.SplitN(entry.Seq, "#", 2)[0])
+	if err != nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// encodeSyncCursor turns a log position into an opaque sync token
+func encodeSyncCursor(cursor string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
+
+// decodeSyncCursor reads the log position of a sync token
+func decodeSyncCursor(token string) (string, error) {
+	cursor, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", ErrInvalidSyncToken
+	}
+	if _, err := time.Parse(syncCursorLayout, string(cursor)); err != nil {
+		return "", ErrInvalidSyncToken
+	}
+	return string(cursor), nil
+}
@@ -0,0 +1,467 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// MaxWebhooksPerUser bounds the webhooks of a user, each note change is
+// queued once for every one of them
+const MaxWebhooksPerUser = 10
+
+// pendingQueue is the PendingIndex hash key of deliveries waiting for an attempt
+const pendingQueue = "pending"
+
+// Errors returned by the webhook functions
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrDeliveryExists  = errors.New("delivery already exists")
+	ErrDeliveryClaimed = errors.New("delivery already claimed")
+)
+
+// CreateWebhook registers a webhook with a new ID and signing secret. It
+// fails with a LimitError when the user has MaxWebhooksPerUser webhooks
+// already, counted in the same transaction so that concurrent creates cannot
+// go over it.
+func CreateWebhook(webhook *models.Webhook) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
+	webhook.WebhookID = uuid.New().String()
+	webhook.Secret = "whsec_" + hex.EncodeToString(secret)
+	webhook.Active = true
+	webhook.FailureCount = 0
+	webhook.CreatedAt = models.GetTimeNow()
+	webhook.UpdatedAt = webhook.CreatedAt
+
+	item, err := attributevalue.MarshalMap(webhook)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName: aws.String(os.Getenv("WEBHOOKS_TABLE")),
+				Item:      item,
+			}},
+			{Update: &types.Update{
+				TableName:           aws.String(os.Getenv("USERS_TABLE")),
+				Key:                 userKey(webhook.UserID),
+				UpdateExpression:    aws.String("ADD webhookCount :one"),
+				ConditionExpression: aws.String("attribute_exists(userId) AND (attribute_not_exists(webhookCount) OR webhookCount < :max)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":one": &types.AttributeValueMemberN{Value: "1"},
+					":max": &types.AttributeValueMemberN{Value: strconv.Itoa(MaxWebhooksPerUser)},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) && len(canceled.CancellationReasons) == 2 &&
+			aws.ToString(canceled.CancellationReasons[1].Code) == "ConditionalCheckFailed" {
+			return &LimitError{CodeWebhookQuotaExceeded, fmt.Sprintf("at most %d webhooks are allowed", MaxWebhooksPerUser)}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhooksByUserID gets all webhooks of a user
+func GetWebhooksByUserID(userID string) ([]models.Webhook, error) {
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("WEBHOOKS_TABLE")),
+		KeyConditionExpression: aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userID},
+		},
+	}
+
+	var webhooks []models.Webhook
+	paginator := dynamodb.NewQueryPaginator(dynamoClient, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var pageWebhooks []models.Webhook
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageWebhooks); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, pageWebhooks...)
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook gets a webhook of a user by ID
+func GetWebhook(webhookID string, userID string) (*models.Webhook, error) {
+	result, err := dynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(os.Getenv("WEBHOOKS_TABLE")),
+		Key:       webhookKey(webhookID, userID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	var webhook models.Webhook
+	if err := attributevalue.UnmarshalMap(result.Item, &webhook); err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// UpdateWebhook changes the URL, events or active state of a webhook.
+// Activating a webhook clears its failure count.
+func UpdateWebhook(webhookID string, userID string, update models.WebhookUpdate) (*models.Webhook, error) {
+	sets := []string{"#updatedAt = :updatedAt"}
+	var removes []string
+	names := map[string]string{
+		"#webhookId": "webhookId",
+		"#updatedAt": "updatedAt",
+	}
+	values := map[string]types.AttributeValue{
+		":updatedAt": &types.AttributeValueMemberS{Value: models.GetTimeNow()},
+	}
+
+	if update.URL != nil {
+		sets = append(sets, "#url = :url")
+		names["#url"] = "url"
+		values[":url"] = &types.AttributeValueMemberS{Value: *update.URL}
+	}
+	if len(update.Events) > 0 {
+		sets = append(sets, "#events = :events")
+		names["#events"] = "events"
+		values[":events"] = &types.AttributeValueMemberSS{Value: update.Events}
+	}
+	if update.Active != nil {
+		sets = append(sets, "#active = :active")
+		names["#active"] = "active"
+		values[":active"] = &types.AttributeValueMemberBOOL{Value: *update.Active}
+		if *update.Active {
+			sets = append(sets, "#failureCount = :zero")
+			removes = append(removes, "#disabledAt")
+			names["#failureCount"] = "failureCount"
+			names["#disabledAt"] = "disabledAt"
+			values[":zero"] = &types.AttributeValueMemberN{Value: "0"}
+		}
+	}
+
+	updateExpression := "SET " + strings.Join(sets, ", ")
+	if len(removes) > 0 {
+		updateExpression += " REMOVE " + strings.Join(removes, ", ")
+	}
+
+	result, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(os.Getenv("WEBHOOKS_TABLE")),
+		Key:                       webhookKey(webhookID, userID),
+		UpdateExpression:          aws.String(updateExpression),
+		ConditionExpression:       aws.String("attribute_exists(#webhookId)"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ReturnValues:              types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	var webhook models.Webhook
+	if err := attributevalue.UnmarshalMap(result.Attributes, &webhook); err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// DeleteWebhook removes a webhook of a user. Its delivery history expires on its own.
+func DeleteWebhook(webhookID string, userID string) error {
+	_, err := dynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName:           aws.String(os.Getenv("WEBHOOKS_TABLE")),
+		Key:                 webhookKey(webhookID, userID),
+		ConditionExpression: aws.String("attribute_exists(webhookId)"),
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+
+	// Webhooks created before the count was kept would take it below zero
+	_, err = dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(os.Getenv("USERS_TABLE")),
+		Key:                 userKey(userID),
+		UpdateExpression:    aws.String("ADD webhookCount :minusOne"),
+		ConditionExpression: aws.String("webhookCount > :zero"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":minusOne": &types.AttributeValueMemberN{Value: "-1"},
+			":zero":     &types.AttributeValueMemberN{Value: "0"},
+		},
+	})
+	return ignoreConditionFailure(err)
+}
+
+// RecordWebhookSuccess clears the consecutive failure count of a webhook
+func RecordWebhookSuccess(webhookID string, userID string) error {
+	_, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(os.Getenv("WEBHOOKS_TABLE")),
+		Key:                 webhookKey(webhookID, userID),
+		UpdateExpression:    aws.String("SET failureCount = :zero"),
+		ConditionExpression: aws.String("attribute_exists(webhookId)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":zero": &types.AttributeValueMemberN{Value: "0"},
+		},
+	})
+	return ignoreConditionFailure(err)
+}
+
+// RecordWebhookFailure counts a failed delivery attempt and disables the
+// webhook once it failed disableAfter times in a row. It reports whether
+// this call disabled the webhook.
+func RecordWebhookFailure(webhookID string, userID string, disableAfter int) (bool, error) {
+	result, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(os.Getenv("WEBHOOKS_TABLE")),
+		Key:                 webhookKey(webhookID, userID),
+		UpdateExpression:    aws.String("ADD failureCount :one"),
+		ConditionExpression: aws.String("attribute_exists(webhookId)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one": &types.AttributeValueMemberN{Value: "1"},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		return false, ignoreConditionFailure(err)
+	}
+
+	var webhook models.Webhook
+	if err := attributevalue.UnmarshalMap(result.Attributes, &webhook); err != nil {
+		return false, err
+	}
+	if !webhook.Active || webhook.FailureCount < disableAfter {
+		return false, nil
+	}
+
+	// Only one caller gets to disable the webhook
+	_, err = dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(os.Getenv("WEBHOOKS_TABLE")),
+		Key:                 webhookKey(webhookID, userID),
+		UpdateExpression:    aws.String("SET #active = :false, #disabledAt = :now"),
+		ConditionExpression: aws.String("#active = :true"),
+		ExpressionAttributeNames: map[string]string{
+			"#active":     "active",
+			"#disabledAt": "disabledAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":false": &types.AttributeValueMemberBOOL{Value: false},
+			":true":  &types.AttributeValueMemberBOOL{Value: true},
+			":now":   &types.AttributeValueMemberS{Value: models.GetTimeNow()},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// EnqueueDelivery stores a new delivery. It fails with ErrDeliveryExists when
+// the delivery was stored before, which makes enqueueing a replayed stream
+// record a no-op.
+func EnqueueDelivery(delivery *models.WebhookDelivery) error {
+	delivery.Status = DeliveryPending
+	delivery.Queue = pendingQueue
+
+	item, err := marshalDelivery(*delivery)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           aws.String(os.Getenv("WEBHOOK_DELIVERIES_TABLE")),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(deliveryId)"),
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return ErrDeliveryExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetDueDeliveries returns the pending deliveries whose next attempt is due at now
+func GetDueDeliveries(now string) ([]models.WebhookDelivery, error) {
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("WEBHOOK_DELIVERIES_TABLE")),
+		IndexName:              aws.String("PendingIndex"),
+		KeyConditionExpression: aws.String("#queue = :queue AND nextAttemptAt <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#queue": "queue",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":queue": &types.AttributeValueMemberS{Value: pendingQueue},
+			":now":   &types.AttributeValueMemberS{Value: now},
+		},
+	}
+
+	var deliveries []models.WebhookDelivery
+	paginator := dynamodb.NewQueryPaginator(dynamoClient, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var pageDeliveries []models.WebhookDelivery
+		if err := unmarshalDeliveries(page.Items, &pageDeliveries); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, pageDeliveries...)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDelivery moves the next attempt of a pending delivery to leaseUntil so
+// that no other dispatcher picks it up meanwhile. It fails with
+// ErrDeliveryClaimed when the delivery changed since it was read.
+func ClaimDelivery(delivery *models.WebhookDelivery, leaseUntil string) error {
+	_, err := dynamoClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(os.Getenv("WEBHOOK_DELIVERIES_TABLE")),
+		Key:                 deliveryKey(delivery),
+		UpdateExpression:    aws.String("SET nextAttemptAt = :leaseUntil"),
+		ConditionExpression: aws.String("#status = :pending AND nextAttemptAt = :seen"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":leaseUntil": &types.AttributeValueMemberS{Value: leaseUntil},
+			":pending":    &types.AttributeValueMemberS{Value: DeliveryPending},
+			":seen":       &types.AttributeValueMemberS{Value: delivery.NextAttemptAt},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return ErrDeliveryClaimed
+		}
+		return err
+	}
+
+	delivery.NextAttemptAt = leaseUntil
+	return nil
+}
+
+// SaveDelivery stores the outcome of a delivery attempt. Finished deliveries
+// leave the PendingIndex.
+func SaveDelivery(delivery *models.WebhookDelivery) error {
+	if delivery.Status == DeliveryPending {
+		delivery.Queue = pendingQueue
+	} else {
+		delivery.Queue = ""
+		delivery.NextAttemptAt = ""
+	}
+
+	item, err := marshalDelivery(*delivery)
+	if err != nil {
+		return err
+	}
+
+	_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(os.Getenv("WEBHOOK_DELIVERIES_TABLE")),
+		Item:      item,
+	})
+
+	return err
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook, newest first
+func GetWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	result, err := dynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("WEBHOOK_DELIVERIES_TABLE")),
+		IndexName:              aws.String("CreatedAtIndex"),
+		KeyConditionExpression: aws.String("webhookId = :webhookId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":webhookId": &types.AttributeValueMemberS{Value: webhookID},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := unmarshalDeliveries(result.Items, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// webhookKey builds the primary key of a webhook
+func webhookKey(webhookID string, userID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"userId":    &types.AttributeValueMemberS{Value: userID},
+		"webhookId": &types.AttributeValueMemberS{Value: webhookID},
+	}
+}
+
+// deliveryKey builds the primary key of a delivery
+func deliveryKey(delivery *models.WebhookDelivery) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"webhookId":  &types.AttributeValueMemberS{Value: delivery.WebhookID},
+		"deliveryId": &types.AttributeValueMemberS{Value: delivery.DeliveryID},
+	}
+}
+
+// ignoreConditionFailure drops the error of a write whose target is gone
+func ignoreConditionFailure(err error) error {
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return nil
+	}
+	return err
+}
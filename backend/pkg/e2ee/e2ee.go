@@ -0,0 +1,308 @@
+// Package e2ee is the reference implementation of end-to-end encrypted notes.
+//
+// Each user has one or more random 256-bit data keys. A data key is wrapped
+// (encrypted) with a key encryption key derived from the user's passphrase
+// with Argon2id, and only the wrapped key is stored on the server. Note
+// content is encrypted with a data key using AES-256-GCM, authenticating the
+// algorithm and key ID along with the ciphertext. Clients in other languages
+// must produce the same envelopes to interoperate.
+//
+// The server uses this package only to check the shape of envelopes and key
+// records, it never sees a passphrase or an unwrapped key.
+package e2ee
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/omidiyanto/mino/pkg/models"
+	"golang.org/x/crypto/argon2"
+)
+
+// Algorithms
+const (
+	AlgorithmAES256GCM = "AES-256-GCM"
+	KDFArgon2id        = "argon2id"
+)
+
+// Sizes in bytes
+const (
+	KeySize   = 32
+	NonceSize = 12
+	TagSize   = 16
+	SaltSize  = 16
+)
+
+// Default Argon2id parameters, the second recommended option of RFC 9106
+const (
+	DefaultTime    = 3
+	DefaultMemory  = 64 * 1024 // KiB
+	DefaultThreads = 4
+)
+
+// Bounds on Argon2id parameters. The minimum keeps passphrases from being
+// cheap to guess, the maximum keeps a key record from stalling clients.
+const (
+	MinMemory  = 19 * 1024 // KiB
+	MaxMemory  = 1024 * 1024
+	MaxTime    = 16
+	MaxThreads = 16
+)
+
+var (
+	// ErrDecrypt is returned when a ciphertext does not authenticate, because
+	// the key is wrong or the envelope was tampered with
+	ErrDecrypt = errors.New("decryption failed")
+	// ErrWrongPassphrase is returned when a data key cannot be unwrapped
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// NewKDFParams returns the default Argon2id parameters with a new random salt
+func NewKDFParams() (models.KDFParams, error) {
+	salt, err := random(SaltSize)
+	if err != nil {
+		return models.KDFParams{}, err
+	}
+	return models.KDFParams{
+		Algorithm: KDFArgon2id,
+		Salt:      base64.StdEncoding.EncodeToString(salt),
+		Time:      DefaultTime,
+		Memory:    DefaultMemory,
+		Threads:   DefaultThreads,
+	}, nil
+}
+
+// DeriveKey derives a key encryption key from a passphrase
+func DeriveKey(passphrase string, params models.KDFParams) ([]byte, error) {
+	if err := ValidateKDFParams(params); err != nil {
+		return nil, err
+	}
+	salt, _ := base64.StdEncoding.DecodeString(params.Salt)
+	return argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, KeySize), nil
+}
+
+// NewKey creates a random data key and its record, wrapped with a key derived
+// from passphrase. The record is what clients upload, the data key stays on
+// the client.
+func NewKey(userID string, passphrase string) (*models.EncryptionKey, []byte, error) {
+	dataKey, err := random(KeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := models.GetTimeNow()
+	record := &models.EncryptionKey{
+		UserID:    userID,
+		KeyID:     uuid.New().String(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := wrap(record, dataKey, passphrase); err != nil {
+		return nil, nil, err
+	}
+	return record, dataKey, nil
+}
+
+// UnwrapKey recovers the data key of a record with the user's passphrase
+func UnwrapKey(record models.EncryptionKey, passphrase string) ([]byte, error) {
+	if err := ValidateKey(record); err != nil {
+		return nil, err
+	}
+	kek, err := DeriveKey(passphrase, record.KDF)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, _ := base64.StdEncoding.DecodeString(record.Nonce)
+	wrapped, _ := base64.StdEncoding.DecodeString(record.WrappedKey)
+	dataKey, err := open(kek, nonce, wrapped, keyAD(record.KeyID))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return dataKey, nil
+}
+
+// Rewrap wraps the data key of a record with a new passphrase and a new salt.
+// Notes encrypted with the key stay readable, only the record changes.
+func Rewrap(record models.EncryptionKey, oldPassphrase string, newPassphrase string) (*models.EncryptionKey, error) {
+	dataKey, err := UnwrapKey(record, oldPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	rewrapped := record
+	rewrapped.UpdatedAt = models.GetTimeNow()
+	if err := wrap(&rewrapped, dataKey, newPassphrase); err != nil {
+		return nil, err
+	}
+	return &rewrapped, nil
+}
+
+// Encrypt encrypts note content with a data key
+func Encrypt(dataKey []byte, keyID string, plaintext []byte) (*models.EncryptedContent, error) {
+	nonce, err := random(NonceSize)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(dataKey, nonce, plaintext, contentAD(AlgorithmAES256GCM, keyID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EncryptedContent{
+		Algorithm:  AlgorithmAES256GCM,
+		KeyID:      keyID,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}, nil
+}
+
+// Decrypt decrypts note content with the data key the envelope names
+func Decrypt(dataKey []byte, envelope models.EncryptedContent) ([]byte, error) {
+	if err := ValidateEnvelope(envelope); err != nil {
+		return nil, err
+	}
+
+	nonce, _ := base64.StdEncoding.DecodeString(envelope.Nonce)
+	ciphertext, _ := base64.StdEncoding.DecodeString(envelope.Ciphertext)
+	plaintext, err := open(dataKey, nonce, ciphertext, contentAD(envelope.Algorithm, envelope.KeyID))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// ValidateEnvelope checks that an envelope is well formed, without any key
+func ValidateEnvelope(envelope models.EncryptedContent) error {
+	if envelope.Algorithm != AlgorithmAES256GCM {
+		return fmt.Errorf("encryption algorithm must be %s", AlgorithmAES256GCM)
+	}
+	if _, err := uuid.Parse(envelope.KeyID); err != nil {
+		return errors.New("encryption keyId must be a UUID")
+	}
+	if nonce, err := base64.StdEncoding.DecodeString(envelope.Nonce); err != nil || len(nonce) != NonceSize {
+		return fmt.Errorf("encryption nonce must be %d bytes in base64", NonceSize)
+	}
+	if ciphertext, err := base64.StdEncoding.DecodeString(envelope.Ciphertext); err != nil || len(ciphertext) < TagSize {
+		return fmt.Errorf("encryption ciphertext must be at least %d bytes in base64", TagSize)
+	}
+	return nil
+}
+
+// ValidateKDFParams checks the algorithm, salt and cost of key derivation
+func ValidateKDFParams(params models.KDFParams) error {
+	if params.Algorithm != KDFArgon2id {
+		return fmt.Errorf("kdf algorithm must be %s", KDFArgon2id)
+	}
+	if salt, err := base64.StdEncoding.DecodeString(params.Salt); err != nil || len(salt) < SaltSize {
+		return fmt.Errorf("kdf salt must be at least %d bytes in base64", SaltSize)
+	}
+	if params.Time < 1 || params.Time > MaxTime {
+		return fmt.Errorf("kdf time must be between 1 and %d", MaxTime)
+	}
+	if params.Memory < MinMemory || params.Memory > MaxMemory {
+		return fmt.Errorf("kdf memory must be between %d and %d KiB", MinMemory, MaxMemory)
+	}
+	if params.Threads < 1 || params.Threads > MaxThreads {
+		return fmt.Errorf("kdf threads must be between 1 and %d", MaxThreads)
+	}
+	return nil
+}
+
+// ValidateKey checks that a key record is well formed, without the passphrase
+func ValidateKey(record models.EncryptionKey) error {
+	if _, err := uuid.Parse(record.KeyID); err != nil {
+		return errors.New("keyId must be a UUID")
+	}
+	if err := ValidateKDFParams(record.KDF); err != nil {
+		return err
+	}
+	if record.Algorithm != AlgorithmAES256GCM {
+		return fmt.Errorf("algorithm must be %s", AlgorithmAES256GCM)
+	}
+	if nonce, err := base64.StdEncoding.DecodeString(record.Nonce); err != nil || len(nonce) != NonceSize {
+		return fmt.Errorf("nonce must be %d bytes in base64", NonceSize)
+	}
+	if wrapped, err := base64.StdEncoding.DecodeString(record.WrappedKey); err != nil || len(wrapped) != KeySize+TagSize {
+		return fmt.Errorf("wrappedKey must be %d bytes in base64", KeySize+TagSize)
+	}
+	return nil
+}
+
+// wrap derives a key encryption key with new parameters and wraps dataKey
+// into record
+func wrap(record *models.EncryptionKey, dataKey []byte, passphrase string) error {
+	params, err := NewKDFParams()
+	if err != nil {
+		return err
+	}
+	kek, err := DeriveKey(passphrase, params)
+	if err != nil {
+		return err
+	}
+	nonce, err := random(NonceSize)
+	if err != nil {
+		return err
+	}
+	wrapped, err := seal(kek, nonce, dataKey, keyAD(record.KeyID))
+	if err != nil {
+		return err
+	}
+
+	record.KDF = params
+	record.Algorithm = AlgorithmAES256GCM
+	record.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	record.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+	return nil
+}
+
+// contentAD is the additional data of note content, so that an envelope
+// cannot be relabelled with another algorithm or key
+func contentAD(algorithm string, keyID string) []byte {
+	return []byte("mino-note\x00" + algorithm + "\x00" + keyID)
+}
+
+// keyAD is the additional data of a wrapped data key
+func keyAD(keyID string) []byte {
+	return []byte("mino-key\x00" + keyID)
+}
+
+func seal(key []byte, nonce []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, nonce []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes", KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func random(size int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
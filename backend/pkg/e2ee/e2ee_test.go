@@ -0,0 +1,222 @@
+package e2ee
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// newTestKey creates a key record once per test, key derivation is slow on purpose
+func newTestKey(t *testing.T, passphrase string) (*models.EncryptionKey, []byte) {
+	t.Helper()
+
+	record, dataKey, err := NewKey("user-1", passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateKey(*record); err != nil {
+		t.Fatalf("new key record is invalid: %v", err)
+	}
+	return record, dataKey
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	dataKey := bytes.Repeat([]byte{7}, KeySize)
+	keyID := "0b0e0d3a-8a3e-4e77-9a4c-3f1f1d2c0a11"
+	plaintext := []byte("# Secret plans\n\nMeet at noon.")
+
+	envelope, err := Encrypt(dataKey, keyID, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateEnvelope(*envelope); err != nil {
+		t.Fatalf("envelope is invalid: %v", err)
+	}
+	if strings.Contains(envelope.Ciphertext, base64.StdEncoding.EncodeToString(plaintext)) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	decrypted, err := Decrypt(dataKey, *envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decrypted %q, want %q", decrypted, plaintext)
+	}
+
+	// Nonces are random, the same content never encrypts the same way twice
+	again, err := Encrypt(dataKey, keyID, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Nonce == envelope.Nonce || again.Ciphertext == envelope.Ciphertext {
+		t.Error("encrypting twice gave the same envelope")
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	dataKey := bytes.Repeat([]byte{7}, KeySize)
+	keyID := "0b0e0d3a-8a3e-4e77-9a4c-3f1f1d2c0a11"
+	envelope, err := Encrypt(dataKey, keyID, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped, _ := base64.StdEncoding.DecodeString(envelope.Ciphertext)
+	flipped[0] ^= 1
+
+	tests := []struct {
+		name     string
+		key      []byte
+		envelope func(e models.EncryptedContent) models.EncryptedContent
+	}{
+		{"wrong key", bytes.Repeat([]byte{8}, KeySize), func(e models.EncryptedContent) models.EncryptedContent { return e }},
+		{"tampered ciphertext", dataKey, func(e models.EncryptedContent) models.EncryptedContent {
+			e.Ciphertext = base64.StdEncoding.EncodeToString(flipped)
+			return e
+		}},
+		{"relabelled key", dataKey, func(e models.EncryptedContent) models.EncryptedContent {
+			e.KeyID = "5d1c9f0e-2b7a-4c3d-8e6f-0a1b2c3d4e5f"
+			return e
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt(tt.key, tt.envelope(*envelope))
+			if !errors.Is(err, ErrDecrypt) {
+				t.Errorf("got error %v, want ErrDecrypt", err)
+			}
+		})
+	}
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	record, dataKey := newTestKey(t, "correct horse battery staple")
+
+	unwrapped, err := UnwrapKey(*record, "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Error("unwrapped key differs from the data key")
+	}
+
+	if _, err := UnwrapKey(*record, "wrong passphrase"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("got error %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	record, dataKey := newTestKey(t, "old passphrase")
+	envelope, err := Encrypt(dataKey, record.KeyID, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewrapped, err := Rewrap(*record, "old passphrase", "new passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewrapped.KeyID != record.KeyID || rewrapped.KDF.Salt == record.KDF.Salt {
+		t.Error("rewrapping must keep the key ID and change the salt")
+	}
+	if _, err := UnwrapKey(*rewrapped, "old passphrase"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("old passphrase still unwraps: %v", err)
+	}
+
+	// Notes encrypted before the passphrase change stay readable
+	unwrapped, err := UnwrapKey(*rewrapped, "new passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := Decrypt(unwrapped, *envelope); err != nil || string(plaintext) != "secret" {
+		t.Errorf("decrypted %q, %v after rewrapping", plaintext, err)
+	}
+
+	if _, err := Rewrap(*record, "wrong passphrase", "new passphrase"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("got error %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestValidateEnvelope(t *testing.T) {
+	valid := models.EncryptedContent{
+		Algorithm:  AlgorithmAES256GCM,
+		KeyID:      "0b0e0d3a-8a3e-4e77-9a4c-3f1f1d2c0a11",
+		Nonce:      base64.StdEncoding.EncodeToString(make([]byte, NonceSize)),
+		Ciphertext: base64.StdEncoding.EncodeToString(make([]byte, TagSize+5)),
+	}
+
+	tests := []struct {
+		name   string
+		modify func(e *models.EncryptedContent)
+		valid  bool
+	}{
+		{"valid", func(e *models.EncryptedContent) {}, true},
+		{"empty content", func(e *models.EncryptedContent) {
+			e.Ciphertext = base64.StdEncoding.EncodeToString(make([]byte, TagSize))
+		}, true},
+		{"unknown algorithm", func(e *models.EncryptedContent) { e.Algorithm = "ROT13" }, false},
+		{"key ID not a UUID", func(e *models.EncryptedContent) { e.KeyID = "my-key" }, false},
+		{"short nonce", func(e *models.EncryptedContent) {
+			e.Nonce = base64.StdEncoding.EncodeToString(make([]byte, 8))
+		}, false},
+		{"nonce not base64", func(e *models.EncryptedContent) { e.Nonce = "not base64!" }, false},
+		{"ciphertext without tag", func(e *models.EncryptedContent) {
+			e.Ciphertext = base64.StdEncoding.EncodeToString(make([]byte, TagSize-1))
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope := valid
+			tt.modify(&envelope)
+			err := ValidateEnvelope(envelope)
+			if tt.valid && err != nil {
+				t.Errorf("got error %v, want none", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestValidateKDFParams(t *testing.T) {
+	valid, err := NewKDFParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(p *models.KDFParams)
+		valid  bool
+	}{
+		{"defaults", func(p *models.KDFParams) {}, true},
+		{"unknown algorithm", func(p *models.KDFParams) { p.Algorithm = "pbkdf2" }, false},
+		{"short salt", func(p *models.KDFParams) { p.Salt = base64.StdEncoding.EncodeToString(make([]byte, 8)) }, false},
+		{"no passes", func(p *models.KDFParams) { p.Time = 0 }, false},
+		{"too little memory", func(p *models.KDFParams) { p.Memory = 1024 }, false},
+		{"too much memory", func(p *models.KDFParams) { p.Memory = MaxMemory + 1 }, false},
+		{"no threads", func(p *models.KDFParams) { p.Threads = 0 }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := valid
+			tt.modify(&params)
+			err := ValidateKDFParams(params)
+			if tt.valid && err != nil {
+				t.Errorf("got error %v, want none", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package envelope
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// tokenSize is the length in bytes an HMAC is cut to, a collision only makes
+// a lookup return an entry that the caller filters out
+const tokenSize = 16
+
+// BlindIndex turns the terms of an index into keyed HMAC tokens, so that the
+// index can be looked up by term without storing the terms. Tokens are per
+// user, the same term gives different tokens to different users.
+type BlindIndex struct {
+	key []byte
+}
+
+// NewBlindIndex returns a BlindIndex. A nil key disables it: tokens are the
+// values themselves.
+func NewBlindIndex(key []byte) *BlindIndex {
+	return &BlindIndex{key: key}
+}
+
+// DefaultIndex is the BlindIndex the environment configures, see
+// BlindIndexKeyFromEnv
+var DefaultIndex *BlindIndex
+
+func init() {
+	key, err := BlindIndexKeyFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to load search index key: %v", err)
+	}
+	DefaultIndex = NewBlindIndex(key)
+}
+
+// Enabled reports whether tokens are HMACs
+func (b *BlindIndex) Enabled() bool {
+	return b.key != nil
+}
+
+// Token returns the token of a value of a user's index
+func (b *BlindIndex) Token(userID string, value string) string {
+	if !b.Enabled() {
+		return value
+	}
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(userID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:tokenSize])
+}
+
+// BlindIndexKeyFromEnv reads the base64 32-byte key of SEARCH_INDEX_KEY, nil
+// when it is not set. Functions using the search index with encryption
+// enabled must have it, so that terms are not left in plaintext in the index.
+// Changing it requires rebuilding the index.
+func BlindIndexKeyFromEnv() ([]byte, error) {
+	value := os.Getenv("SEARCH_INDEX_KEY")
+	if value == "" {
+		if os.Getenv("SEARCH_TABLE") != "" && os.Getenv("ENCRYPTION_KEYRING") != "" {
+			return nil, errors.New("SEARCH_INDEX_KEY is required when ENCRYPTION_KEYRING and SEARCH_TABLE are set")
+		}
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("SEARCH_INDEX_KEY is not base64: %w", err)
+	}
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("SEARCH_INDEX_KEY must be %d bytes", dataKeySize)
+	}
+	return key, nil
+}
@@ -0,0 +1,390 @@
+// Package envelope encrypts note fields at rest with envelope encryption.
+//
+// Each value is encrypted with AES-256-GCM under a data key, and the data key
+// is wrapped by a versioned master key held in a Keyring (KMS or local). An
+// encrypted value is a string that carries everything needed to decrypt it:
+//
+//	enc1:<master key version>:<wrapped data key>:<nonce and ciphertext>
+//
+// with both binary parts in unpadded base64url. Values are bound to where they
+// are stored, a ciphertext copied to another note or field does not decrypt.
+// Strings without the prefix are plaintext written before encryption was
+// enabled and are returned as is. Plaintext that happens to start with a
+// prefix of the format is written behind the escape prefix enc0:, so that it
+// is never taken for ciphertext.
+package envelope
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// prefix marks encrypted values, the digit is the format version
+const prefix = "enc1:"
+
+// plainPrefix escapes plaintext values that start with prefix or with
+// plainPrefix itself
+const plainPrefix = "enc0:"
+
+// dataKeySize is the size of data and local master keys, for AES-256
+const dataKeySize = 32
+
+// A data key is reused for a while so that writes do not each call KMS
+const (
+	DataKeyMaxAge  = 5 * time.Minute
+	DataKeyMaxUses = 10000
+)
+
+// maxCachedKeys bounds the unwrapped data keys kept for reads
+const maxCachedKeys = 1000
+
+var (
+	// ErrDecrypt is returned when a value does not authenticate
+	ErrDecrypt = errors.New("decryption failed")
+	// ErrNoKeyring is returned when an encrypted value is read without a
+	// keyring configured
+	ErrNoKeyring = errors.New("value is encrypted but no keyring is configured")
+	// ErrMalformed is returned for a value with the prefix but not the format
+	ErrMalformed = errors.New("malformed encrypted value")
+)
+
+// Encrypter encrypts and decrypts values with data keys from a keyring. It
+// is safe for concurrent use.
+type Encrypter struct {
+	keyring Keyring
+
+	mu      sync.Mutex
+	current *dataKey
+	cache   map[string][]byte // Unwrapped data keys by version and wrapped key
+}
+
+// dataKey is the data key new values are encrypted with
+type dataKey struct {
+	version   string
+	plaintext []byte
+	wrapped   string // base64url
+	createdAt time.Time
+	uses      int
+}
+
+// New returns an Encrypter. A nil keyring disables encryption: values are
+// written in plaintext and encrypted values cannot be read.
+func New(keyring Keyring) *Encrypter {
+	return &Encrypter{
+		keyring: keyring,
+		cache:   make(map[string][]byte),
+	}
+}
+
+// Default is the Encrypter the environment configures, see KeyringFromEnv
+var Default *Encrypter
+
+func init() {
+	keyring, err := KeyringFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to load encryption keyring: %v", err)
+	}
+	Default = New(keyring)
+}
+
+// Enabled reports whether new values are encrypted
+func (e *Encrypter) Enabled() bool {
+	return e.keyring != nil
+}
+
+// CurrentVersion is the master key version new values are encrypted with,
+// empty when encryption is disabled
+func (e *Encrypter) CurrentVersion() string {
+	if !e.Enabled() {
+		return ""
+	}
+	return e.keyring.CurrentVersion()
+}
+
+// IsEncrypted reports whether a stored value is encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Version returns the master key version of an encrypted value
+func Version(value string) (string, bool) {
+	if !IsEncrypted(value) {
+		return "", false
+	}
+	version, _, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return version, ok
+}
+
+// NeedsReencryption reports whether a stored value is in plaintext or under
+// an older master key version than the current one
+func (e *Encrypter) NeedsReencryption(value string) bool {
+	if !e.Enabled() {
+		return false
+	}
+	version, ok := Version(value)
+	return !ok || version != e.keyring.CurrentVersion()
+}
+
+// Encrypt encrypts a value. binding names where the value is stored, the
+// same binding must be given to Decrypt.
+func (e *Encrypter) Encrypt(ctx context.Context, plaintext string, binding string) (string, error) {
+	if !e.Enabled() {
+		return escape(plaintext), nil
+	}
+
+	key, err := e.dataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(key.plaintext, []byte(plaintext), additionalData(key.version, binding))
+	if err != nil {
+		return "", err
+	}
+	return prefix + key.version + ":" + key.wrapped + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value written by Encrypt, plaintext values are returned
+// unchanged and escaped ones unescaped
+func (e *Encrypter) Decrypt(ctx context.Context, value string, binding string) (string, error) {
+	if escaped, ok := strings.CutPrefix(value, plainPrefix); ok {
+		return escaped, nil
+	}
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if !e.Enabled() {
+		return "", ErrNoKeyring
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	version, wrapped := parts[0], parts[1]
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	key, err := e.unwrap(ctx, version, wrapped)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(key, sealed, additionalData(version, binding))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// escape escapes a plaintext value that would otherwise read as ciphertext
+// or as an escaped value
+func escape(plaintext string) string {
+	if strings.HasPrefix(plaintext, prefix) || strings.HasPrefix(plaintext, plainPrefix) {
+		return plainPrefix + plaintext
+	}
+	return plaintext
+}
+
+// dataKey returns the current data key, generating a new one when there is
+// none yet, it is too old or too used, or the master key version changed
+func (e *Encrypter) dataKey(ctx context.Context) (*dataKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	version := e.keyring.CurrentVersion()
+	current := e.current
+	if current == nil || current.version != version || current.uses >= DataKeyMaxUses || time.Since(current.createdAt) > DataKeyMaxAge {
+		plaintext, wrapped, err := e.keyring.GenerateDataKey(ctx, version)
+		if err != nil {
+			return nil, fmt.Errorf("generating data key: %w", err)
+		}
+		current = &dataKey{
+			version:   version,
+			plaintext: plaintext,
+			wrapped:   base64.RawURLEncoding.EncodeToString(wrapped),
+			createdAt: time.Now(),
+		}
+		e.current = current
+		e.cacheKey(version+":"+current.wrapped, plaintext)
+	}
+	current.uses++
+	return current, nil
+}
+
+// unwrap returns the plaintext of a wrapped data key, from the cache when
+// the key was seen before
+func (e *Encrypter) unwrap(ctx context.Context, version string, wrapped string) ([]byte, error) {
+	cacheKey := version + ":" + wrapped
+
+	e.mu.Lock()
+	key, ok := e.cache[cacheKey]
+	e.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	key, err = e.keyring.UnwrapDataKey(ctx, version, raw)
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data key: %w", err)
+	}
+
+	e.mu.Lock()
+	e.cacheKey(cacheKey, key)
+	e.mu.Unlock()
+	return key, nil
+}
+
+// cacheKey keeps an unwrapped data key, the cache is emptied when full.
+// The caller holds e.mu.
+func (e *Encrypter) cacheKey(cacheKey string, key []byte) {
+	if len(e.cache) >= maxCachedKeys {
+		e.cache = make(map[string][]byte)
+	}
+	e.cache[cacheKey] = key
+}
+
+// additionalData authenticates the format, master key version and binding
+func additionalData(version string, binding string) []byte {
+	return []byte(prefix + version + ":" + binding)
+}
+
+// noteBinding binds a note field to its note
+func noteBinding(noteID string, field string) string {
+	return "note/" + noteID + "/" + field
+}
+
+// EncryptNote encrypts the title, content and checklist item texts of a note
+// in place, before it is stored. The items are copied so that a slice the
+// caller shares stays in plaintext.
+func (e *Encrypter) EncryptNote(ctx context.Context, note *models.Note) error {
+	var err error
+	if note.Title, err = e.EncryptNoteField(ctx, note.NoteID, "title", note.Title); err != nil {
+		return err
+	}
+	if note.Content, err = e.EncryptNoteField(ctx, note.NoteID, "content", note.Content); err != nil {
+		return err
+	}
+	note.Items, err = e.EncryptItems(ctx, note.NoteID, note.Items)
+	return err
+}
+
+// DecryptNote decrypts the title, content and checklist item texts of a
+// stored note in place
+func (e *Encrypter) DecryptNote(ctx context.Context, note *models.Note) error {
+	var err error
+	if note.Title, err = e.DecryptNoteField(ctx, note.NoteID, "title", note.Title); err != nil {
+		return fmt.Errorf("note %s title: %w", note.NoteID, err)
+	}
+	if note.Content, err = e.DecryptNoteField(ctx, note.NoteID, "content", note.Content); err != nil {
+		return fmt.Errorf("note %s content: %w", note.NoteID, err)
+	}
+	for i, item := range note.Items {
+		if note.Items[i].Text, err = e.DecryptItemText(ctx, note.NoteID, item.ItemID, item.Text); err != nil {
+			return fmt.Errorf("note %s item %s: %w", note.NoteID, item.ItemID, err)
+		}
+	}
+	return nil
+}
+
+// EncryptItems returns a copy of checklist items with their texts encrypted
+func (e *Encrypter) EncryptItems(ctx context.Context, noteID string, items []models.ChecklistItem) ([]models.ChecklistItem, error) {
+	if items == nil {
+		return nil, nil
+	}
+
+	encrypted := make([]models.ChecklistItem, len(items))
+	for i, item := range items {
+		var err error
+		if item.Text, err = e.EncryptItemText(ctx, noteID, item.ItemID, item.Text); err != nil {
+			return nil, err
+		}
+		encrypted[i] = item
+	}
+	return encrypted, nil
+}
+
+// EncryptItemText encrypts the text of one checklist item, which is bound to
+// the item so that texts cannot be swapped between items
+func (e *Encrypter) EncryptItemText(ctx context.Context, noteID string, itemID string, text string) (string, error) {
+	return e.Encrypt(ctx, text, noteBinding(noteID, "items/"+itemID))
+}
+
+// DecryptItemText decrypts the stored text of one checklist item
+func (e *Encrypter) DecryptItemText(ctx context.Context, noteID string, itemID string, text string) (string, error) {
+	return e.Decrypt(ctx, text, noteBinding(noteID, "items/"+itemID))
+}
+
+// EncryptNoteField encrypts one field of a note, for partial updates
+func (e *Encrypter) EncryptNoteField(ctx context.Context, noteID string, field string, value string) (string, error) {
+	return e.Encrypt(ctx, value, noteBinding(noteID, field))
+}
+
+// DecryptNoteField decrypts one stored field of a note
+func (e *Encrypter) DecryptNoteField(ctx context.Context, noteID string, field string, value string) (string, error) {
+	return e.Decrypt(ctx, value, noteBinding(noteID, field))
+}
+
+// deliveryBinding binds the payload of a webhook delivery to the delivery
+func deliveryBinding(delivery *models.WebhookDelivery) string {
+	return "delivery/" + delivery.WebhookID + "/" + delivery.DeliveryID
+}
+
+// EncryptDelivery encrypts the payload of a webhook delivery in place, before
+// it is stored. The payload holds the note the event is about.
+func (e *Encrypter) EncryptDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	var err error
+	delivery.Payload, err = e.Encrypt(ctx, delivery.Payload, deliveryBinding(delivery))
+	return err
+}
+
+// DecryptDelivery decrypts the payload of a stored webhook delivery in place
+func (e *Encrypter) DecryptDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	var err error
+	if delivery.Payload, err = e.Decrypt(ctx, delivery.Payload, deliveryBinding(delivery)); err != nil {
+		return fmt.Errorf("delivery %s payload: %w", delivery.DeliveryID, err)
+	}
+	return nil
+}
+
+// indexBinding binds a term of the search index to its entry
+func indexBinding(userID string, key string) string {
+	return "search/" + userID + "/" + key
+}
+
+// EncryptIndexTerm encrypts a term stored in the search index entry of a user
+// with sort key key
+func (e *Encrypter) EncryptIndexTerm(ctx context.Context, userID string, key string, term string) (string, error) {
+	return e.Encrypt(ctx, term, indexBinding(userID, key))
+}
+
+// DecryptIndexTerm decrypts a term stored in a search index entry
+func (e *Encrypter) DecryptIndexTerm(ctx context.Context, userID string, key string, term string) (string, error) {
+	return e.Decrypt(ctx, term, indexBinding(userID, key))
+}
+
+// EncryptedNoteFields are the string note attributes stored encrypted, the
+// text of every checklist item in items is encrypted too
+var EncryptedNoteFields = []string{"title", "content"}
+
+// IsEncryptedNoteField reports whether a note attribute is stored encrypted
+func IsEncryptedNoteField(field string) bool {
+	for _, encrypted := range EncryptedNoteFields {
+		if field == encrypted {
+			return true
+		}
+	}
+	return false
+}
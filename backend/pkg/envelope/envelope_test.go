@@ -0,0 +1,277 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// countingKeyring counts the calls a cache should save
+type countingKeyring struct {
+	*LocalKeyring
+	generated int
+	unwrapped int
+}
+
+func (k *countingKeyring) GenerateDataKey(ctx context.Context, version string) ([]byte, []byte, error) {
+	k.generated++
+	return k.LocalKeyring.GenerateDataKey(ctx, version)
+}
+
+func (k *countingKeyring) UnwrapDataKey(ctx context.Context, version string, wrapped []byte) ([]byte, error) {
+	k.unwrapped++
+	return k.LocalKeyring.UnwrapDataKey(ctx, version, wrapped)
+}
+
+func newTestKeyring(t *testing.T, current string) *countingKeyring {
+	t.Helper()
+
+	keyring, err := NewLocalKeyring(map[string][]byte{
+		"v1": bytes.Repeat([]byte{1}, dataKeySize),
+		"v2": bytes.Repeat([]byte{2}, dataKeySize),
+	}, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &countingKeyring{LocalKeyring: keyring}
+}
+
+func TestEncryptNote(t *testing.T) {
+	ctx := context.Background()
+	encrypter := New(newTestKeyring(t, "v1"))
+
+	note := models.Note{NoteID: "note-1", Title: "Groceries", Content: "Milk, eggs"}
+	if err := encrypter.EncryptNote(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{note.Title, note.Content} {
+		if !IsEncrypted(value) || strings.Contains(value, "Milk") || strings.Contains(value, "Groceries") {
+			t.Fatalf("stored value %q is not encrypted", value)
+		}
+	}
+
+	if err := encrypter.DecryptNote(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	if note.Title != "Groceries" || note.Content != "Milk, eggs" {
+		t.Errorf("decrypted %q and %q", note.Title, note.Content)
+	}
+}
+
+func TestEncryptChecklistItems(t *testing.T) {
+	ctx := context.Background()
+	encrypter := New(newTestKeyring(t, "v1"))
+
+	items := []models.ChecklistItem{{ItemID: "item-1", Text: "Milk"}, {ItemID: "item-2", Text: "Eggs", Checked: true}}
+	note := models.Note{NoteID: "note-1", Type: "checklist", Items: items}
+	if err := encrypter.EncryptNote(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range note.Items {
+		if !IsEncrypted(item.Text) {
+			t.Fatalf("stored item text %q is not encrypted", item.Text)
+		}
+	}
+	if items[0].Text != "Milk" {
+		t.Errorf("the caller's items were encrypted too")
+	}
+
+	// Texts are bound to their item
+	note.Items[0].Text, note.Items[1].Text = note.Items[1].Text, note.Items[0].Text
+	if err := encrypter.DecryptNote(ctx, &note); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("swapped item texts: got error %v, want ErrDecrypt", err)
+	}
+
+	note.Items, _ = encrypter.EncryptItems(ctx, "note-1", items)
+	if err := encrypter.DecryptNote(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	if note.Items[0].Text != "Milk" || note.Items[1].Text != "Eggs" || !note.Items[1].Checked {
+		t.Errorf("decrypted %+v", note.Items)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	index := NewBlindIndex(bytes.Repeat([]byte{3}, dataKeySize))
+
+	token := index.Token("user-1", "t:groceries")
+	if token != index.Token("user-1", "t:groceries") {
+		t.Error("tokens of the same term differ")
+	}
+	if strings.Contains(token, "groceries") {
+		t.Errorf("token %q holds the term", token)
+	}
+	if token == index.Token("user-2", "t:groceries") {
+		t.Error("two users share a token")
+	}
+	if token == index.Token("user-1", "t:grocerie") {
+		t.Error("two terms share a token")
+	}
+	if other := NewBlindIndex(bytes.Repeat([]byte{4}, dataKeySize)); token == other.Token("user-1", "t:groceries") {
+		t.Error("two keys share a token")
+	}
+
+	// Without a key the index stores the terms
+	if got := NewBlindIndex(nil).Token("user-1", "t:groceries"); got != "t:groceries" {
+		t.Errorf("disabled index token %q", got)
+	}
+}
+
+func TestDecryptPlaintext(t *testing.T) {
+	ctx := context.Background()
+
+	// Notes written before encryption was enabled stay readable
+	for _, encrypter := range []*Encrypter{New(nil), New(newTestKeyring(t, "v1"))} {
+		value, err := encrypter.Decrypt(ctx, "written in plaintext", "note/note-1/title")
+		if err != nil || value != "written in plaintext" {
+			t.Errorf("got %q, %v", value, err)
+		}
+	}
+
+	// Without a keyring nothing is encrypted and encrypted values cannot be read
+	disabled := New(nil)
+	value, err := disabled.Encrypt(ctx, "secret", "note/note-1/title")
+	if err != nil || value != "secret" {
+		t.Errorf("got %q, %v", value, err)
+	}
+	encrypted, err := New(newTestKeyring(t, "v1")).Encrypt(ctx, "secret", "note/note-1/title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := disabled.Decrypt(ctx, encrypted, "note/note-1/title"); !errors.Is(err, ErrNoKeyring) {
+		t.Errorf("got error %v, want ErrNoKeyring", err)
+	}
+}
+
+func TestPlaintextWithPrefix(t *testing.T) {
+	ctx := context.Background()
+
+	// A title typed with the prefix is stored escaped and read back as typed
+	for _, encrypter := range []*Encrypter{New(nil), New(newTestKeyring(t, "v1"))} {
+		for _, typed := range []string{"enc1:not a ciphertext", "enc0:also plaintext", "enc1:v1:a:b"} {
+			stored, err := encrypter.Encrypt(ctx, typed, "note/note-1/title")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !encrypter.Enabled() && stored == typed {
+				t.Errorf("%q was stored unescaped", typed)
+			}
+			value, err := encrypter.Decrypt(ctx, stored, "note/note-1/title")
+			if err != nil || value != typed {
+				t.Errorf("got %q, %v, want %q", value, err, typed)
+			}
+		}
+	}
+
+	note := models.Note{NoteID: "note-1", Title: "enc1:groceries", Content: "Milk"}
+	disabled := New(nil)
+	if err := disabled.EncryptNote(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	if err := disabled.DecryptNote(ctx, &note); err != nil || note.Title != "enc1:groceries" {
+		t.Errorf("got %q, %v", note.Title, err)
+	}
+}
+
+func TestDecryptRejectsMovedValues(t *testing.T) {
+	ctx := context.Background()
+	encrypter := New(newTestKeyring(t, "v1"))
+
+	title, err := encrypter.EncryptNoteField(ctx, "note-1", "title", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := encrypter.DecryptNoteField(ctx, "note-2", "title", title); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("value copied to another note: got error %v, want ErrDecrypt", err)
+	}
+	if _, err := encrypter.DecryptNoteField(ctx, "note-1", "content", title); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("value copied to another field: got error %v, want ErrDecrypt", err)
+	}
+
+	// Claiming another master key version fails too
+	relabelled := strings.Replace(title, "enc1:v1:", "enc1:v2:", 1)
+	if _, err := encrypter.DecryptNoteField(ctx, "note-1", "title", relabelled); err == nil {
+		t.Error("relabelled value decrypted")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	ctx := context.Background()
+	old := New(newTestKeyring(t, "v1"))
+	value, err := old.Encrypt(ctx, "secret", "note/note-1/content")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := New(newTestKeyring(t, "v2"))
+	if !rotated.NeedsReencryption(value) || !rotated.NeedsReencryption("plaintext") {
+		t.Error("values under v1 and plaintext need re-encryption after rotating to v2")
+	}
+
+	// Values under the old version still decrypt after the rotation
+	plaintext, err := rotated.Decrypt(ctx, value, "note/note-1/content")
+	if err != nil || plaintext != "secret" {
+		t.Fatalf("got %q, %v", plaintext, err)
+	}
+
+	reencrypted, err := rotated.Encrypt(ctx, plaintext, "note/note-1/content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := Version(reencrypted); version != "v2" || rotated.NeedsReencryption(reencrypted) {
+		t.Errorf("re-encrypted value is under %q", version)
+	}
+}
+
+func TestDataKeyCache(t *testing.T) {
+	ctx := context.Background()
+	keyring := newTestKeyring(t, "v1")
+	encrypter := New(keyring)
+
+	var values []string
+	for i := 0; i < 10; i++ {
+		value, err := encrypter.Encrypt(ctx, "secret", "note/note-1/title")
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, value)
+	}
+	if keyring.generated != 1 {
+		t.Errorf("generated %d data keys for 10 values, want 1", keyring.generated)
+	}
+	if values[0] == values[1] {
+		t.Error("nonces are reused")
+	}
+
+	// Another process unwraps the shared data key once
+	reader := New(keyring)
+	for _, value := range values {
+		if _, err := reader.Decrypt(ctx, value, "note/note-1/title"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keyring.unwrapped != 1 {
+		t.Errorf("unwrapped the data key %d times, want 1", keyring.unwrapped)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := parseKeys("v1=AAAA==, v2=alias/mino-notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys["v1"] != "AAAA==" || keys["v2"] != "alias/mino-notes" {
+		t.Errorf("got %v", keys)
+	}
+
+	for _, value := range []string{"", "v1", "=key", "v:1=key"} {
+		if _, err := parseKeys(value); err == nil {
+			t.Errorf("%q: got no error", value)
+		}
+	}
+}
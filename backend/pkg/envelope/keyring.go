@@ -0,0 +1,177 @@
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Keyring wraps and unwraps data keys with versioned master keys. The
+// version of the master key a data key was wrapped with is stored next to
+// every encrypted value, so that old versions keep decrypting after a
+// rotation until the values are re-encrypted.
+type Keyring interface {
+	// CurrentVersion is the master key version new data keys are wrapped with
+	CurrentVersion() string
+	// GenerateDataKey returns a new 256-bit data key, in plaintext and
+	// wrapped with the given master key version
+	GenerateDataKey(ctx context.Context, version string) (plaintext []byte, wrapped []byte, err error)
+	// UnwrapDataKey returns the plaintext of a wrapped data key
+	UnwrapDataKey(ctx context.Context, version string, wrapped []byte) ([]byte, error)
+}
+
+// ErrUnknownVersion is returned for a master key version the keyring does not have
+var ErrUnknownVersion = errors.New("unknown master key version")
+
+// LocalKeyring keeps master keys in memory and wraps data keys with
+// AES-256-GCM. It is meant for development and for deployments without KMS.
+type LocalKeyring struct {
+	keys    map[string][]byte
+	current string
+}
+
+// NewLocalKeyring returns a keyring of 32-byte master keys by version
+func NewLocalKeyring(keys map[string][]byte, current string) (*LocalKeyring, error) {
+	for version, key := range keys {
+		if len(key) != dataKeySize {
+			return nil, fmt.Errorf("master key %s must be %d bytes", version, dataKeySize)
+		}
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownVersion, current)
+	}
+	return &LocalKeyring{keys: keys, current: current}, nil
+}
+
+// CurrentVersion implements Keyring
+func (k *LocalKeyring) CurrentVersion() string {
+	return k.current
+}
+
+// GenerateDataKey implements Keyring
+func (k *LocalKeyring) GenerateDataKey(ctx context.Context, version string) ([]byte, []byte, error) {
+	masterKey, ok := k.keys[version]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownVersion, version)
+	}
+
+	plaintext := make([]byte, dataKeySize)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := seal(masterKey, plaintext, []byte("mino-data-key:"+version))
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, wrapped, nil
+}
+
+// UnwrapDataKey implements Keyring
+func (k *LocalKeyring) UnwrapDataKey(ctx context.Context, version string, wrapped []byte) ([]byte, error) {
+	masterKey, ok := k.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownVersion, version)
+	}
+	return open(masterKey, wrapped, []byte("mino-data-key:"+version))
+}
+
+// KeyringFromEnv builds the keyring the environment configures:
+//
+//	ENCRYPTION_KEYRING      local or kms, empty disables encryption
+//	ENCRYPTION_KEYS         version=key pairs separated by commas, base64
+//	                        32-byte keys for local, key IDs or aliases for kms
+//	ENCRYPTION_KEY_VERSION  version new values are encrypted with
+//
+// It returns nil when encryption is disabled.
+func KeyringFromEnv() (Keyring, error) {
+	kind := os.Getenv("ENCRYPTION_KEYRING")
+	if kind == "" {
+		return nil, nil
+	}
+
+	keys, err := parseKeys(os.Getenv("ENCRYPTION_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	current := os.Getenv("ENCRYPTION_KEY_VERSION")
+
+	switch kind {
+	case "local":
+		decoded := make(map[string][]byte, len(keys))
+		for version, key := range keys {
+			raw, err := base64.StdEncoding.DecodeString(key)
+			if err != nil {
+				return nil, fmt.Errorf("master key %s is not base64: %w", version, err)
+			}
+			decoded[version] = raw
+		}
+		return NewLocalKeyring(decoded, current)
+	case "kms":
+		return NewKMSKeyring(keys, current)
+	default:
+		return nil, fmt.Errorf("ENCRYPTION_KEYRING must be local or kms, not %q", kind)
+	}
+}
+
+// parseKeys parses version=key pairs separated by commas
+func parseKeys(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		version, key, ok := strings.Cut(pair, "=")
+		if !ok || version == "" || key == "" || strings.Contains(version, ":") {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS entry %q must be version=key", pair)
+		}
+		keys[version] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("ENCRYPTION_KEYS is empty")
+	}
+	return keys, nil
+}
+
+// seal encrypts with AES-256-GCM and prepends the random nonce
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts the output of seal
+func open(key []byte, sealed []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,143 @@
+// Package addchecklistitem is the handler of POST /notes/{noteId}/items
+package addchecklistitem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/validate"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "POST,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Get note ID from path parameters
+	noteID := request.PathParameters["noteId"]
+	if noteID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note ID is required"}`,
+		}, nil
+	}
+
+	// Parse item from request body
+	var item models.ChecklistItem
+	if err := validate.Decode(request.Body, &item); err != nil {
+		statusCode, response := validate.Failure(err)
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Check the user owns the note or may edit it through a share
+	access, err := db.GetNoteAccess(noteID, claims.UserID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note not found"}`,
+		}, nil
+	}
+	if !access.CanEdit() {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 403,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"You do not have permission to edit this note"}`,
+		}, nil
+	}
+
+	// Check the note's size with the item and the owner's quotas
+	grown := *access.Note
+	grown.Items = append(append([]models.ChecklistItem(nil), access.Note.Items...), models.ChecklistItem{Text: item.Text})
+	if err := db.CheckNoteLimits(access.OwnerID, grown, access.Note); err != nil {
+		var limitErr *db.LimitError
+		if !errors.As(err, &limitErr) {
+			logging.FromContext(ctx).Error("failed to check quota", "error", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: 500,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Failed to check quota"}`,
+			}, nil
+		}
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: limitErr.Message, Code: limitErr.Code})
+		return events.APIGatewayProxyResponse{
+			StatusCode: limitErr.StatusCode(),
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Append the item
+	note, err := db.AddChecklistItem(*access.Note, item.Text, item.Checked)
+	if err != nil {
+		statusCode, response := db.ChecklistFailure(err)
+		if statusCode == 500 {
+			logging.FromContext(ctx).Error("failed to update checklist", "error", err)
+		}
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Item added successfully",
+		Data:    note,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 201, // Created,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
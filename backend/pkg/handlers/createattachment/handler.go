@@ -0,0 +1,226 @@
+// Package createattachment is the handler of POST /notes/{noteId}/attachments
+package createattachment
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/storage"
+	"github.com/omidiyanto/mino/pkg/validate"
+)
+
+// maxNameLength is the longest file name accepted
+const maxNameLength = 255
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "GET,POST,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Get note ID from path parameters
+	noteID := request.PathParameters["noteId"]
+	if noteID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note ID is required"}`,
+		}, nil
+	}
+
+	// Parse request body
+	var attachmentRequest models.AttachmentRequest
+	if err := validate.Decode(request.Body, &attachmentRequest); err != nil {
+		statusCode, response := validate.Failure(err)
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Validate the file metadata, S3 enforces size, type and checksum on upload
+	if err := checkFile(&attachmentRequest); err != nil {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: err.Error()})
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+	if attachmentRequest.Size > db.MaxAttachmentSize {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 413, // Payload Too Large
+			Headers:    headers,
+			Body:       fmt.Sprintf(`{"success":false,"message":"Attachments can be at most %d bytes","code":"%s"}`, db.MaxAttachmentSize, db.CodeAttachmentTooLarge),
+		}, nil
+	}
+
+	// Check the user owns the note or may edit it through a share
+	access, err := db.GetNoteAccess(noteID, claims.UserID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note not found"}`,
+		}, nil
+	}
+	if !access.CanEdit() {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 403,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"You do not have permission to edit this note"}`,
+		}, nil
+	}
+
+	// The owner's plan sets the quota
+	owner, err := db.GetUserByID(access.OwnerID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create attachment", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create attachment"}`,
+		}, nil
+	}
+
+	// Record the attachment, its size counts towards the owner's quota until
+	// reconcile_attachments finds the file was never uploaded
+	attachmentID := uuid.New().String()
+	attachment := models.Attachment{
+		NoteID:       noteID,
+		AttachmentID: attachmentID,
+		UserID:       access.OwnerID,
+		Name:         attachmentRequest.Name,
+		Size:         attachmentRequest.Size,
+		ContentType:  attachmentRequest.ContentType,
+		Checksum:     attachmentRequest.Checksum,
+		Key:          fmt.Sprintf("attachments/%s/%s/%s", access.OwnerID, noteID, attachmentID),
+		CreatedAt:    models.GetTimeNow(),
+	}
+	err = db.CreateAttachment(attachment, db.LimitsFor(owner.Plan).MaxAttachmentBytes, time.Now().Add(storage.UploadWindow))
+	if errors.Is(err, db.ErrAttachmentQuotaExceeded) {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: "Attachment storage quota exceeded", Code: db.CodeAttachmentQuotaExceeded})
+		return events.APIGatewayProxyResponse{
+			StatusCode: 403,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+	if errors.Is(err, db.ErrNoteNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note not found"}`,
+		}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create attachment", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create attachment"}`,
+		}, nil
+	}
+
+	// Presign the upload
+	upload, err := storage.PresignUpload(ctx, attachment.Key, attachment.ContentType, attachment.Size, attachment.Checksum)
+	if err != nil {
+		if deleteErr := db.DeleteAttachment(attachment); deleteErr != nil {
+			err = errors.Join(err, deleteErr)
+		}
+		logging.FromContext(ctx).Error("failed to create upload URL", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create upload URL"}`,
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Attachment created, upload the file to the upload URL",
+		Data:    models.AttachmentUpload{Attachment: attachment, Upload: *upload},
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 201, // Created
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
+
+// checkFile checks the metadata of a file to attach and normalizes its content type
+func checkFile(request *models.AttachmentRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" || len(request.Name) > maxNameLength {
+		return fmt.Errorf("name must be between 1 and %d bytes", maxNameLength)
+	}
+	if strings.ContainsAny(request.Name, `/\`) || strings.IndexFunc(request.Name, unicode.IsControl) >= 0 {
+		return errors.New("name must not contain slashes or control characters")
+	}
+
+	if request.Size <= 0 {
+		return errors.New("size must be positive")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(request.ContentType)
+	if err != nil {
+		return errors.New("contentType must be a media type such as image/png")
+	}
+	request.ContentType = mime.FormatMediaType(mediaType, params)
+
+	checksum, err := base64.StdEncoding.DecodeString(request.Checksum)
+	if err != nil || len(checksum) != 32 {
+		return errors.New("checksum must be the base64 SHA-256 of the file")
+	}
+	return nil
+}
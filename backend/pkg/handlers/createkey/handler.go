@@ -0,0 +1,112 @@
+// Package createkey is the handler of POST /keys
+package createkey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/e2ee"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/validate"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "GET,POST,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Parse the wrapped key from request body
+	var key models.EncryptionKey
+	if err := validate.Decode(request.Body, &key); err != nil {
+		statusCode, response := validate.Failure(err)
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Check the record is well formed, the server cannot check the key itself
+	if err := e2ee.ValidateKey(key); err != nil {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: err.Error()})
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Store the key for the user
+	key.UserID = claims.UserID
+	key.CreatedAt = models.GetTimeNow()
+	key.UpdatedAt = key.CreatedAt
+	err = db.CreateKey(key)
+	if errors.Is(err, db.ErrKeyExists) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 409, // Conflict
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Encryption key already exists"}`,
+		}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create encryption key", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create encryption key"}`,
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Encryption key created successfully",
+		Data:    key,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 201, // Created
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
@@ -0,0 +1,165 @@
+// Package createnote is the handler of POST /notes
+package createnote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/validate"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "POST,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Parse note from request body
+	var note models.Note
+	if err := validate.Decode(request.Body, &note); err != nil {
+		statusCode, response := validate.Failure(err)
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// The HTML rendering is never stored
+	note.ContentHTML = ""
+
+	// Check that the body matches the note type
+	if err := db.ValidateNoteBody(note); err != nil {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: err.Error()})
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Check the reminder time
+	if note.RemindAt != "" && !db.ValidRemindAt(note.RemindAt) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"remindAt must be an RFC3339 time in the future"}`,
+		}, nil
+	}
+
+	// Set user ID from token
+	note.UserID = claims.UserID
+
+	// Make sure the target notebook belongs to the user
+	if note.NotebookID != "" {
+		if _, err := db.GetNotebookByID(note.NotebookID, claims.UserID); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Notebook not found"}`,
+			}, nil
+		}
+	}
+
+	// Make sure an encrypted note names one of the user's keys
+	if err := db.CheckNoteKey(note, claims.UserID); err != nil {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: err.Error()})
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Check the note's size and the owner's quotas
+	if err := db.CheckNoteLimits(claims.UserID, note, nil); err != nil {
+		var limitErr *db.LimitError
+		if !errors.As(err, &limitErr) {
+			logging.FromContext(ctx).Error("failed to check quota", "error", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: 500,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Failed to check quota"}`,
+			}, nil
+		}
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: limitErr.Message, Code: limitErr.Code})
+		return events.APIGatewayProxyResponse{
+			StatusCode: limitErr.StatusCode(),
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Create note
+	err = db.CreateNote(note)
+	var limitErr *db.LimitError
+	if errors.As(err, &limitErr) {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: limitErr.Message, Code: limitErr.Code})
+		return events.APIGatewayProxyResponse{
+			StatusCode: limitErr.StatusCode(),
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create note", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create note"}`,
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Note created successfully",
+		Data:    note,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 201, // Created
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
@@ -0,0 +1,135 @@
+// Package createsharelink is the handler of POST /notes/{noteId}/share-link
+package createsharelink
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/validate"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "POST,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Get note ID from path parameters
+	noteID := request.PathParameters["noteId"]
+	if noteID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note ID is required"}`,
+		}, nil
+	}
+
+	// Parse link options from request body, all of them are optional
+	var linkRequest models.ShareLinkRequest
+	if request.Body != "" {
+		if err := validate.Decode(request.Body, &linkRequest); err != nil {
+			statusCode, response := validate.Failure(err)
+			body, _ := json.Marshal(response)
+			return events.APIGatewayProxyResponse{
+				StatusCode: statusCode,
+				Headers:    headers,
+				Body:       string(body),
+			}, nil
+		}
+	}
+
+	// Validate expiry
+	var expiresAt time.Time
+	if linkRequest.ExpiresAt != "" {
+		expiresAt, err = time.Parse(time.RFC3339, linkRequest.ExpiresAt)
+		if err != nil || !expiresAt.After(time.Now()) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"expiresAt must be an RFC3339 time in the future"}`,
+			}, nil
+		}
+	}
+
+	// Only the owner may publish a note
+	note, err := db.GetNoteByID(noteID, claims.UserID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note not found"}`,
+		}, nil
+	}
+
+	// Readers of a public page have no key to decrypt with
+	if note.Encrypted != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 409, // Conflict
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Encrypted notes cannot be published"}`,
+		}, nil
+	}
+
+	// Create share link
+	link, err := db.CreateShareLink(noteID, claims.UserID, expiresAt, linkRequest.Password)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create share link", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create share link"}`,
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Share link created successfully",
+		Data:    link,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 201, // Created
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
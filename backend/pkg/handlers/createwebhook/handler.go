@@ -0,0 +1,124 @@
+// Package createwebhook is the handler of POST /webhooks
+package createwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/validate"
+	"github.com/omidiyanto/mino/pkg/webhook"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "POST,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Parse request body
+	var webhookRequest models.WebhookRequest
+	if err := validate.Decode(request.Body, &webhookRequest); err != nil {
+		statusCode, response := validate.Failure(err)
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Validate URL and events
+	if !webhook.ValidURL(webhookRequest.URL) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"url must be an absolute http or https URL of a public host"}`,
+		}, nil
+	}
+	subscribed, err := webhook.NormalizeEvents(webhookRequest.Events)
+	if err != nil {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: err.Error()})
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Register webhook
+	hook := models.Webhook{
+		UserID: claims.UserID,
+		URL:    webhookRequest.URL,
+		Events: subscribed,
+	}
+	err = db.CreateWebhook(&hook)
+	var limitErr *db.LimitError
+	if errors.As(err, &limitErr) {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: limitErr.Message, Code: limitErr.Code})
+		return events.APIGatewayProxyResponse{
+			StatusCode: limitErr.StatusCode(),
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create webhook", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create webhook"}`,
+		}, nil
+	}
+
+	// Create response, the only time the signing secret is returned
+	response := models.APIResponse{
+		Success: true,
+		Message: "Webhook created successfully",
+		Data:    hook,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 201, // Created
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
@@ -0,0 +1,132 @@
+// Package deleteattachment is the handler of DELETE /notes/{noteId}/attachments/{attachmentId}
+package deleteattachment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/storage"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "DELETE,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Get note and attachment IDs from path parameters
+	noteID := request.PathParameters["noteId"]
+	attachmentID := request.PathParameters["attachmentId"]
+	if noteID == "" || attachmentID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note ID and attachment ID are required"}`,
+		}, nil
+	}
+
+	// Check the user owns the note or may edit it through a share
+	access, err := db.GetNoteAccess(noteID, claims.UserID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note not found"}`,
+		}, nil
+	}
+	if !access.CanEdit() {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 403,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"You do not have permission to edit this note"}`,
+		}, nil
+	}
+
+	// Get the attachment for its storage key and size
+	attachment, err := db.GetAttachment(noteID, attachmentID, access.OwnerID)
+	if errors.Is(err, db.ErrAttachmentNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Attachment not found"}`,
+		}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to retrieve attachment", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to retrieve attachment"}`,
+		}, nil
+	}
+
+	// Delete the file first, a record without a file is only wasted quota
+	if err := storage.DeleteObject(ctx, attachment.Key); err != nil {
+		logging.FromContext(ctx).Error("failed to delete attachment file", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to delete attachment file"}`,
+		}, nil
+	}
+	err = db.DeleteAttachment(*attachment)
+	if err != nil && !errors.Is(err, db.ErrAttachmentNotFound) {
+		logging.FromContext(ctx).Error("failed to delete attachment", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to delete attachment"}`,
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Attachment deleted successfully",
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
@@ -0,0 +1,170 @@
+// Package getnote is the handler of GET /notes/{noteId}
+package getnote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/render"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                  "application/json",
+		"Access-Control-Allow-Origin":   "*",
+		"Access-Control-Allow-Headers":  "Content-Type,X-Amz-Date,Authorization,X-Api-Key,If-None-Match,If-Modified-Since",
+		"Access-Control-Allow-Methods":  "GET,OPTIONS",
+		"Access-Control-Expose-Headers": "ETag,Last-Modified",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Get note ID from path parameters
+	noteID := request.PathParameters["noteId"]
+	if noteID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note ID is required"}`,
+		}, nil
+	}
+
+	// Get note, either owned by or shared with the user
+	access, err := db.GetNoteAccess(noteID, claims.UserID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note not found"}`,
+		}, nil
+	}
+	note := access.Note
+
+	// Render the content on request, ?render=html, the server cannot render
+	// encrypted notes
+	renderHTML := request.QueryStringParameters["render"] == "html" && note.Encrypted == nil
+	if renderHTML {
+		note.ContentHTML, err = render.NoteHTML(*note)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to render note", "error", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: 500,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Failed to render note"}`,
+			}, nil
+		}
+	}
+
+	// Set validators, the entity tag from the note as it is sent
+	etag, err := noteETag(note)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+	headers["ETag"] = etag
+	headers["Cache-Control"] = "private, no-cache"
+	lastModified, err := time.Parse(time.RFC3339, note.UpdatedAt)
+	if err == nil {
+		headers["Last-Modified"] = lastModified.UTC().Format(http.TimeFormat)
+	}
+
+	// Answer conditional requests without a body when the client copy is current
+	if notModified(request.Headers, etag, lastModified) {
+		delete(headers, "Content-Type")
+		return events.APIGatewayProxyResponse{
+			StatusCode: 304,
+			Headers:    headers,
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Note retrieved successfully",
+		Data:    note,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
+
+// noteETag derives a strong entity tag from a hash of the note's JSON, so
+// that two writes within the second of UpdatedAt still get different tags.
+// The rendered representation carries contentHtml and gets a tag of its own.
+func noteETag(note *models.Note) (string, error) {
+	representation, err := json.Marshal(note)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(representation)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since as described in
+// RFC 9110. If-None-Match wins when both are present.
+func notModified(requestHeaders map[string]string, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := requestHeaders["If-None-Match"]; ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := requestHeaders["If-Modified-Since"]; ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
@@ -0,0 +1,182 @@
+// Package getnotes is the handler of GET /notes
+package getnotes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/render"
+)
+
+const maxLimit = 100
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "GET,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Hide archived notes unless asked for, optionally keep favorites only
+	filter := db.NoteFilter{
+		NotebookID:      request.QueryStringParameters["notebookId"],
+		IncludeArchived: request.QueryStringParameters["archived"] == "true",
+		FavoritesOnly:   request.QueryStringParameters["favorite"] == "true",
+	}
+
+	// Return one page when a limit is given, starting after the cursor, or
+	// every note otherwise
+	var notes []models.Note
+	var nextCursor string
+	if rawLimit := request.QueryStringParameters["limit"]; rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Limit must be between 1 and 100"}`,
+			}, nil
+		}
+		notes, nextCursor, err = db.GetNotesPage(claims.UserID, filter, request.QueryStringParameters["cursor"], limit)
+		if errors.Is(err, db.ErrInvalidCursor) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Invalid cursor"}`,
+			}, nil
+		}
+	} else {
+		notes, err = allNotes(claims.UserID, filter)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to retrieve notes", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to retrieve notes"}`,
+		}, nil
+	}
+	if notes == nil {
+		notes = []models.Note{}
+	}
+
+	// Render the content on request, ?render=html
+	if request.QueryStringParameters["render"] == "html" {
+		for i := range notes {
+			// The server cannot render encrypted notes
+			if notes[i].Encrypted != nil {
+				continue
+			}
+			notes[i].ContentHTML, err = render.NoteHTML(notes[i])
+			if err != nil {
+				logging.FromContext(ctx).Error("failed to render notes", "error", err)
+				return events.APIGatewayProxyResponse{
+					StatusCode: 500,
+					Headers:    headers,
+					Body:       `{"success":false,"message":"Failed to render notes"}`,
+				}, nil
+			}
+		}
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success:    true,
+		Message:    "Notes retrieved successfully",
+		Data:       notes,
+		NextCursor: nextCursor,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
+
+// allNotes returns every note matching filter, pinned notes first and then
+// newest first, ties broken by ID
+func allNotes(userID string, filter db.NoteFilter) ([]models.Note, error) {
+	var notes []models.Note
+	var err error
+	if filter.NotebookID != "" {
+		notes, err = db.GetNotesByNotebookID(filter.NotebookID, userID)
+	} else {
+		notes, err = db.GetNotesByUserID(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	notes = filterNotes(notes, filter)
+	sort.SliceStable(notes, func(i, j int) bool {
+		return before(notes[i], notes[j])
+	})
+	return notes, nil
+}
+
+// filterNotes drops archived and non-favorite notes as requested
+func filterNotes(notes []models.Note, filter db.NoteFilter) []models.Note {
+	filtered := make([]models.Note, 0, len(notes))
+	for _, note := range notes {
+		if note.Archived && !filter.IncludeArchived {
+			continue
+		}
+		if filter.FavoritesOnly && !note.Favorite {
+			continue
+		}
+		filtered = append(filtered, note)
+	}
+	return filtered
+}
+
+// before reports whether note a is listed before note b
+func before(a, b models.Note) bool {
+	if a.Pinned != b.Pinned {
+		return a.Pinned
+	}
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt > b.CreatedAt
+	}
+	return a.NoteID < b.NoteID
+}
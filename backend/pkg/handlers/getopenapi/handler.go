@@ -0,0 +1,33 @@
+// Package getopenapi is the handler of GET /openapi.json
+package getopenapi
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Document is the OpenAPI document of the REST API, generated from the route
+// table in pkg/api. TestSpec in that package fails when it is out of date.
+//
+//go:embed openapi.json
+var Document []byte
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "GET,OPTIONS",
+		"Cache-Control":                "public, max-age=300",
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(Document),
+	}, nil
+}
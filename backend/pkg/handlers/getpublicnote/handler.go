@@ -0,0 +1,154 @@
+// Package getpublicnote is the handler of GET /public/{token}
+package getpublicnote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/render"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,X-Api-Key,X-Share-Password",
+		"Access-Control-Allow-Methods": "GET,OPTIONS",
+		"Cache-Control":                "no-store",
+		"X-Robots-Tag":                 "noindex",
+	}
+
+	// Get token from path parameters
+	token := request.PathParameters["token"]
+	if token == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Token is required"}`,
+		}, nil
+	}
+
+	// The password of protected links only comes in a header, never in the
+	// URL where logs and browser history would keep it
+	password := request.Headers["X-Share-Password"]
+
+	// Resolve share link
+	note, err := db.GetSharedNoteByLink(token, password)
+	if err != nil {
+		statusCode := 500
+		message := "Failed to retrieve note"
+		var lockedErr *db.ShareLinkLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			statusCode, message = 429, "Too many wrong passwords, try again later"
+			headers["Retry-After"] = strconv.Itoa(int(time.Until(lockedErr.Until).Seconds()) + 1)
+		case errors.Is(err, db.ErrShareLinkNotFound):
+			statusCode, message = 404, "Share link not found"
+		case errors.Is(err, db.ErrShareLinkExpired):
+			statusCode, message = 410, "Share link expired"
+		case errors.Is(err, db.ErrShareLinkPassword):
+			statusCode, message = 401, "Password required or incorrect"
+		default:
+			logging.FromContext(ctx).Error("failed to retrieve note", "error", err)
+		}
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: message})
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// A note encrypted after it was published is no longer readable by link
+	if note.Encrypted != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Share link not found"}`,
+		}, nil
+	}
+
+	// Only publish what a reader needs, never the owner's IDs
+	contentHTML, err := render.NoteHTML(*note)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to render note", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to render note"}`,
+		}, nil
+	}
+	publicNote := models.PublicNote{
+		Title:       note.Title,
+		Content:     note.Content,
+		Format:      note.Format,
+		Type:        note.Type,
+		Items:       note.Items,
+		ContentHTML: contentHTML,
+		UpdatedAt:   note.UpdatedAt,
+	}
+
+	// Render sanitised HTML for browsers
+	if wantsHTML(request) {
+		page, err := render.PublicNotePage(publicNote)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to render note", "error", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: 500,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Failed to render note"}`,
+			}, nil
+		}
+		headers["Content-Type"] = "text/html; charset=utf-8"
+		headers["Content-Security-Policy"] = "default-src 'none'; style-src 'unsafe-inline'; img-src https: data:"
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers:    headers,
+			Body:       page,
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Note retrieved successfully",
+		Data:    publicNote,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
+
+// wantsHTML reports whether the client asked for an HTML page, either with
+// ?format=html or by preferring text/html over JSON
+func wantsHTML(request events.APIGatewayProxyRequest) bool {
+	switch request.QueryStringParameters["format"] {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+	return strings.Contains(request.Headers["Accept"], "text/html")
+}
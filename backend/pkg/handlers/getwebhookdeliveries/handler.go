@@ -0,0 +1,126 @@
+// Package getwebhookdeliveries is the handler of GET /webhooks/{webhookId}/deliveries
+package getwebhookdeliveries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Delivery history page sizes
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "GET,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Get webhook ID from path parameters
+	webhookID := request.PathParameters["webhookId"]
+	if webhookID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Webhook ID is required"}`,
+		}, nil
+	}
+
+	// Parse limit from query parameters
+	limit := defaultLimit
+	if value := request.QueryStringParameters["limit"]; value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"limit must be a number between 1 and 100"}`,
+			}, nil
+		}
+	}
+
+	// Deliveries are keyed by webhook only, check that the webhook belongs to the user
+	_, err = db.GetWebhook(webhookID, claims.UserID)
+	if errors.Is(err, db.ErrWebhookNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Webhook not found"}`,
+		}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to retrieve webhook", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to retrieve webhook"}`,
+		}, nil
+	}
+
+	// Get latest deliveries
+	deliveries, err := db.GetWebhookDeliveries(webhookID, limit)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to retrieve deliveries", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to retrieve deliveries"}`,
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Deliveries retrieved successfully",
+		Data:    deliveries,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
@@ -0,0 +1,308 @@
+// Package patchnote is the handler of PATCH /notes/{noteId}
+package patchnote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/patch"
+	"github.com/omidiyanto/mino/pkg/validate"
+)
+
+// noteFields are the note fields a client may patch
+var noteFields = map[string]patch.Field{
+	"title":      {Kind: patch.String},
+	"content":    {Kind: patch.String},
+	"format":     {Kind: patch.String, Removable: true}, // Removing makes the note plain text
+	"notebookId": {Kind: patch.String, Removable: true}, // Removing moves the note to the default notebook
+	"pinned":     {Kind: patch.Bool},
+	"archived":   {Kind: patch.Bool},
+	"favorite":   {Kind: patch.Bool},
+	"remindAt":   {Kind: patch.String, Removable: true}, // Removing cancels the reminder
+}
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "PATCH,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Get note ID from path parameters
+	noteID := request.PathParameters["noteId"]
+	if noteID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note ID is required"}`,
+		}, nil
+	}
+
+	// Parse merge patch or JSON patch from request body
+	notePatch, err := patch.Parse(request.Headers["Content-Type"], []byte(request.Body), noteFields)
+	if err != nil {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: fmt.Sprintf("Invalid patch: %s", err.Error())})
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	if err := validatePatch(notePatch); err != nil {
+		status, response := validate.Failure(err)
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	if remindAt, ok := notePatch.Set["remindAt"].(string); ok && !db.ValidRemindAt(remindAt) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"remindAt must be an RFC3339 time in the future"}`,
+		}, nil
+	}
+
+	if notePatch.Empty() {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Patch does not change any field"}`,
+		}, nil
+	}
+
+	// Check the user owns the note or may edit it through a share
+	access, err := db.GetNoteAccess(noteID, claims.UserID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note not found"}`,
+		}, nil
+	}
+	if !access.CanEdit() {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 403,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"You do not have permission to edit this note"}`,
+		}, nil
+	}
+
+	// Checklist notes hold items instead of content
+	if content, ok := notePatch.Set["content"].(string); ok && content != "" && access.Note.Type == db.NoteTypeChecklist {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"checklist notes hold items instead of content"}`,
+		}, nil
+	}
+
+	// Encrypted notes hold ciphertext, which only a full update replaces
+	if content, ok := notePatch.Set["content"].(string); ok && content != "" && access.Note.Encrypted != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"encrypted notes hold ciphertext instead of content"}`,
+		}, nil
+	}
+
+	// Notebook and pinned, archived or favorite state belong to the owner
+	if !access.IsOwner() && touchesOwnerFields(notePatch) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 403,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Only the owner can change the notebook or state of this note"}`,
+		}, nil
+	}
+
+	// Make sure the target notebook belongs to the user
+	if notebookID, ok := notePatch.Set["notebookId"].(string); ok && notebookID != "" {
+		if _, err := db.GetNotebookByID(notebookID, claims.UserID); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Notebook not found"}`,
+			}, nil
+		}
+	} else if ok {
+		// An empty notebook ID means the default notebook
+		delete(notePatch.Set, "notebookId")
+		notePatch.Remove = append(notePatch.Remove, "notebookId")
+	}
+
+	// Check the patched note's size and the owner's quotas
+	patched := *access.Note
+	if title, ok := notePatch.Set["title"].(string); ok {
+		patched.Title = title
+	}
+	if content, ok := notePatch.Set["content"].(string); ok {
+		patched.Content = content
+	}
+	if err := db.CheckNoteLimits(access.OwnerID, patched, access.Note); err != nil {
+		var limitErr *db.LimitError
+		if !errors.As(err, &limitErr) {
+			logging.FromContext(ctx).Error("failed to check quota", "error", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: 500,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Failed to check quota"}`,
+			}, nil
+		}
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: limitErr.Message, Code: limitErr.Code})
+		return events.APIGatewayProxyResponse{
+			StatusCode: limitErr.StatusCode(),
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Patch note
+	note, err := db.PatchNote(noteID, access.OwnerID, notePatch)
+	if errors.Is(err, db.ErrNoteNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note not found"}`,
+		}, nil
+	}
+	if errors.Is(err, db.ErrPatchTestFailed) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 409,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Patch test operation failed"}`,
+		}, nil
+	}
+	if errors.Is(err, db.ErrVersionConflict) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 409,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note was changed concurrently, try again"}`,
+		}, nil
+	}
+	var limitErr *db.LimitError
+	if errors.As(err, &limitErr) {
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: limitErr.Message, Code: limitErr.Code})
+		return events.APIGatewayProxyResponse{
+			StatusCode: limitErr.StatusCode(),
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to update note", "error", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to update note"}`,
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Note updated successfully",
+		Data:    note,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
+
+// validatePatch checks the fields a patch sets against the validate rules of
+// the note model. The stored note passed them already, so the fields the
+// patch leaves alone need no check, and the patch is checked before the note
+// is read.
+func validatePatch(p patch.Patch) error {
+	raw, err := json.Marshal(p.Set)
+	if err != nil {
+		return err
+	}
+	var note models.Note
+	if err := json.Unmarshal(raw, &note); err != nil {
+		return err
+	}
+
+	var validationErr *validate.Error
+	if err := validate.Struct(note); !errors.As(err, &validationErr) {
+		return err
+	}
+	var fieldErrs []models.FieldError
+	for _, fieldErr := range validationErr.Errors {
+		if _, ok := p.Set[fieldErr.Field]; ok {
+			fieldErrs = append(fieldErrs, fieldErr)
+		}
+	}
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+	return &validate.Error{Errors: fieldErrs}
+}
+
+// touchesOwnerFields reports whether a patch changes anything but the title,
+// content or content format
+func touchesOwnerFields(p patch.Patch) bool {
+	for field := range p.Set {
+		if !editorFields[field] {
+			return true
+		}
+	}
+	for _, field := range p.Remove {
+		if !editorFields[field] {
+			return true
+		}
+	}
+	return false
+}
+
+// editorFields are the fields users a note is shared with may change
+var editorFields = map[string]bool{
+	"title":   true,
+	"content": true,
+	"format":  true,
+}
@@ -0,0 +1,74 @@
+package patchnote
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/patch"
+)
+
+func TestHandlerValidatesPatchedFields(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	token, err := auth.GenerateToken(models.User{UserID: "u1", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"empty title", patch.MergePatchType, `{"title":""}`, "title:required"},
+		{"blank title", patch.JSONPatchType, `[{"op":"replace","path":"/title","value":"  "}]`, "title:required"},
+		{"notebook ID", patch.MergePatchType, `{"notebookId":"work"}`, "notebookId:format"},
+		{"format", patch.MergePatchType, `{"format":"html"}`, "format:oneof"},
+		{"every field", patch.MergePatchType, `{"title":"","format":"html","remindAt":"tomorrow"}`, "title:required format:oneof remindAt:format"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := Handler(context.Background(), events.APIGatewayProxyRequest{
+				Headers: map[string]string{
+					"Authorization": "Bearer " + token,
+					"Content-Type":  test.contentType,
+				},
+				PathParameters: map[string]string{"noteId": "n1"},
+				Body:           test.body,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.StatusCode != 422 {
+				t.Fatalf("got status %d, want 422: %s", response.StatusCode, response.Body)
+			}
+
+			var body models.APIResponse
+			if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, fieldErr := range body.Errors {
+				got = append(got, fieldErr.Field+":"+fieldErr.Rule)
+			}
+			if strings.Join(got, " ") != test.want {
+				t.Errorf("got errors %v, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidatePatchIgnoresUnpatchedFields(t *testing.T) {
+	// A zero note has no title, which only matters when the patch sets one
+	if err := validatePatch(patch.Patch{Set: map[string]interface{}{"pinned": true}}); err != nil {
+		t.Errorf("got %v for a patch that sets no validated field", err)
+	}
+	if err := validatePatch(patch.Patch{Set: map[string]interface{}{"title": "Groceries", "format": "markdown"}}); err != nil {
+		t.Errorf("got %v for a valid patch", err)
+	}
+}
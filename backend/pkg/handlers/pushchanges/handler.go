@@ -0,0 +1,283 @@
+// Package pushchanges is the handler of POST /sync
+package pushchanges
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/validate"
+)
+
+// maxChanges is the largest batch a client may push at once
+const maxChanges = 100
+
+// Sync operations
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+// Result states
+const (
+	statusApplied  = "applied"
+	statusConflict = "conflict"
+	statusRejected = "rejected"
+	statusError    = "error"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "GET,POST,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Parse request body
+	var push models.SyncPush
+	if err := validate.Decode(request.Body, &push); err != nil {
+		statusCode, response := validate.Failure(err)
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+	if len(push.Changes) > maxChanges {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       fmt.Sprintf(`{"success":false,"message":"At most %d changes can be pushed at once"}`, maxChanges),
+		}, nil
+	}
+
+	// Apply the changes in order, each one succeeds or fails on its own
+	results := make([]models.SyncResult, 0, len(push.Changes))
+	for _, change := range push.Changes {
+		results = append(results, apply(ctx, change, claims.UserID))
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Changes processed",
+		Data:    results,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
+
+// apply applies one pushed change to a note of the user
+func apply(ctx context.Context, change models.SyncChange, userID string) models.SyncResult {
+	result := models.SyncResult{ClientID: change.ClientID, Op: change.Op, NoteID: change.NoteID}
+
+	switch change.Op {
+	case opCreate:
+		if change.NoteID != "" {
+			if _, err := uuid.Parse(change.NoteID); err != nil {
+				return reject(result, "noteId must be a UUID")
+			}
+		}
+		if err := check(change.Note, userID, nil); err != nil {
+			return invalid(result, err)
+		}
+
+		note := *change.Note
+		note.NoteID = change.NoteID
+		note.UserID = userID
+		note.ContentHTML = ""
+		err := db.CreateSyncedNote(&note)
+		if errors.Is(err, db.ErrNoteExists) {
+			return conflict(result, userID)
+		}
+		if errors.Is(err, db.ErrNoteIDTaken) {
+			return reject(result, "noteId is already in use, choose another one")
+		}
+		var limitErr *db.LimitError
+		if errors.As(err, &limitErr) {
+			return invalid(result, err)
+		}
+		if err != nil {
+			return fail(ctx, result, err)
+		}
+		result.NoteID = note.NoteID
+		result.Status = statusApplied
+		result.Version = note.Version
+		return result
+
+	case opUpdate:
+		if change.NoteID == "" {
+			return reject(result, "noteId is required")
+		}
+		existing, err := db.GetNoteByID(change.NoteID, userID)
+		if errors.Is(err, db.ErrNoteNotFound) {
+			return conflict(result, userID)
+		}
+		if err != nil {
+			return fail(ctx, result, err)
+		}
+		if err := check(change.Note, userID, existing); err != nil {
+			return invalid(result, err)
+		}
+
+		note := *change.Note
+		note.NoteID = change.NoteID
+		note.UserID = userID
+		note.ContentHTML = ""
+		updated, err := db.UpdateNoteAtVersion(note, change.BaseVersion)
+		if errors.Is(err, db.ErrVersionConflict) || errors.Is(err, db.ErrNoteNotFound) {
+			return conflict(result, userID)
+		}
+		var limitErr *db.LimitError
+		if errors.As(err, &limitErr) {
+			return invalid(result, err)
+		}
+		if err != nil {
+			return fail(ctx, result, err)
+		}
+		result.Status = statusApplied
+		result.Version = updated.Version
+		return result
+
+	case opDelete:
+		if change.NoteID == "" {
+			return reject(result, "noteId is required")
+		}
+		err := db.DeleteNoteAtVersion(change.NoteID, userID, change.BaseVersion)
+		if errors.Is(err, db.ErrVersionConflict) {
+			return conflict(result, userID)
+		}
+		if err != nil {
+			return fail(ctx, result, err)
+		}
+		result.Status = statusApplied
+		return result
+
+	default:
+		return reject(result, "op must be create, update or delete")
+	}
+}
+
+// check checks a pushed note the way create_note and update_note do.
+// existing is the stored note for an update, nil for a create.
+func check(note *models.Note, userID string, existing *models.Note) error {
+	if note == nil {
+		return errors.New("note is required")
+	}
+	if err := validate.Struct(note); err != nil {
+		return err
+	}
+
+	effective := *note
+	if existing != nil && effective.Type == "" {
+		effective.Type = existing.Type
+	}
+	if err := db.ValidateNoteBody(effective); err != nil {
+		return err
+	}
+
+	unchangedReminder := existing != nil && note.RemindAt == existing.RemindAt
+	if note.RemindAt != "" && !unchangedReminder && !db.ValidRemindAt(note.RemindAt) {
+		return errors.New("remindAt must be an RFC3339 time in the future")
+	}
+
+	if existing == nil && note.NotebookID != "" {
+		if _, err := db.GetNotebookByID(note.NotebookID, userID); err != nil {
+			return errors.New("notebook not found")
+		}
+	}
+	if err := db.CheckNoteKey(*note, userID); err != nil {
+		return err
+	}
+	return db.CheckNoteLimits(userID, *note, existing)
+}
+
+// conflict reports a change that lost against the server copy, which is
+// returned for the client to resolve against
+func conflict(result models.SyncResult, userID string) models.SyncResult {
+	result.Status = statusConflict
+	result.Message = "note was changed or deleted on the server"
+
+	note, err := db.GetNoteByID(result.NoteID, userID)
+	if err == nil {
+		result.Note = note
+		result.Version = note.Version
+	}
+	return result
+}
+
+// reject reports a change that is invalid and will never apply
+func reject(result models.SyncResult, message string) models.SyncResult {
+	result.Status = statusRejected
+	result.Message = message
+	return result
+}
+
+// invalid rejects a change that failed validation, with the error code of
+// broken field rules or a size limit or quota
+func invalid(result models.SyncResult, err error) models.SyncResult {
+	result = reject(result, err.Error())
+	var validationErr *validate.Error
+	var limitErr *db.LimitError
+	switch {
+	case errors.As(err, &validationErr):
+		result.Code = validate.CodeValidationFailed
+		result.Errors = validationErr.Errors
+	case errors.As(err, &limitErr):
+		result.Code = limitErr.Code
+	}
+	return result
+}
+
+// fail reports a change that could not be applied for now
+func fail(ctx context.Context, result models.SyncResult, err error) models.SyncResult {
+	logging.FromContext(ctx).Error("sync change failed", "op", result.Op, "noteId", result.NoteID, "error", err)
+	result.Status = statusError
+	result.Message = "Failed to apply change, push it again later"
+	return result
+}
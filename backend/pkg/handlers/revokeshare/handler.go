@@ -0,0 +1,100 @@
+// Package revokeshare is the handler of DELETE /notes/{noteId}/shares/{userId}
+package revokeshare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "DELETE,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Get note ID and shared user ID from path parameters
+	noteID := request.PathParameters["noteId"]
+	sharedWithUserID := request.PathParameters["userId"]
+	if noteID == "" || sharedWithUserID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note ID and user ID are required"}`,
+		}, nil
+	}
+
+	// The owner may revoke any share, a recipient may leave a note shared with them
+	ownerID := claims.UserID
+	if _, err := db.GetNoteByID(noteID, claims.UserID); err != nil {
+		share, shareErr := db.GetShare(noteID, claims.UserID)
+		if sharedWithUserID != claims.UserID || shareErr != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 404,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Note not found"}`,
+			}, nil
+		}
+		ownerID = share.OwnerID
+	}
+
+	// Revoke share
+	err = db.RevokeShare(noteID, sharedWithUserID, ownerID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       fmt.Sprintf(`{"success":false,"message":"%s"}`, err.Error()),
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Share revoked successfully",
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
@@ -0,0 +1,170 @@
+// Package updatechecklistitem is the handler of PUT /notes/{noteId}/items/{itemId}
+package updatechecklistitem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/omidiyanto/mino/pkg/validate"
+)
+
+// Handler is the Lambda function handler
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Set CORS headers
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
+		"Access-Control-Allow-Methods": "PUT,OPTIONS",
+	}
+
+	// Get authorization token
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Authorization required"}`,
+		}, nil
+	}
+
+	// Verify token
+	claims, err := auth.ParseToken(authHeader)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Invalid token"}`,
+		}, nil
+	}
+
+	// Get note ID from path parameters
+	noteID := request.PathParameters["noteId"]
+	if noteID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note ID is required"}`,
+		}, nil
+	}
+
+	// Get item ID from path parameters
+	itemID := request.PathParameters["itemId"]
+	if itemID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Item ID is required"}`,
+		}, nil
+	}
+
+	// Parse item changes from request body
+	var update models.ChecklistItemUpdate
+	if err := validate.Decode(request.Body, &update); err != nil {
+		statusCode, response := validate.Failure(err)
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Check the new item text
+	if update.Text != nil {
+		if err := db.ValidateChecklistItemText(*update.Text); err != nil {
+			body, _ := json.Marshal(models.APIResponse{Success: false, Message: err.Error()})
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Headers:    headers,
+				Body:       string(body),
+			}, nil
+		}
+	}
+
+	// Check the user owns the note or may edit it through a share
+	access, err := db.GetNoteAccess(noteID, claims.UserID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Note not found"}`,
+		}, nil
+	}
+	if !access.CanEdit() {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 403,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"You do not have permission to edit this note"}`,
+		}, nil
+	}
+
+	// Check the note's size with the new text and the owner's quotas
+	grown := *access.Note
+	grown.Items = append([]models.ChecklistItem(nil), access.Note.Items...)
+	for i := range grown.Items {
+		if grown.Items[i].ItemID == itemID && update.Text != nil {
+			grown.Items[i].Text = *update.Text
+		}
+	}
+	if err := db.CheckNoteLimits(access.OwnerID, grown, access.Note); err != nil {
+		var limitErr *db.LimitError
+		if !errors.As(err, &limitErr) {
+			logging.FromContext(ctx).Error("failed to check quota", "error", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: 500,
+				Headers:    headers,
+				Body:       `{"success":false,"message":"Failed to check quota"}`,
+			}, nil
+		}
+		body, _ := json.Marshal(models.APIResponse{Success: false, Message: limitErr.Message, Code: limitErr.Code})
+		return events.APIGatewayProxyResponse{
+			StatusCode: limitErr.StatusCode(),
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Update the item in place
+	note, err := db.UpdateChecklistItem(*access.Note, itemID, update)
+	if err != nil {
+		statusCode, response := db.ChecklistFailure(err)
+		if statusCode == 500 {
+			logging.FromContext(ctx).Error("failed to update checklist", "error", err)
+		}
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+			Body:       string(body),
+		}, nil
+	}
+
+	// Create response
+	response := models.APIResponse{
+		Success: true,
+		Message: "Item updated successfully",
+		Data:    note,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Headers:    headers,
+			Body:       `{"success":false,"message":"Failed to create response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
@@ -0,0 +1,204 @@
+// Package logging writes structured JSON logs to stdout, where Lambda sends
+// them on to CloudWatch Logs.
+//
+// Importing the package makes its logger the default one, so that
+// slog.Info and the log package write JSON too. The level is read from
+// LOG_LEVEL: debug, info (the default), warn or error.
+//
+// Middleware gives every request a logger carrying its request IDs, route
+// and user, which handlers get with FromContext, and logs the outcome of the
+// request when it completes.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/google/uuid"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/router"
+)
+
+// RequestIDHeader is the header a client may send a request ID in, and
+// which every response carries the request ID in
+const RequestIDHeader = "X-Request-ID"
+
+// Redacted replaces the value of a sensitive attribute
+const Redacted = "[REDACTED]"
+
+// sensitive are parts of the keys whose values are never logged
+var sensitive = []string{"authorization", "password", "token", "secret", "cookie", "credential"}
+
+// requestIDPattern is what a client's request ID may look like, anything
+// else is replaced rather than written to the logs
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+func init() {
+	slog.SetDefault(New(os.Stdout, ParseLevel(os.Getenv("LOG_LEVEL"))))
+}
+
+// New returns a logger writing JSON to w from a level, with sensitive
+// attributes redacted
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}))
+}
+
+// ParseLevel parses a level name, info when it is empty or unknown
+func ParseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// redact hides the values of attributes whose key looks sensitive, in
+// groups too
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() == slog.KindGroup {
+		return attr
+	}
+	key := strings.ToLower(attr.Key)
+	for _, part := range sensitive {
+		if strings.Contains(key, part) {
+			return slog.String(attr.Key, Redacted)
+		}
+	}
+	return attr
+}
+
+type contextKey struct{}
+
+// WithLogger returns a context carrying a logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger of a request, or the default logger
+// outside of one
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Middleware logs every request a handler serves and gives the handler a
+// logger for the request. The request ID is the client's X-Request-ID when
+// it sent a usable one, and the API Gateway or Lambda request ID otherwise;
+// it is echoed back in the X-Request-ID response header.
+func Middleware(next router.HandlerFunc) router.HandlerFunc {
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		start := time.Now()
+
+		var lambdaRequestID string
+		if lc, ok := lambdacontext.FromContext(ctx); ok {
+			lambdaRequestID = lc.AwsRequestID
+		}
+		requestID := requestID(request.Headers[http.CanonicalHeaderKey(RequestIDHeader)], request.RequestContext.RequestID, lambdaRequestID)
+
+		attrs := []any{
+			slog.String("requestId", requestID),
+			slog.String("route", route(request)),
+		}
+		if request.RequestContext.RequestID != "" {
+			attrs = append(attrs, slog.String("apiRequestId", request.RequestContext.RequestID))
+		}
+		if lambdaRequestID != "" {
+			attrs = append(attrs, slog.String("lambdaRequestId", lambdaRequestID))
+		}
+		// The handler checks the token, this only names the user in the logs
+		if header := request.Headers["Authorization"]; header != "" {
+			if claims, err := auth.ParseToken(header); err == nil {
+				attrs = append(attrs, slog.String("userId", claims.UserID))
+			}
+		}
+		logger := FromContext(ctx).With(attrs...)
+		ctx = WithLogger(ctx, logger)
+
+		logger.Debug("request",
+			slog.String("path", request.Path),
+			group("query", request.QueryStringParameters),
+			group("headers", request.Headers),
+		)
+
+		response, err := next(ctx, request)
+
+		latency := slog.Int64("latencyMs", time.Since(start).Milliseconds())
+		switch {
+		case err != nil:
+			logger.Error("request failed", latency, slog.String("outcome", "error"), slog.Any("error", err))
+			return response, err
+		case response.StatusCode >= 500:
+			logger.Error("request completed", latency, slog.Int("status", response.StatusCode), slog.String("outcome", "server_error"))
+		case response.StatusCode >= 400:
+			logger.Info("request completed", latency, slog.Int("status", response.StatusCode), slog.String("outcome", "client_error"))
+		default:
+			logger.Info("request completed", latency, slog.Int("status", response.StatusCode), slog.String("outcome", "success"))
+		}
+
+		headers := make(map[string]string, len(response.Headers)+2)
+		for name, value := range response.Headers {
+			headers[name] = value
+		}
+		headers[RequestIDHeader] = requestID
+		headers["Access-Control-Expose-Headers"] = exposeHeader(headers["Access-Control-Expose-Headers"])
+		response.Headers = headers
+		return response, nil
+	}
+}
+
+// requestID returns the first usable request ID, a new one when there is
+// none
+func requestID(candidates ...string) string {
+	for _, id := range candidates {
+		if requestIDPattern.MatchString(id) {
+			return id
+		}
+	}
+	return uuid.New().String()
+}
+
+// route names the route of a request, by its template when the event source
+// matched one
+func route(request events.APIGatewayProxyRequest) string {
+	path := request.Resource
+	if path == "" {
+		path = request.Path
+	}
+	return request.HTTPMethod + " " + path
+}
+
+// exposeHeader adds the request ID header to the headers browsers may read
+func exposeHeader(exposed string) string {
+	if exposed == "" {
+		return RequestIDHeader
+	}
+	for _, name := range strings.Split(exposed, ",") {
+		if strings.EqualFold(strings.TrimSpace(name), RequestIDHeader) {
+			return exposed
+		}
+	}
+	return exposed + "," + RequestIDHeader
+}
+
+// group logs a map of strings as a group, so that each key is redacted on
+// its own
+func group(key string, values map[string]string) slog.Attr {
+	attrs := make([]any, 0, len(values))
+	for name, value := range values {
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Group(key, attrs...)
+}
@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/omidiyanto/mino/pkg/auth"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// records decodes the JSON lines a logger wrote
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("%q is not JSON: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+		"WARN":    slog.LevelWarn,
+		" error ": slog.LevelError,
+		"verbose": slog.LevelInfo,
+	} {
+		if got := ParseLevel(name); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, slog.LevelInfo).Info("login",
+		"password", "correct horse",
+		"email", "ada@example.com",
+		group("headers", map[string]string{"Authorization": "Bearer t", "Cookie": "s=1", "Accept": "text/html"}),
+		group("query", map[string]string{"password": "p", "shareToken": "s", "render": "html"}),
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"correct horse", "Bearer t", "s=1", `"p"`, `"s"`} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%s leaked in %s", secret, out)
+		}
+	}
+	for _, kept := range []string{"ada@example.com", "text/html", `"render":"html"`} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("%s missing from %s", kept, out)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	token, err := auth.GenerateToken(models.User{UserID: "u1", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		header    string
+		status    int
+		err       error
+		requestID string
+		level     string
+		outcome   string
+	}{
+		{"client request ID", "req-42", 200, nil, "req-42", "INFO", "success"},
+		{"unusable client request ID", "no spaces please", 404, nil, "api-1", "INFO", "client_error"},
+		{"server error", "", 503, nil, "api-1", "ERROR", "server_error"},
+		{"handler error", "", 0, errors.New("boom"), "api-1", "ERROR", "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ctx := WithLogger(context.Background(), New(&buf, slog.LevelInfo))
+			ctx = lambdacontext.NewContext(ctx, &lambdacontext.LambdaContext{AwsRequestID: "lambda-1"})
+
+			var handlerLogger *slog.Logger
+			handler := Middleware(func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				handlerLogger = FromContext(ctx)
+				return events.APIGatewayProxyResponse{
+					StatusCode: tt.status,
+					Headers:    map[string]string{"Content-Type": "application/json"},
+				}, tt.err
+			})
+
+			headers := map[string]string{"Authorization": "Bearer " + token}
+			if tt.header != "" {
+				headers["X-Request-Id"] = tt.header
+			}
+			response, err := handler(ctx, events.APIGatewayProxyRequest{
+				HTTPMethod:     "GET",
+				Resource:       "/notes/{id}",
+				Path:           "/notes/n1",
+				Headers:        headers,
+				RequestContext: events.APIGatewayProxyRequestContext{RequestID: "api-1"},
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if tt.err == nil {
+				if got := response.Headers[RequestIDHeader]; got != tt.requestID {
+					t.Errorf("echoed request ID %q, want %q", got, tt.requestID)
+				}
+				if response.Headers["Content-Type"] != "application/json" || response.Headers["Access-Control-Expose-Headers"] != RequestIDHeader {
+					t.Errorf("got headers %v", response.Headers)
+				}
+			}
+
+			handlerLogger.Warn("from the handler")
+			logged := records(t, &buf)
+			if len(logged) != 2 {
+				t.Fatalf("logged %d records, want 2", len(logged))
+			}
+			for _, record := range logged {
+				for key, want := range map[string]interface{}{
+					"requestId":       tt.requestID,
+					"apiRequestId":    "api-1",
+					"lambdaRequestId": "lambda-1",
+					"route":           "GET /notes/{id}",
+					"userId":          "u1",
+				} {
+					if record[key] != want {
+						t.Errorf("%q is %v in %v, want %v", key, record[key], record, want)
+					}
+				}
+			}
+			if completed := logged[0]; completed["level"] != tt.level || completed["outcome"] != tt.outcome {
+				t.Errorf("got %v, want level %s and outcome %s", completed, tt.level, tt.outcome)
+			}
+		})
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	handler := Middleware(func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: 204}, nil
+	})
+	ctx := WithLogger(context.Background(), New(&bytes.Buffer{}, slog.LevelInfo))
+	response, err := handler(ctx, events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Headers[RequestIDHeader]) != 36 {
+		t.Errorf("got request ID %q, want a UUID", response.Headers[RequestIDHeader])
+	}
+}
@@ -1,35 +1,367 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // User represents a MiNo user
 type User struct {
 	UserID    string `json:"userId" dynamodbav:"userId"`
 	Email     string `json:"email" dynamodbav:"email"`
-	Password  string `json:"-" dynamodbav:"password"` // Password hash, not sent to client
+	Password  string `json:"-" dynamodbav:"password"`                    // Password hash, not sent to client
+	Plan      string `json:"plan,omitempty" dynamodbav:"plan,omitempty"` // Empty means the free plan
 	CreatedAt string `json:"createdAt" dynamodbav:"createdAt"`
+
+	// Usage counters, updated with atomic ADDs
+	NoteCount    int64 `json:"-" dynamodbav:"noteCount,omitempty"`
+	NoteBytes    int64 `json:"-" dynamodbav:"noteBytes,omitempty"`
+	StorageUsed  int64 `json:"-" dynamodbav:"storageUsed,omitempty"` // Attachment bytes
+	WebhookCount int64 `json:"-" dynamodbav:"webhookCount,omitempty"`
 }
 
 // Note represents a user's note
 type Note struct {
+	NoteID     string          `json:"noteId" dynamodbav:"noteId"`
+	UserID     string          `json:"userId" dynamodbav:"userId"`
+	NotebookID string          `json:"notebookId,omitempty" dynamodbav:"notebookId,omitempty" validate:"format=uuid"` // Empty means the default notebook
+	Title      string          `json:"title" dynamodbav:"title" validate:"required"`
+	Content    string          `json:"content" dynamodbav:"content"`
+	Format     string          `json:"format,omitempty" dynamodbav:"format,omitempty" validate:"oneof=plain markdown"` // plain or markdown, empty means plain
+	Type       string          `json:"type,omitempty" dynamodbav:"type,omitempty" validate:"oneof=text checklist"`     // text or checklist, empty means text
+	Items      []ChecklistItem `json:"items,omitempty" dynamodbav:"items,omitempty" validate:"max=500"`                // Checklist items, sorted by order
+	RemindAt   string          `json:"remindAt,omitempty" dynamodbav:"remindAt,omitempty" validate:"format=rfc3339"`   // RFC3339 time to send a reminder at
+	Pinned     bool            `json:"pinned" dynamodbav:"pinned"`
+	Archived   bool            `json:"archived" dynamodbav:"archived"`
+	Favorite   bool            `json:"favorite" dynamodbav:"favorite"`
+	CreatedAt  string          `json:"createdAt" dynamodbav:"createdAt"`
+	UpdatedAt  string          `json:"updatedAt" dynamodbav:"updatedAt"`
+	Version    int64           `json:"version" dynamodbav:"version"` // Incremented on every write, zero for notes written before versioning
+
+	Encrypted *EncryptedContent `json:"encrypted,omitempty" dynamodbav:"encrypted,omitempty"` // Set for end-to-end encrypted notes, which have no content
+
+	ContentHTML string `json:"contentHtml,omitempty" dynamodbav:"-"` // Sanitized rendering of the content, only on request
+}
+
+// Text returns the searchable text of a note: the content, or the item texts
+// of a checklist one per line
+func (n Note) Text() string {
+	if len(n.Items) == 0 {
+		return n.Content
+	}
+	texts := make([]string, 0, len(n.Items))
+	for _, item := range n.Items {
+		texts = append(texts, item.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// EncryptedContent is the envelope of an end-to-end encrypted note body. The
+// client encrypts the content with one of the user's data keys, the server
+// stores the envelope as is and cannot read it.
+type EncryptedContent struct {
+	Algorithm  string `json:"algorithm" dynamodbav:"algorithm" validate:"required"`                 // AES-256-GCM
+	KeyID      string `json:"keyId" dynamodbav:"keyId" validate:"required,format=uuid"`             // Encryption key the content was encrypted with
+	Nonce      string `json:"nonce" dynamodbav:"nonce" validate:"required,format=base64"`           // Base64
+	Ciphertext string `json:"ciphertext" dynamodbav:"ciphertext" validate:"required,format=base64"` // Base64, including the authentication tag
+}
+
+// KDFParams are the parameters a key encryption key is derived from a
+// passphrase with
+type KDFParams struct {
+	Algorithm string `json:"algorithm" dynamodbav:"algorithm"` // argon2id
+	Salt      string `json:"salt" dynamodbav:"salt"`           // Base64
+	Time      uint32 `json:"time" dynamodbav:"time"`           // Passes over memory
+	Memory    uint32 `json:"memory" dynamodbav:"memory"`       // KiB
+	Threads   uint8  `json:"threads" dynamodbav:"threads"`
+}
+
+// EncryptionKey is a user's data key, wrapped with a key derived from their
+// passphrase. The server only stores it for the user's clients to fetch.
+type EncryptionKey struct {
+	UserID     string    `json:"userId" dynamodbav:"userId"`
+	KeyID      string    `json:"keyId" dynamodbav:"keyId" validate:"format=uuid"`
+	KDF        KDFParams `json:"kdf" dynamodbav:"kdf"`
+	Algorithm  string    `json:"algorithm" dynamodbav:"algorithm" validate:"required,oneof=AES-256-GCM"` // Algorithm the data key is wrapped with
+	Nonce      string    `json:"nonce" dynamodbav:"nonce" validate:"required,format=base64"`
+	WrappedKey string    `json:"wrappedKey" dynamodbav:"wrappedKey" validate:"required,format=base64"` // Including the authentication tag
+	CreatedAt  string    `json:"createdAt" dynamodbav:"createdAt"`
+	UpdatedAt  string    `json:"updatedAt" dynamodbav:"updatedAt"`
+}
+
+// ChecklistItem is one entry of a checklist note
+type ChecklistItem struct {
+	ItemID  string `json:"itemId" dynamodbav:"itemId"`
+	Text    string `json:"text" dynamodbav:"text" validate:"required,maxbytes=1000"`
+	Checked bool   `json:"checked" dynamodbav:"checked"`
+	Order   int    `json:"order" dynamodbav:"order"`
+}
+
+// ChecklistItemUpdate represents a partial update of a checklist item, nil fields are left unchanged
+type ChecklistItemUpdate struct {
+	Text    *string `json:"text,omitempty" validate:"maxbytes=1000"`
+	Checked *bool   `json:"checked,omitempty"`
+}
+
+// ChecklistOrder lists the IDs of every item of a checklist in their new order
+type ChecklistOrder struct {
+	ItemIDs []string `json:"itemIds" validate:"required"`
+}
+
+// Reminder is an entry of the reminders index. Entries are grouped in one
+// minute buckets so that the dispatcher can query everything due in a minute.
+type Reminder struct {
+	Bucket    string `json:"bucket" dynamodbav:"bucket"`
 	NoteID    string `json:"noteId" dynamodbav:"noteId"`
 	UserID    string `json:"userId" dynamodbav:"userId"`
-	Title     string `json:"title" dynamodbav:"title"`
-	Content   string `json:"content" dynamodbav:"content"`
-	CreatedAt string `json:"createdAt" dynamodbav:"createdAt"`
-	UpdatedAt string `json:"updatedAt" dynamodbav:"updatedAt"`
+	RemindAt  string `json:"remindAt" dynamodbav:"remindAt"`
+	ClaimedAt string `json:"claimedAt,omitempty" dynamodbav:"claimedAt,omitempty"` // Set while a dispatcher delivers the reminder
+	SentAt    string `json:"sentAt,omitempty" dynamodbav:"sentAt,omitempty"`
+	TTL       int64  `json:"-" dynamodbav:"ttl,omitempty"` // Expiry in Unix seconds, sent reminders are dropped by DynamoDB
+}
+
+// NoteFlags represents a partial update of a note's state, nil fields are left unchanged
+type NoteFlags struct {
+	Pinned   *bool `json:"pinned,omitempty"`
+	Archived *bool `json:"archived,omitempty"`
+	Favorite *bool `json:"favorite,omitempty"`
+}
+
+// SearchResult represents a note matching a search query
+type SearchResult struct {
+	Note           Note    `json:"note"`
+	Score          float64 `json:"score"`
+	TitleHighlight string  `json:"titleHighlight"` // HTML-escaped title with matches in <mark> tags
+	Snippet        string  `json:"snippet"`        // HTML-escaped excerpt with matches in <mark> tags
+}
+
+// Share grants another user access to a note
+type Share struct {
+	NoteID           string `json:"noteId" dynamodbav:"noteId"`
+	OwnerID          string `json:"ownerId" dynamodbav:"ownerId"`
+	SharedWithUserID string `json:"sharedWithUserId" dynamodbav:"sharedWithUserId"`
+	SharedWithEmail  string `json:"sharedWithEmail" dynamodbav:"sharedWithEmail"`
+	Permission       string `json:"permission" dynamodbav:"permission"` // "read" or "edit"
+	CreatedAt        string `json:"createdAt" dynamodbav:"createdAt"`
+}
+
+// ShareRequest represents the data needed to share a note
+type ShareRequest struct {
+	Email      string `json:"email" validate:"required,format=email"`
+	Permission string `json:"permission" validate:"oneof=read edit"` // Defaults to read
+}
+
+// SharedNote is a note another user shared with the caller
+type SharedNote struct {
+	Note       Note   `json:"note"`
+	Permission string `json:"permission"`
+	SharedAt   string `json:"sharedAt"`
+}
+
+// ShareLink is a public, unauthenticated link to a note
+type ShareLink struct {
+	ID           string `json:"id" dynamodbav:"linkId"`         // SHA-256 of the token
+	Token        string `json:"token,omitempty" dynamodbav:"-"` // Only sent when the link is created
+	NoteID       string `json:"noteId" dynamodbav:"noteId"`
+	OwnerID      string `json:"ownerId" dynamodbav:"ownerId"`
+	ExpiresAt    string `json:"expiresAt,omitempty" dynamodbav:"expiresAt,omitempty"`
+	HasPassword  bool   `json:"hasPassword" dynamodbav:"hasPassword"`
+	PasswordHash string `json:"-" dynamodbav:"passwordHash,omitempty"` // Not sent to client
+	TTL          int64  `json:"-" dynamodbav:"ttl,omitempty"`          // DynamoDB expiry, unix seconds
+	CreatedAt    string `json:"createdAt" dynamodbav:"createdAt"`
+
+	FailedAttempts int   `json:"-" dynamodbav:"failedAttempts,omitempty"` // Password attempts in a row
+	LockedUntil    int64 `json:"-" dynamodbav:"lockedUntil,omitempty"`    // Unix seconds until which no password is checked
+}
+
+// ShareLinkRequest represents the options of a new share link
+type ShareLinkRequest struct {
+	ExpiresAt string `json:"expiresAt,omitempty" validate:"format=rfc3339"` // RFC3339, empty for a link that never expires
+	Password  string `json:"password,omitempty"`
+}
+
+// PublicNote is the view of a note served through a share link
+type PublicNote struct {
+	Title       string          `json:"title"`
+	Content     string          `json:"content"`
+	Format      string          `json:"format,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	Items       []ChecklistItem `json:"items,omitempty"`
+	ContentHTML string          `json:"contentHtml"` // Sanitized rendering of the content
+	UpdatedAt   string          `json:"updatedAt"`
+}
+
+// Notebook represents a folder grouping a user's notes
+type Notebook struct {
+	NotebookID string `json:"notebookId" dynamodbav:"notebookId"`
+	UserID     string `json:"userId" dynamodbav:"userId"`
+	Name       string `json:"name" dynamodbav:"name" validate:"required,maxbytes=255"`
+	CreatedAt  string `json:"createdAt" dynamodbav:"createdAt"`
+	UpdatedAt  string `json:"updatedAt" dynamodbav:"updatedAt"`
+}
+
+// Webhook is a URL a user registered to be told about note changes
+type Webhook struct {
+	WebhookID    string   `json:"webhookId" dynamodbav:"webhookId"`
+	UserID       string   `json:"userId" dynamodbav:"userId"`
+	URL          string   `json:"url" dynamodbav:"url"`
+	Events       []string `json:"events" dynamodbav:"events,stringset"`
+	Secret       string   `json:"secret,omitempty" dynamodbav:"secret"` // Only sent to the client when the webhook is created
+	Active       bool     `json:"active" dynamodbav:"active"`
+	FailureCount int      `json:"failureCount" dynamodbav:"failureCount"` // Consecutive failed delivery attempts
+	DisabledAt   string   `json:"disabledAt,omitempty" dynamodbav:"disabledAt,omitempty"`
+	CreatedAt    string   `json:"createdAt" dynamodbav:"createdAt"`
+	UpdatedAt    string   `json:"updatedAt" dynamodbav:"updatedAt"`
+}
+
+// WebhookRequest represents a request to register a webhook
+type WebhookRequest struct {
+	URL    string   `json:"url" validate:"required,max=2048"`
+	Events []string `json:"events" validate:"required"`
+}
+
+// WebhookUpdate represents a partial update of a webhook, nil fields are left unchanged.
+// Setting active to true re-enables a webhook that was disabled after repeated failures.
+type WebhookUpdate struct {
+	URL    *string  `json:"url,omitempty" validate:"max=2048"`
+	Events []string `json:"events,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook
+type WebhookDelivery struct {
+	WebhookID      string `json:"webhookId" dynamodbav:"webhookId"`
+	DeliveryID     string `json:"deliveryId" dynamodbav:"deliveryId"` // Stream event ID the delivery was created for
+	UserID         string `json:"userId" dynamodbav:"userId"`
+	Event          string `json:"event" dynamodbav:"event"`
+	NoteID         string `json:"noteId" dynamodbav:"noteId"`
+	Payload        string `json:"payload" dynamodbav:"payload"` // JSON body sent to the webhook
+	Status         string `json:"status" dynamodbav:"status"`   // pending, succeeded or failed
+	Attempts       int    `json:"attempts" dynamodbav:"attempts"`
+	NextAttemptAt  string `json:"nextAttemptAt,omitempty" dynamodbav:"nextAttemptAt,omitempty"`
+	Queue          string `json:"-" dynamodbav:"queue,omitempty"` // Only set while pending, keys the sparse PendingIndex
+	LastStatusCode int    `json:"lastStatusCode,omitempty" dynamodbav:"lastStatusCode,omitempty"`
+	LastError      string `json:"lastError,omitempty" dynamodbav:"lastError,omitempty"`
+	CreatedAt      string `json:"createdAt" dynamodbav:"createdAt"`
+	CompletedAt    string `json:"completedAt,omitempty" dynamodbav:"completedAt,omitempty"`
+	TTL            int64  `json:"-" dynamodbav:"ttl,omitempty"` // Expiry in Unix seconds, old history is dropped by DynamoDB
+}
+
+// SyncEntry is an entry of a user's change log, written for every change to
+// one of their notes. Entries are ordered by Seq, the time the change was
+// logged followed by the note ID.
+type SyncEntry struct {
+	UserID  string `json:"userId" dynamodbav:"userId"`
+	Seq     string `json:"seq" dynamodbav:"seq"`
+	NoteID  string `json:"noteId" dynamodbav:"noteId"`
+	Deleted bool   `json:"deleted" dynamodbav:"deleted"`
+	Version int64  `json:"version" dynamodbav:"version"`
+	TTL     int64  `json:"-" dynamodbav:"ttl,omitempty"` // Expiry in Unix seconds, older sync tokens need a full sync
+}
+
+// Tombstone tells a syncing client that a note was deleted
+type Tombstone struct {
+	NoteID    string `json:"noteId"`
+	DeletedAt string `json:"deletedAt"`
+}
+
+// SyncChanges is the response to a pull: the notes changed and deleted since
+// the sync token, and the token to pass on the next pull
+type SyncChanges struct {
+	Notes     []Note      `json:"notes"`
+	Deleted   []Tombstone `json:"deleted"`
+	SyncToken string      `json:"syncToken"`
+	HasMore   bool        `json:"hasMore"` // Pull again with the new token right away
+	Full      bool        `json:"full"`    // Notes holds every note, clients drop what they have first
+}
+
+// SyncChange is one change pushed by a client
+type SyncChange struct {
+	ClientID    string `json:"clientId,omitempty"`          // Echoed in the result so that clients can match it
+	Op          string `json:"op"`                          // create, update or delete
+	NoteID      string `json:"noteId,omitempty"`            // Optional for create, a client generated UUID makes retries safe
+	BaseVersion int64  `json:"baseVersion"`                 // Version the client changed, checked for update and delete
+	Note        *Note  `json:"note,omitempty" validate:"-"` // Checked per change, an invalid note rejects only its change
+}
+
+// SyncPush is a batch of changes pushed by a client
+type SyncPush struct {
+	Changes []SyncChange `json:"changes"`
+}
+
+// SyncResult is the outcome of one pushed change
+type SyncResult struct {
+	ClientID string       `json:"clientId,omitempty"`
+	Op       string       `json:"op"`
+	NoteID   string       `json:"noteId,omitempty"`
+	Status   string       `json:"status"` // applied, conflict, rejected or error
+	Version  int64        `json:"version,omitempty"`
+	Note     *Note        `json:"note,omitempty"` // Server copy of the note on a conflict, nil if it was deleted
+	Message  string       `json:"message,omitempty"`
+	Code     string       `json:"code,omitempty"`   // Error code of a rejected change
+	Errors   []FieldError `json:"errors,omitempty"` // Invalid fields of a rejected note
+}
+
+// Attachment is a file attached to a note. The metadata is recorded when the
+// upload URL is issued, the file itself is stored in S3 under Key.
+type Attachment struct {
+	NoteID       string `json:"noteId" dynamodbav:"noteId"`
+	AttachmentID string `json:"attachmentId" dynamodbav:"attachmentId"`
+	UserID       string `json:"userId" dynamodbav:"userId"` // Owner of the note, whose storage quota the file counts towards
+	Name         string `json:"name" dynamodbav:"name"`
+	Size         int64  `json:"size" dynamodbav:"size"`
+	ContentType  string `json:"contentType" dynamodbav:"contentType"`
+	Checksum     string `json:"checksum" dynamodbav:"checksum"` // Base64 SHA-256 of the file, checked by S3 on upload
+	Key          string `json:"-" dynamodbav:"key"`
+	CreatedAt    string `json:"createdAt" dynamodbav:"createdAt"`
+
+	Upload         string `json:"-" dynamodbav:"upload,omitempty"`         // Only set until the file is found, keys the sparse UploadIndex
+	UploadDeadline string `json:"-" dynamodbav:"uploadDeadline,omitempty"` // When a file not uploaded by then is given up on
+
+	Download *PresignedRequest `json:"download,omitempty" dynamodbav:"-"` // Only when listing
+}
+
+// AttachmentRequest represents a request to attach a file to a note
+type AttachmentRequest struct {
+	Name        string `json:"name" validate:"required,maxbytes=255"`
+	Size        int64  `json:"size" validate:"required,min=1"`
+	ContentType string `json:"contentType" validate:"required"`
+	Checksum    string `json:"checksum" validate:"required,format=base64"`
+}
+
+// AttachmentUpload is a new attachment and the request that uploads its file
+type AttachmentUpload struct {
+	Attachment Attachment       `json:"attachment"`
+	Upload     PresignedRequest `json:"upload"`
+}
+
+// PresignedRequest is a request a client makes directly against S3
+type PresignedRequest struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers,omitempty"` // Headers the client must send as signed
+	ExpiresAt string            `json:"expiresAt"`
+}
+
+// Connection is a live WebSocket connection of a user
+type Connection struct {
+	UserID       string `json:"userId" dynamodbav:"userId"`
+	ConnectionID string `json:"connectionId" dynamodbav:"connectionId"`
+	CallbackURL  string `json:"callbackUrl" dynamodbav:"callbackUrl"` // Management API endpoint messages are posted to
+	ConnectedAt  string `json:"connectedAt" dynamodbav:"connectedAt"`
+	TTL          int64  `json:"-" dynamodbav:"ttl,omitempty"` // Expiry in Unix seconds, drops connections whose disconnect was missed
 }
 
 // UserCredentials represents login credentials
 type UserCredentials struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,max=254"`
+	Password string `json:"password" validate:"required,maxbytes=72"`
 }
 
 // UserRegistration represents registration data
 type UserRegistration struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,max=254,format=email"`
+	Password string `json:"password" validate:"required,min=8,maxbytes=72"` // bcrypt ignores bytes past 72
 }
 
 // AuthResponse represents the response after authentication
@@ -40,9 +372,20 @@ type AuthResponse struct {
 
 // APIResponse represents a standard API response
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Success bool         `json:"success"`
+	Message string       `json:"message"`
+	Code    string       `json:"code,omitempty"` // Machine readable reason of some errors
+	Data    interface{}  `json:"data,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"` // Every invalid field of a rejected request
+
+	NextCursor string `json:"nextCursor,omitempty"` // Cursor of the next page of a paginated list, empty on the last page
+}
+
+// FieldError is a rule a request field breaks
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, such as items[2].text
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 // GetTimeNow returns the current time in ISO8601 format
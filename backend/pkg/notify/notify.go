@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reminder is a due reminder ready to be delivered
+type Reminder struct {
+	NoteID   string `json:"noteId"`
+	UserID   string `json:"userId"`
+	Email    string `json:"email"`
+	Title    string `json:"title"`
+	RemindAt string `json:"remindAt"`
+}
+
+// Notifier delivers reminders to users
+type Notifier interface {
+	Notify(ctx context.Context, reminder Reminder) error
+}
+
+// LogNotifier writes reminders to a log, for local development
+type LogNotifier struct {
+	Logger *log.Logger // Defaults to the standard logger
+}
+
+// Notify logs the reminder
+func (n LogNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	logger := n.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("reminder for %s: note %s %q was due at %s", reminder.Email, reminder.NoteID, reminder.Title, reminder.RemindAt)
+	return nil
+}
+
+// WebhookNotifier posts reminders as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // Defaults to a client with a 10 second timeout
+}
+
+// Notify posts the reminder, any status other than 2xx is an error
+func (n WebhookNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	body, err := json.Marshal(reminder)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", response.Status)
+	}
+	return nil
+}
+
+// Message is an email to send
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	SendMail(ctx context.Context, message Message) error
+}
+
+// EmailNotifier sends reminders by email through a Mailer
+type EmailNotifier struct {
+	Mailer Mailer
+	From   string
+}
+
+// Notify emails the reminder to the owner of the note
+func (n EmailNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	if reminder.Email == "" {
+		return fmt.Errorf("no email address for user %s", reminder.UserID)
+	}
+	return n.Mailer.SendMail(ctx, Message{
+		From:    n.From,
+		To:      reminder.Email,
+		Subject: "Reminder: " + reminder.Title,
+		Body:    fmt.Sprintf("This is your MiNo reminder for the note %q, due at %s.\r\n", reminder.Title, reminder.RemindAt),
+	})
+}
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string // Optional, enables PLAIN authentication
+	Password string
+}
+
+// SendMail sends a plain text email. The exchange with the server is
+// abandoned when ctx is done.
+func (m SMTPMailer) SendMail(ctx context.Context, message Message) error {
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return err
+	}
+
+	// Header values must not contain line breaks
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(message.Subject)
+	body := "From: " + message.From + "\r\n" +
+		"To: " + message.To + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + message.Body
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock reads and writes as soon as ctx is done
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if err := m.send(conn, host, message, []byte(body)); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("send mail: %w", ctx.Err())
+		}
+		return err
+	}
+	return nil
+}
+
+// send runs the SMTP exchange on an open connection, as smtp.SendMail does
+func (m SMTPMailer) send(conn net.Conn, host string, message Message, body []byte) error {
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(message.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// FromEnv builds the notifier selected by the NOTIFIER environment variable:
+// log (the default), webhook (REMINDER_WEBHOOK_URL) or email (SMTP_ADDR,
+// SMTP_USERNAME, SMTP_PASSWORD and MAIL_FROM).
+func FromEnv() (Notifier, error) {
+	switch notifier := os.Getenv("NOTIFIER"); notifier {
+	case "", "log":
+		return LogNotifier{}, nil
+	case "webhook":
+		url := os.Getenv("REMINDER_WEBHOOK_URL")
+		if url == "" {
+			return nil, fmt.Errorf("REMINDER_WEBHOOK_URL is required for the webhook notifier")
+		}
+		return WebhookNotifier{URL: url}, nil
+	case "email":
+		addr, from := os.Getenv("SMTP_ADDR"), os.Getenv("MAIL_FROM")
+		if addr == "" || from == "" {
+			return nil, fmt.Errorf("SMTP_ADDR and MAIL_FROM are required for the email notifier")
+		}
+		return EmailNotifier{
+			Mailer: SMTPMailer{
+				Addr:     addr,
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+			},
+			From: from,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", notifier)
+	}
+}
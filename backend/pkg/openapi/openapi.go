@@ -0,0 +1,374 @@
+// Package openapi builds an OpenAPI 3.1 document of the REST API from its
+// operations and the Go types of their bodies.
+//
+// Schemas are derived by reflection: properties from the json tags, and
+// constraints from the validate tags the handlers check requests with, so
+// that the document says what the API actually accepts. Named struct types
+// become components referenced by name, successful responses wrap their data
+// in models.APIResponse unless an operation answers with the bare value.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Version is the OpenAPI version of the documents built
+const Version = "3.1.0"
+
+// Operation describes a route of the API
+type Operation struct {
+	Method      string
+	Path        string // Resource template, such as /notes/{noteId}
+	ID          string // operationId, unique in the document
+	Summary     string
+	Public      bool        // No bearer token needed
+	Query       []Parameter // Path parameters are taken from the template
+	Request     interface{} // Value of the body type, nil without a body
+	RequestType string      // Content type of the body, application/json when empty
+	Response    interface{} // Value of the data type of a successful response, nil without data
+	Bare        bool        // The response is the data itself, not wrapped in an APIResponse
+	HTML        bool        // The response may also be an HTML page
+	Status      int         // Of a successful response
+}
+
+// Parameter is a query string parameter
+type Parameter struct {
+	Name        string
+	Type        string // JSON Schema type, string when empty
+	Enum        []string
+	Description string
+}
+
+// Info is the metadata of a document
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                      `json:"openapi"`
+	Info       Info                        `json:"info"`
+	Paths      map[string]map[string]*path `json:"paths"`
+	Components components                  `json:"components"`
+	Security   []map[string][]string       `json:"security"`
+}
+
+// Schema is a JSON Schema, the subset the documents use
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+type path struct {
+	OperationID string                 `json:"operationId"`
+	Summary     string                 `json:"summary,omitempty"`
+	Parameters  []parameter            `json:"parameters,omitempty"`
+	RequestBody *requestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]*response   `json:"responses"`
+	Security    *[]map[string][]string `json:"security,omitempty"` // Empty for public operations
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	Responses       map[string]*response      `json:"responses"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat"`
+}
+
+// New builds the document of a list of operations. It panics on two types
+// of the same name, which would share a component.
+func New(info Info, operations []Operation) *Document {
+	g := &generator{
+		schemas: make(map[string]*Schema),
+		types:   make(map[string]reflect.Type),
+	}
+	envelope := g.schema(reflect.TypeOf(models.APIResponse{}))
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]map[string]*path),
+		Components: components{
+			Schemas: g.schemas,
+			Responses: map[string]*response{
+				"Error": {
+					Description: "Error, with a code and the invalid fields for some",
+					Content:     map[string]mediaType{"application/json": {Schema: envelope}},
+				},
+			},
+			SecuritySchemes: map[string]securityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Security: []map[string][]string{{"bearerAuth": {}}},
+	}
+
+	for _, op := range operations {
+		p := &path{
+			OperationID: op.ID,
+			Summary:     op.Summary,
+			Responses: map[string]*response{
+				"default": {Ref: "#/components/responses/Error"},
+			},
+		}
+		if op.Public {
+			p.Security = &[]map[string][]string{}
+		}
+
+		for _, segment := range strings.Split(op.Path, "/") {
+			if name, ok := strings.CutPrefix(segment, "{"); ok {
+				p.Parameters = append(p.Parameters, parameter{
+					Name:     strings.TrimSuffix(name, "}"),
+					In:       "path",
+					Required: true,
+					Schema:   &Schema{Type: "string"},
+				})
+			}
+		}
+		for _, query := range op.Query {
+			schema := &Schema{Type: query.Type, Enum: query.Enum}
+			if schema.Type == "" {
+				schema.Type = "string"
+			}
+			p.Parameters = append(p.Parameters, parameter{
+				Name:        query.Name,
+				In:          "query",
+				Description: query.Description,
+				Schema:      schema,
+			})
+		}
+
+		if op.Request != nil {
+			contentType := op.RequestType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			p.RequestBody = &requestBody{
+				Required: true,
+				Content:  map[string]mediaType{contentType: {Schema: g.schema(reflect.TypeOf(op.Request))}},
+			}
+		}
+
+		var schema *Schema
+		switch {
+		case op.Bare:
+			schema = g.schema(reflect.TypeOf(op.Response))
+		case op.Response == nil:
+			schema = envelope
+		default:
+			schema = &Schema{AllOf: []*Schema{envelope, {
+				Type:       "object",
+				Properties: map[string]*Schema{"data": g.schema(reflect.TypeOf(op.Response))},
+			}}}
+		}
+		success := &response{
+			Description: http.StatusText(op.Status),
+			Content:     map[string]mediaType{"application/json": {Schema: schema}},
+		}
+		if op.HTML {
+			success.Content["text/html"] = mediaType{Schema: &Schema{Type: "string"}}
+		}
+		p.Responses[strconv.Itoa(op.Status)] = success
+
+		if doc.Paths[op.Path] == nil {
+			doc.Paths[op.Path] = make(map[string]*path)
+		}
+		doc.Paths[op.Path][strings.ToLower(op.Method)] = p
+	}
+
+	return doc
+}
+
+// generator derives schemas, collecting those of named structs as components
+type generator struct {
+	schemas map[string]*Schema
+	types   map[string]reflect.Type
+}
+
+// schema returns the schema of a type, a reference for a named struct
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if other, ok := g.types[t.Name()]; ok {
+			if other != t {
+				panic(fmt.Sprintf("openapi: %s and %s are both named %s", other.PkgPath(), t.PkgPath(), t.Name()))
+			}
+		} else {
+			g.types[t.Name()] = t
+			g.schemas[t.Name()] = g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &Schema{}
+}
+
+// object returns the schema of a struct's fields
+func (g *generator) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+
+		// Fields of embedded structs are promoted to the parent
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := g.object(field.Type)
+			for property, fieldSchema := range embedded.Properties {
+				schema.Properties[property] = fieldSchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := g.schema(field.Type)
+		if constrain(fieldSchema, field) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = fieldSchema
+	}
+	return schema
+}
+
+// constrain adds the rules of a field's validate tag to its schema and
+// reports whether the field is required
+func constrain(schema *Schema, field reflect.StructField) bool {
+	tag := field.Tag.Get("validate")
+	if tag == "" || tag == "-" || schema.Ref != "" {
+		return tag == "required"
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		n, _ := strconv.Atoi(param)
+		switch name {
+		case "required":
+			required = true
+			if schema.Type == "string" {
+				schema.MinLength = intPtr(1)
+			}
+		case "min", "max":
+			switch schema.Type {
+			case "string":
+				if name == "min" {
+					schema.MinLength = intPtr(n)
+				} else {
+					schema.MaxLength = intPtr(n)
+				}
+			case "array":
+				if name == "min" {
+					schema.MinItems = intPtr(n)
+				} else {
+					schema.MaxItems = intPtr(n)
+				}
+			case "integer", "number":
+				value := float64(n)
+				if name == "min" {
+					schema.Minimum = &value
+				} else {
+					schema.Maximum = &value
+				}
+			}
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "format":
+			switch param {
+			case "rfc3339":
+				schema.Format = "date-time"
+			case "base64":
+				schema.ContentEncoding = "base64"
+			default:
+				schema.Format = param
+			}
+		case "maxbytes":
+			schema.Description = fmt.Sprintf("At most %d bytes of UTF-8", n)
+		}
+	}
+	return required
+}
+
+func intPtr(n int) *int {
+	return &n
+}
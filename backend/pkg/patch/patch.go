@@ -0,0 +1,243 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Media types accepted for patch documents
+const (
+	MergePatchType = "application/merge-patch+json" // RFC 7396
+	JSONPatchType  = "application/json-patch+json"  // RFC 6902
+)
+
+// Kind is the JSON type a patchable field accepts
+type Kind int
+
+// Supported field kinds
+const (
+	String Kind = iota
+	Bool
+)
+
+// Field describes a patchable top-level field
+type Field struct {
+	Kind      Kind
+	Removable bool // Whether the field may be removed, otherwise it can only be replaced
+}
+
+// Patch is a validated set of changes to apply to a document
+type Patch struct {
+	Set    map[string]interface{} // Fields to replace, by JSON name
+	Remove []string               // Fields to remove, by JSON name
+	Tests  map[string]interface{} // Expected current values, from JSON Patch test operations
+}
+
+// Empty reports whether the patch changes nothing
+func (p Patch) Empty() bool {
+	return len(p.Set) == 0 && len(p.Remove) == 0
+}
+
+// operation is a single RFC 6902 operation
+type operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+	From  string          `json:"from"`
+}
+
+// Parse decodes a patch document according to its content type. JSON Merge
+// Patch is assumed when the content type is empty or plain JSON.
+func Parse(contentType string, body []byte, fields map[string]Field) (Patch, error) {
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+
+	switch strings.ToLower(mediaType) {
+	case JSONPatchType:
+		return ParseJSONPatch(body, fields)
+	case MergePatchType, "application/json", "":
+		return ParseMergePatch(body, fields)
+	default:
+		return Patch{}, fmt.Errorf("unsupported patch content type %q", mediaType)
+	}
+}
+
+// ParseMergePatch decodes an RFC 7396 JSON Merge Patch. Null members remove
+// the field, other members replace it.
+func ParseMergePatch(body []byte, fields map[string]Field) (Patch, error) {
+	// A patch other than an object replaces the whole document, as null
+	// would, which no caller allows
+	var document map[string]json.RawMessage
+	if isNull(body) {
+		return Patch{}, fmt.Errorf("merge patch must be a JSON object, not null")
+	}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return Patch{}, fmt.Errorf("merge patch must be a JSON object: %w", err)
+	}
+
+	p := newPatch()
+	for name, raw := range document {
+		field, ok := fields[name]
+		if !ok {
+			return Patch{}, fmt.Errorf("field %q cannot be patched", name)
+		}
+
+		if isNull(raw) {
+			if err := p.remove(name, field); err != nil {
+				return Patch{}, err
+			}
+			continue
+		}
+
+		value, err := decodeValue(name, field, raw)
+		if err != nil {
+			return Patch{}, err
+		}
+		p.Set[name] = value
+	}
+
+	return p, nil
+}
+
+// ParseJSONPatch decodes an RFC 6902 JSON Patch. Only add, replace, remove
+// and test operations on top-level fields are supported.
+func ParseJSONPatch(body []byte, fields map[string]Field) (Patch, error) {
+	var operations []operation
+	if err := json.Unmarshal(body, &operations); err != nil {
+		return Patch{}, fmt.Errorf("JSON patch must be an array of operations: %w", err)
+	}
+
+	p := newPatch()
+	for i, op := range operations {
+		name, err := fieldName(op.Path)
+		if err != nil {
+			return Patch{}, fmt.Errorf("operation %d: %w", i, err)
+		}
+		field, ok := fields[name]
+		if !ok {
+			return Patch{}, fmt.Errorf("operation %d: field %q cannot be patched", i, name)
+		}
+
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return Patch{}, fmt.Errorf("operation %d: %s requires a value", i, op.Op)
+			}
+			value, err := decodeValue(name, field, op.Value)
+			if err != nil {
+				return Patch{}, fmt.Errorf("operation %d: %w", i, err)
+			}
+			p.Set[name] = value
+			p.Remove = without(p.Remove, name)
+		case "remove":
+			if err := p.remove(name, field); err != nil {
+				return Patch{}, fmt.Errorf("operation %d: %w", i, err)
+			}
+		case "test":
+			if op.Value == nil {
+				return Patch{}, fmt.Errorf("operation %d: test requires a value", i)
+			}
+			value, err := decodeValue(name, field, op.Value)
+			if err != nil {
+				return Patch{}, fmt.Errorf("operation %d: %w", i, err)
+			}
+			if _, changed := p.Set[name]; changed || contains(p.Remove, name) {
+				return Patch{}, fmt.Errorf("operation %d: test of %q after it was changed is not supported", i, name)
+			}
+			p.Tests[name] = value
+		case "move", "copy":
+			return Patch{}, fmt.Errorf("operation %d: %s is not supported", i, op.Op)
+		default:
+			return Patch{}, fmt.Errorf("operation %d: unknown op %q", i, op.Op)
+		}
+	}
+
+	return p, nil
+}
+
+// newPatch returns an empty patch
+func newPatch() Patch {
+	return Patch{
+		Set:   make(map[string]interface{}),
+		Tests: make(map[string]interface{}),
+	}
+}
+
+// remove marks a field for removal
+func (p *Patch) remove(name string, field Field) error {
+	if !field.Removable {
+		return fmt.Errorf("field %q cannot be removed", name)
+	}
+	delete(p.Set, name)
+	if !contains(p.Remove, name) {
+		p.Remove = append(p.Remove, name)
+	}
+	return nil
+}
+
+// fieldName turns a JSON Pointer to a top-level member into its name
+func fieldName(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("invalid path %q", pointer)
+	}
+	name := pointer[1:]
+	if strings.Contains(name, "/") {
+		return "", fmt.Errorf("path %q: only top-level fields can be patched", pointer)
+	}
+	// Unescape per RFC 6901, where ~ only starts ~0 and ~1
+	for i := 0; i < len(name); i++ {
+		if name[i] == '~' && (i+1 == len(name) || name[i+1] != '0' && name[i+1] != '1') {
+			return "", fmt.Errorf("path %q: invalid escape", pointer)
+		}
+	}
+	name = strings.ReplaceAll(name, "~1", "/")
+	name = strings.ReplaceAll(name, "~0", "~")
+	return name, nil
+}
+
+// decodeValue decodes a raw JSON value as the kind a field accepts
+func decodeValue(name string, field Field, raw json.RawMessage) (interface{}, error) {
+	switch field.Kind {
+	case String:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("field %q must be a string", name)
+		}
+		return value, nil
+	case Bool:
+		var value bool
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("field %q must be a boolean", name)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("field %q has an unsupported kind", name)
+	}
+}
+
+// isNull reports whether a raw JSON value is null
+func isNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// without returns list with value removed
+func without(list []string, value string) []string {
+	kept := list[:0]
+	for _, item := range list {
+		if item != value {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,161 @@
+package patch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var fields = map[string]Field{
+	"title":    {Kind: String},
+	"format":   {Kind: String, Removable: true},
+	"pinned":   {Kind: Bool},
+	"a/b":      {Kind: String},
+	"m~n":      {Kind: String},
+	"~1":       {Kind: String},
+	"archived": {Kind: Bool},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        Patch
+		err         string
+	}{
+		{"application/merge-patch+json", `{"title":"a"}`, Patch{Set: map[string]interface{}{"title": "a"}}, ""},
+		{"application/json; charset=utf-8", `{"title":"a"}`, Patch{Set: map[string]interface{}{"title": "a"}}, ""},
+		{"", `{"title":"a"}`, Patch{Set: map[string]interface{}{"title": "a"}}, ""},
+		{"Application/JSON-Patch+JSON", `[{"op":"replace","path":"/title","value":"a"}]`, Patch{Set: map[string]interface{}{"title": "a"}}, ""},
+		{"text/plain", `{"title":"a"}`, Patch{}, "unsupported patch content type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			got, err := Parse(tt.contentType, []byte(tt.body), fields)
+			check(t, got, err, tt.want, tt.err)
+		})
+	}
+}
+
+// RFC 7396 section 3 and appendix A, on the top-level fields a note has
+func TestParseMergePatch(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Patch
+		err  string
+	}{
+		{"replace and remove", `{"title":"Hello!","format":null}`,
+			Patch{Set: map[string]interface{}{"title": "Hello!"}, Remove: []string{"format"}}, ""},
+		{"replace a boolean", `{"pinned":true,"archived":false}`,
+			Patch{Set: map[string]interface{}{"pinned": true, "archived": false}}, ""},
+		{"empty object changes nothing", `{}`, Patch{}, ""},
+		{"member names are not pointers", `{"a/b":"c"}`,
+			Patch{Set: map[string]interface{}{"a/b": "c"}}, ""},
+		{"unknown field", `{"title":"a","owner":"u2"}`, Patch{}, `field "owner" cannot be patched`},
+		{"null removes only removable fields", `{"title":null}`, Patch{}, `field "title" cannot be removed`},
+		{"wrong type", `{"pinned":"yes"}`, Patch{}, `field "pinned" must be a boolean`},
+		{"nested objects are not merged", `{"title":{"text":"a"}}`, Patch{}, `field "title" must be a string`},
+		{"array replaces the document", `["a"]`, Patch{}, "must be a JSON object"},
+		{"null replaces the document", `null`, Patch{}, "must be a JSON object"},
+		{"invalid JSON", `{"title":`, Patch{}, "must be a JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMergePatch([]byte(tt.body), fields)
+			check(t, got, err, tt.want, tt.err)
+		})
+	}
+}
+
+// RFC 6902 appendix A, on the top-level fields a note has
+func TestParseJSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Patch
+		err  string
+	}{
+		{"A.1 add", `[{"op":"add","path":"/title","value":"qux"}]`,
+			Patch{Set: map[string]interface{}{"title": "qux"}}, ""},
+		{"A.3 remove", `[{"op":"remove","path":"/format"}]`,
+			Patch{Remove: []string{"format"}}, ""},
+		{"A.5 replace", `[{"op":"replace","path":"/pinned","value":true}]`,
+			Patch{Set: map[string]interface{}{"pinned": true}}, ""},
+		{"A.8 test then replace", `[{"op":"test","path":"/title","value":"foo"},{"op":"replace","path":"/title","value":"bar"}]`,
+			Patch{Set: map[string]interface{}{"title": "bar"}, Tests: map[string]interface{}{"title": "foo"}}, ""},
+		{"A.11 unrecognized members are ignored", `[{"op":"add","path":"/title","value":"qux","xyz":123}]`,
+			Patch{Set: map[string]interface{}{"title": "qux"}}, ""},
+		{"A.14 ~ escape ordering", `[{"op":"replace","path":"/~01","value":"x"}]`,
+			Patch{Set: map[string]interface{}{"~1": "x"}}, ""},
+		{"escaped slash and tilde", `[{"op":"add","path":"/a~1b","value":"x"},{"op":"add","path":"/m~0n","value":"y"}]`,
+			Patch{Set: map[string]interface{}{"a/b": "x", "m~n": "y"}}, ""},
+		{"add after remove keeps the value", `[{"op":"remove","path":"/format"},{"op":"add","path":"/format","value":"markdown"}]`,
+			Patch{Set: map[string]interface{}{"format": "markdown"}}, ""},
+		{"remove after add removes", `[{"op":"add","path":"/format","value":"markdown"},{"op":"remove","path":"/format"}]`,
+			Patch{Remove: []string{"format"}}, ""},
+		{"empty array changes nothing", `[]`, Patch{}, ""},
+
+		{"A.12 nested target", `[{"op":"add","path":"/title/bat","value":"qux"}]`, Patch{}, "only top-level fields"},
+		{"A.13 missing value", `[{"op":"add","path":"/title"}]`, Patch{}, "add requires a value"},
+		{"A.15 string is not a number", `[{"op":"test","path":"/title","value":10}]`, Patch{}, `field "title" must be a string`},
+		{"unknown field", `[{"op":"replace","path":"/owner","value":"u2"}]`, Patch{}, `field "owner" cannot be patched`},
+		{"remove of a required field", `[{"op":"remove","path":"/title"}]`, Patch{}, `field "title" cannot be removed`},
+		{"pointer without a slash", `[{"op":"add","path":"title","value":"a"}]`, Patch{}, "invalid path"},
+		{"whole document", `[{"op":"replace","path":"","value":{}}]`, Patch{}, "invalid path"},
+		{"invalid escape", `[{"op":"add","path":"/m~2n","value":"a"}]`, Patch{}, "invalid escape"},
+		{"trailing tilde", `[{"op":"add","path":"/m~","value":"a"}]`, Patch{}, "invalid escape"},
+		{"move", `[{"op":"move","from":"/title","path":"/format"}]`, Patch{}, "move is not supported"},
+		{"copy", `[{"op":"copy","from":"/title","path":"/format"}]`, Patch{}, "copy is not supported"},
+		{"unknown op", `[{"op":"merge","path":"/title","value":"a"}]`, Patch{}, `unknown op "merge"`},
+		{"missing op", `[{"path":"/title","value":"a"}]`, Patch{}, `unknown op ""`},
+		{"test without a value", `[{"op":"test","path":"/title"}]`, Patch{}, "test requires a value"},
+		{"test after a change", `[{"op":"replace","path":"/title","value":"a"},{"op":"test","path":"/title","value":"a"}]`, Patch{}, "after it was changed"},
+		{"error names the operation", `[{"op":"add","path":"/title","value":"a"},{"op":"add","path":"/pinned","value":1}]`, Patch{}, "operation 1:"},
+		{"object instead of array", `{"op":"add","path":"/title","value":"a"}`, Patch{}, "must be an array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJSONPatch([]byte(tt.body), fields)
+			check(t, got, err, tt.want, tt.err)
+		})
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	p, err := ParseJSONPatch([]byte(`[{"op":"test","path":"/title","value":"a"}]`), fields)
+	if err != nil || !p.Empty() {
+		t.Errorf("a patch of tests only is not empty: %+v, %v", p, err)
+	}
+	p, err = ParseMergePatch([]byte(`{"format":null}`), fields)
+	if err != nil || p.Empty() {
+		t.Errorf("a removal is empty: %+v, %v", p, err)
+	}
+}
+
+// check compares a parsed patch with the expected one, treating nil and
+// empty collections alike, or its error with the expected message
+func check(t *testing.T, got Patch, err error, want Patch, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("got error %v, want one containing %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if len(got.Set) != len(want.Set) || len(got.Set) > 0 && !reflect.DeepEqual(got.Set, want.Set) {
+		t.Errorf("got Set %v, want %v", got.Set, want.Set)
+	}
+	if len(got.Remove) != len(want.Remove) || len(got.Remove) > 0 && !reflect.DeepEqual(got.Remove, want.Remove) {
+		t.Errorf("got Remove %v, want %v", got.Remove, want.Remove)
+	}
+	if len(got.Tests) != len(want.Tests) || len(got.Tests) > 0 && !reflect.DeepEqual(got.Tests, want.Tests) {
+		t.Errorf("got Tests %v, want %v", got.Tests, want.Tests)
+	}
+}
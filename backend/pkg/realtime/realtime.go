@@ -0,0 +1,141 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewaymanagementapi"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewaymanagementapi/types"
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// ConnectionLifetime is how long API Gateway keeps a WebSocket connection open
+const ConnectionLifetime = 2 * time.Hour
+
+// Protocol is the WebSocket subprotocol of the API. Browsers cannot set
+// headers on a WebSocket, so they offer their token as a second subprotocol:
+// new WebSocket(url, ["mino", token]).
+const Protocol = "mino"
+
+// ErrGone is returned when a connection was closed without a disconnect
+var ErrGone = errors.New("connection is gone")
+
+// Message is a JSON frame sent to clients. Note changes only name the note
+// and its version, clients fetch the note when they do not have that version,
+// so that a frame stays far below the 128 KB limit of API Gateway.
+type Message struct {
+	Type    string `json:"type"` // note.created, note.updated, note.deleted, pong or error
+	NoteID  string `json:"noteId,omitempty"`
+	Version int64  `json:"version,omitempty"` // Version of the change, the last one for note.deleted
+	Message string `json:"message,omitempty"`
+}
+
+// Client posts messages to connections through the API Gateway management API
+type Client struct {
+	api *apigatewaymanagementapi.Client
+}
+
+// NewClient returns a client with the credentials and region of the default
+// chain: the Lambda role in AWS, the environment on LocalStack
+func NewClient() *Client {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		log.Fatalf("Unable to load SDK config: %v", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &Client{api: apigatewaymanagementapi.NewFromConfig(cfg)}
+}
+
+// ConnectToken returns the token a $connect request authenticates with, from
+// the Authorization header or offered as a subprotocol after Protocol. The
+// protocol returned is the one the response must select, empty when the
+// client offered none.
+func ConnectToken(request events.APIGatewayWebsocketProxyRequest) (token string, protocol string) {
+	var offered []string
+	for _, value := range strings.Split(request.Headers["Sec-WebSocket-Protocol"], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			offered = append(offered, value)
+		}
+	}
+	if len(offered) > 0 && offered[0] == Protocol {
+		protocol = Protocol
+		if len(offered) > 1 {
+			token = offered[1]
+		}
+	}
+
+	if authorization := request.Headers["Authorization"]; authorization != "" {
+		token = authorization
+	}
+	return token, protocol
+}
+
+// CallbackURL returns the management API endpoint of the API a request came
+// through. WEBSOCKET_CALLBACK_URL overrides it, for LocalStack.
+func CallbackURL(request events.APIGatewayWebsocketProxyRequest) string {
+	if callbackURL := os.Getenv("WEBSOCKET_CALLBACK_URL"); callbackURL != "" {
+		return strings.TrimSuffix(callbackURL, "/")
+	}
+	return "https://" + request.RequestContext.DomainName + "/" + request.RequestContext.Stage
+}
+
+// Post sends data to a connection. It fails with ErrGone when the client is
+// no longer connected.
+func (c *Client) Post(ctx context.Context, callbackURL string, connectionID string, data []byte) error {
+	_, err := c.api.PostToConnection(ctx, &apigatewaymanagementapi.PostToConnectionInput{
+		ConnectionId: aws.String(connectionID),
+		Data:         data,
+	}, func(o *apigatewaymanagementapi.Options) {
+		o.BaseEndpoint = aws.String(callbackURL)
+	})
+	var gone *types.GoneException
+	if errors.As(err, &gone) {
+		return ErrGone
+	}
+	return err
+}
+
+// Send posts a message to one connection
+func (c *Client) Send(ctx context.Context, connection models.Connection, message Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return c.Post(ctx, connection.CallbackURL, connection.ConnectionID, data)
+}
+
+// Broadcast sends a message to every live connection of a user. Connections
+// that are gone are removed, other failures are logged so that one broken
+// connection does not hold back the others.
+func (c *Client) Broadcast(ctx context.Context, userID string, message Message) error {
+	connections, err := db.GetConnectionsByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, connection := range connections {
+		err := c.Send(ctx, connection, message)
+		if errors.Is(err, ErrGone) {
+			if err := db.DeleteConnection(connection.UserID, connection.ConnectionID); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			logging.FromContext(ctx).Warn("realtime message not sent", "connectionId", connection.ConnectionID, "userId", userID, "error", err)
+		}
+	}
+	return nil
+}
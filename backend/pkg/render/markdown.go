@@ -0,0 +1,128 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/omidiyanto/mino/pkg/models"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// Content formats of a note
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// ValidFormat reports whether format is a supported content format. An empty
+// format is valid and means plain text, the format of notes created before
+// formats existed.
+func ValidFormat(format string) bool {
+	switch format {
+	case "", FormatPlain, FormatMarkdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// markdown converts GitHub Flavored Markdown (tables, task lists,
+// strikethrough and autolinks). Single newlines become line breaks, which is
+// what people typing notes expect. Raw HTML is dropped by goldmark already,
+// the sanitizer below is the actual safety net.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(goldmarkhtml.WithHardWraps()),
+)
+
+// policy is the allowlist every rendered note passes through. It starts from
+// the user generated content policy and adds the markup GFM produces.
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	// Task list checkboxes
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	// Fenced code block languages, for client-side highlighting
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	return p
+}
+
+// ErrEncrypted is returned for end-to-end encrypted notes, only clients
+// holding the key can render them
+var ErrEncrypted = errors.New("encrypted notes cannot be rendered")
+
+// NoteHTML renders the body of a note as sanitized HTML, either its content
+// or its checklist items as a task list
+func NoteHTML(note models.Note) (string, error) {
+	if note.Encrypted != nil {
+		return "", ErrEncrypted
+	}
+	if len(note.Items) == 0 {
+		return ContentHTML(note.Format, note.Content)
+	}
+
+	var b strings.Builder
+	b.WriteString("<ul>\n")
+	for _, item := range note.Items {
+		b.WriteString(`<li><input type="checkbox" disabled=""`)
+		if item.Checked {
+			b.WriteString(` checked=""`)
+		}
+		b.WriteString("> ")
+		b.WriteString(html.EscapeString(item.Text))
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>\n")
+	return b.String(), nil
+}
+
+// ContentHTML renders note content of the given format as sanitized HTML
+func ContentHTML(format string, content string) (string, error) {
+	if format != FormatMarkdown {
+		return plainHTML(content), nil
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(content), &buf); err != nil {
+		return "", err
+	}
+	return Sanitize(buf.String()), nil
+}
+
+// Sanitize strips every element and attribute the allowlist does not permit
+func Sanitize(unsafe string) string {
+	return policy.Sanitize(unsafe)
+}
+
+// plainHTML escapes plain text and keeps its paragraphs and line breaks
+func plainHTML(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, paragraph := range strings.Split(content, "\n\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		lines := strings.Split(paragraph, "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(line)
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.Join(lines, "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,178 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/omidiyanto/mino/pkg/models"
+	"golang.org/x/net/html"
+)
+
+// unsafeElements are elements no rendering may contain
+var unsafeElements = map[string]bool{"script": true, "iframe": true, "object": true, "embed": true, "style": true, "svg": true, "form": true, "link": true, "meta": true, "base": true}
+
+// unsafeSchemes are URL schemes no link or image may use
+var unsafeSchemes = []string{"javascript:", "vbscript:", "data:"}
+
+// assertSafe parses rendered HTML the way a browser would and fails on any
+// element, attribute or URL that could run script
+func assertSafe(t *testing.T, rendered string) {
+	t.Helper()
+	tokenizer := html.NewTokenizer(strings.NewReader(rendered))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if unsafeElements[token.Data] {
+				t.Errorf("<%s> survived in %q", token.Data, rendered)
+			}
+			for _, attr := range token.Attr {
+				if strings.HasPrefix(attr.Key, "on") || attr.Key == "style" || attr.Key == "srcdoc" {
+					t.Errorf("%s attribute survived in %q", attr.Key, rendered)
+				}
+				if attr.Key != "href" && attr.Key != "src" {
+					continue
+				}
+				value := strings.ToLower(strings.TrimSpace(attr.Val))
+				for _, scheme := range unsafeSchemes {
+					if strings.HasPrefix(value, scheme) {
+						t.Errorf("%s URL survived in %q", scheme, rendered)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestContentHTMLStripsUnsafeMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"script element", "Hello <script>alert(1)</script>"},
+		{"script block", "<script>\nalert(1)\n</script>"},
+		{"event handler", `<img src="x" onerror="alert(1)">`},
+		{"event handler on a block", `<div onclick="alert(1)">click</div>`},
+		{"javascript link", "[click](javascript:alert(1))"},
+		{"javascript link with entities", "[click](&#106;avascript:alert(1))"},
+		{"javascript link in mixed case", "[click](JaVaScRiPt:alert(1))"},
+		{"javascript autolink", "<javascript:alert(1)>"},
+		{"vbscript link", "[click](vbscript:msgbox(1))"},
+		{"data link", "[click](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)"},
+		{"data image", "![pixel](data:image/svg+xml;base64,PHN2ZyBvbmxvYWQ9YWxlcnQoMSk+)"},
+		{"reference link", "[click][x]\n\n[x]: javascript:alert(1)"},
+		{"raw iframe", `<iframe src="https://evil.example"></iframe>`},
+		{"raw iframe srcdoc", `<iframe srcdoc="<script>alert(1)</script>"></iframe>`},
+		{"raw object", `<object data="https://evil.example/x.swf"></object>`},
+		{"raw svg", `<svg onload="alert(1)"><circle r="1"/></svg>`},
+		{"raw style", "<style>body { display: none }</style>"},
+		{"raw form", `<form action="https://evil.example"><input name="password"></form>`},
+		{"inline html in a table", "| a |\n|---|\n| <img src=x onerror=alert(1)> |"},
+		{"inline html in a task list", "- [ ] <a href=\"javascript:alert(1)\">x</a>"},
+		{"html in a fenced code block stays text", "```html\n<script>alert(1)</script>\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := ContentHTML(FormatMarkdown, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSafe(t, rendered)
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	// The sanitizer must hold on its own, whatever the Markdown renderer lets through
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"script", `<p>a<script>alert(1)</script></p>`, `<p>a</p>`},
+		{"event handler", `<img src="https://example.com/a.png" onerror="alert(1)">`, `<img src="https://example.com/a.png">`},
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, `x`},
+		{"data href", `<a href="data:text/html,x">x</a>`, `x`},
+		{"data image", `<img src="data:image/png;base64,iVBORw0KGgo=">`, ``},
+		{"style attribute", `<p style="background:url(javascript:alert(1))">x</p>`, `<p>x</p>`},
+		{"iframe", `<iframe src="https://example.com"></iframe>`, ``},
+		{"checkbox kept", `<input type="checkbox" checked="" disabled="">`, `<input type="checkbox" checked="" disabled="">`},
+		{"other inputs dropped", `<input type="text" value="x">`, ``},
+		{"code language kept", `<code class="language-go">x</code>`, `<code class="language-go">x</code>`},
+		{"other classes dropped", `<code class="x" onclick="alert(1)">x</code>`, `<code>x</code>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.input); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentHTMLKeepsMarkdown(t *testing.T) {
+	content := "# Title\n\n**bold** ~~gone~~\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n- [x] done\n- [ ] todo\n\n[site](https://example.com)\n\n```go\nfmt.Println(\"<hi>\")\n```"
+	rendered, err := ContentHTML(FormatMarkdown, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"<h1>Title</h1>",
+		"<strong>bold</strong>",
+		"<del>gone</del>",
+		"<td>1</td>",
+		`<input checked="" disabled="" type="checkbox">`,
+		`<input disabled="" type="checkbox">`,
+		`<a href="https://example.com" rel="nofollow noopener" target="_blank">site</a>`,
+		`<code class="language-go">`,
+		"&lt;hi&gt;",
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("%s missing from %s", want, rendered)
+		}
+	}
+}
+
+func TestPlainContentIsEscaped(t *testing.T) {
+	rendered, err := ContentHTML(FormatPlain, "<script>alert(1)</script>\nline two\n\nnext")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<p>&lt;script&gt;alert(1)&lt;/script&gt;<br>\nline two</p>\n<p>next</p>\n"
+	if rendered != want {
+		t.Errorf("got %q, want %q", rendered, want)
+	}
+}
+
+func TestNoteHTMLEscapesChecklistItems(t *testing.T) {
+	rendered, err := NoteHTML(models.Note{Type: "checklist", Items: []models.ChecklistItem{
+		{Text: `<img src=x onerror=alert(1)>`, Checked: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSafe(t, rendered)
+	if !strings.Contains(rendered, "&lt;img") {
+		t.Errorf("item text not escaped in %q", rendered)
+	}
+}
+
+func TestPublicNotePageSanitizesAgain(t *testing.T) {
+	page, err := PublicNotePage(models.PublicNote{
+		Title:       `</title><script>alert(1)</script>`,
+		ContentHTML: `<p onclick="alert(1)">x</p><script>alert(2)</script>`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := page[strings.Index(page, "<body>"):]
+	assertSafe(t, body)
+	if strings.Contains(page, "<script>") {
+		t.Errorf("script in page %s", page)
+	}
+}
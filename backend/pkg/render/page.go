@@ -0,0 +1,61 @@
+package render
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// publicNoteTemplate is the standalone page served for public share links.
+// html/template escapes every field, the content is inserted as the HTML
+// rendered by NoteHTML, which went through the sanitizer.
+var publicNoteTemplate = template.Must(template.New("note").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}} - MiNo</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 720px; margin: 2rem auto; padding: 0 1rem; color: #1f2937; }
+h1 { margin-bottom: 0.25rem; }
+.meta { color: #6b7280; font-size: 0.875rem; margin-bottom: 1.5rem; }
+.content { line-height: 1.6; }
+.content pre { background: #f3f4f6; padding: 0.75rem; overflow-x: auto; }
+.content table { border-collapse: collapse; }
+.content th, .content td { border: 1px solid #d1d5db; padding: 0.25rem 0.5rem; }
+.content blockquote { border-left: 3px solid #d1d5db; margin-left: 0; padding-left: 1rem; color: #4b5563; }
+.content ul:has(input[type="checkbox"]) { list-style: none; padding-left: 1rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">Shared with MiNo &middot; updated {{.UpdatedAt}}</div>
+<div class="content">{{.ContentHTML}}</div>
+</body>
+</html>
+`))
+
+// publicNotePage is the data of publicNoteTemplate
+type publicNotePage struct {
+	Title       string
+	UpdatedAt   string
+	ContentHTML template.HTML
+}
+
+// PublicNotePage renders a note as a standalone HTML page. The note's
+// ContentHTML must come from NoteHTML, it is sanitized once more anyway.
+func PublicNotePage(note models.PublicNote) (string, error) {
+	page := publicNotePage{
+		Title:       note.Title,
+		UpdatedAt:   note.UpdatedAt,
+		ContentHTML: template.HTML(Sanitize(note.ContentHTML)),
+	}
+
+	var buf bytes.Buffer
+	if err := publicNoteTemplate.Execute(&buf, page); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,209 @@
+// Package router dispatches API Gateway requests to handlers by method and
+// resource, so that one Lambda function can serve every route.
+//
+// Routes are resource templates as API Gateway names them, such as
+// /notes/{noteId}/items/{itemId}. A request is matched on its Resource when
+// API Gateway defines the route itself, and on its Path otherwise, for
+// example behind a {proxy+} resource, in which case the path parameters are
+// filled in from the path. Literal segments win over parameters, so
+// /notes/search is not taken for a note ID.
+package router
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HandlerFunc handles an API Gateway request, the signature of every
+// handler package's Handler
+type HandlerFunc func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Route maps a method and resource template to a handler
+type Route struct {
+	Method   string
+	Resource string
+	Handler  HandlerFunc
+}
+
+// corsHeaders are sent on responses the router makes itself. The allowed
+// headers are those any handler reads.
+var corsHeaders = map[string]string{
+	"Access-Control-Allow-Origin":  "*",
+	"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key,If-None-Match,If-Modified-Since,X-Share-Password,X-Request-ID",
+}
+
+// Router dispatches requests to the handlers of a route table
+type Router struct {
+	resources map[string]*resource // By template
+	ordered   []*resource          // Most specific first, for matching paths
+}
+
+// resource holds the handlers of one resource template by method
+type resource struct {
+	template string
+	segments []string
+	handlers map[string]HandlerFunc
+}
+
+// New returns a router for a route table. It panics on a route without a
+// handler or a method registered twice for a resource.
+func New(routes []Route) *Router {
+	r := &Router{resources: make(map[string]*resource)}
+	for _, route := range routes {
+		if route.Handler == nil {
+			panic("router: no handler for " + route.Method + " " + route.Resource)
+		}
+		res, ok := r.resources[route.Resource]
+		if !ok {
+			res = &resource{
+				template: route.Resource,
+				segments: split(route.Resource),
+				handlers: make(map[string]HandlerFunc),
+			}
+			r.resources[route.Resource] = res
+			r.ordered = append(r.ordered, res)
+		}
+		method := strings.ToUpper(route.Method)
+		if _, ok := res.handlers[method]; ok {
+			panic("router: " + method + " " + route.Resource + " registered twice")
+		}
+		res.handlers[method] = route.Handler
+	}
+
+	sort.SliceStable(r.ordered, func(i, j int) bool {
+		return moreSpecific(r.ordered[i].segments, r.ordered[j].segments)
+	})
+	return r
+}
+
+// Handle dispatches a request. It answers 404 for an unknown resource, 405
+// for a method the resource does not have and OPTIONS itself with the
+// methods it has.
+func (r *Router) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	res, params := r.match(request)
+	if res == nil {
+		return respond(404, nil, `{"success":false,"message":"Not found"}`), nil
+	}
+
+	allowed := res.methods()
+	method := strings.ToUpper(request.HTTPMethod)
+	handler, ok := res.handlers[method]
+	switch {
+	case ok:
+	case method == "OPTIONS":
+		return respond(204, map[string]string{
+			"Access-Control-Allow-Methods": allowed,
+			"Allow":                        allowed,
+		}, ""), nil
+	default:
+		return respond(405, map[string]string{
+			"Access-Control-Allow-Methods": allowed,
+			"Allow":                        allowed,
+		}, `{"success":false,"message":"Method not allowed"}`), nil
+	}
+
+	if params != nil {
+		request.Resource = res.template
+		request.PathParameters = params
+	}
+	return handler(ctx, request)
+}
+
+// match finds the resource of a request. The path parameters are nil when
+// API Gateway matched the resource and has set them already.
+func (r *Router) match(request events.APIGatewayProxyRequest) (*resource, map[string]string) {
+	if res, ok := r.resources[request.Resource]; ok {
+		return res, nil
+	}
+
+	segments := split(request.Path)
+	for _, res := range r.ordered {
+		if params, ok := res.matchPath(segments); ok {
+			return res, params
+		}
+	}
+	return nil, nil
+}
+
+// matchPath matches the segments of a request path against the template
+func (res *resource) matchPath(segments []string) (map[string]string, bool) {
+	if len(segments) != len(res.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range res.segments {
+		if name, ok := parameter(segment); ok {
+			value, err := url.PathUnescape(segments[i])
+			if err != nil || value == "" {
+				return nil, false
+			}
+			params[name] = value
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// methods lists the methods of a resource for the Allow header, OPTIONS
+// included
+func (res *resource) methods() string {
+	var methods []string
+	for method := range res.handlers {
+		if method != "OPTIONS" {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return strings.Join(append(methods, "OPTIONS"), ",")
+}
+
+// parameter returns the name of a {name} template segment
+func parameter(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}"), true
+	}
+	return "", false
+}
+
+// moreSpecific orders templates so that a literal segment is tried before a
+// parameter in the same place
+func moreSpecific(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		_, aParam := parameter(a[i])
+		_, bParam := parameter(b[i])
+		if aParam != bParam {
+			return !aParam
+		}
+	}
+	return len(a) < len(b)
+}
+
+// split splits a path into its segments, ignoring a trailing slash
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// respond builds a response the router makes itself
+func respond(statusCode int, extra map[string]string, body string) events.APIGatewayProxyResponse {
+	headers := map[string]string{"Content-Type": "application/json"}
+	for name, value := range corsHeaders {
+		headers[name] = value
+	}
+	for name, value := range extra {
+		headers[name] = value
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       body,
+	}
+}
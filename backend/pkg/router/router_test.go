@@ -0,0 +1,116 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// named returns a handler that answers with its name and the path parameters
+func named(name string) HandlerFunc {
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		body := name
+		for _, param := range []string{"noteId", "itemId"} {
+			if value, ok := request.PathParameters[param]; ok {
+				body += " " + param + "=" + value
+			}
+		}
+		return events.APIGatewayProxyResponse{StatusCode: 200, Body: body}, nil
+	}
+}
+
+func newTestRouter() *Router {
+	return New([]Route{
+		{Method: "GET", Resource: "/notes", Handler: named("get_notes")},
+		{Method: "POST", Resource: "/notes", Handler: named("create_note")},
+		{Method: "GET", Resource: "/notes/{noteId}", Handler: named("get_note")},
+		{Method: "DELETE", Resource: "/notes/{noteId}", Handler: named("delete_note")},
+		{Method: "GET", Resource: "/notes/search", Handler: named("search_notes")},
+		{Method: "PUT", Resource: "/notes/{noteId}/items/{itemId}", Handler: named("update_checklist_item")},
+		{Method: "PUT", Resource: "/notes/{noteId}/items/order", Handler: named("reorder_checklist_items")},
+	})
+}
+
+func TestHandle(t *testing.T) {
+	router := newTestRouter()
+
+	tests := []struct {
+		name    string
+		request events.APIGatewayProxyRequest
+		status  int
+		body    string
+		allow   string
+	}{
+		{
+			"resource matched by API Gateway",
+			events.APIGatewayProxyRequest{HTTPMethod: "DELETE", Resource: "/notes/{noteId}", Path: "/notes/n1", PathParameters: map[string]string{"noteId": "n1"}},
+			200, "delete_note noteId=n1", "",
+		},
+		{
+			"path behind a proxy resource",
+			events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/{proxy+}", Path: "/notes/n%201"},
+			200, "get_note noteId=n 1", "",
+		},
+		{
+			"literal segment before a parameter",
+			events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/notes/search/"},
+			200, "search_notes", "",
+		},
+		{
+			"literal segment deeper in the path",
+			events.APIGatewayProxyRequest{HTTPMethod: "PUT", Path: "/notes/n1/items/order"},
+			200, "reorder_checklist_items noteId=n1", "",
+		},
+		{
+			"two parameters",
+			events.APIGatewayProxyRequest{HTTPMethod: "PUT", Path: "/notes/n1/items/i2"},
+			200, "update_checklist_item noteId=n1 itemId=i2", "",
+		},
+		{
+			"unknown resource",
+			events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/notes/n1/unknown"},
+			404, `{"success":false,"message":"Not found"}`, "",
+		},
+		{
+			"unknown method",
+			events.APIGatewayProxyRequest{HTTPMethod: "PATCH", Path: "/notes/n1"},
+			405, `{"success":false,"message":"Method not allowed"}`, "DELETE,GET,OPTIONS",
+		},
+		{
+			"preflight",
+			events.APIGatewayProxyRequest{HTTPMethod: "OPTIONS", Path: "/notes"},
+			204, "", "GET,POST,OPTIONS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := router.Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.StatusCode != tt.status || response.Body != tt.body {
+				t.Errorf("got %d %q, want %d %q", response.StatusCode, response.Body, tt.status, tt.body)
+			}
+			if allow := response.Headers["Allow"]; allow != tt.allow {
+				t.Errorf("got Allow %q, want %q", allow, tt.allow)
+			}
+			if tt.allow != "" && response.Headers["Access-Control-Allow-Methods"] != tt.allow {
+				t.Errorf("got Access-Control-Allow-Methods %q", response.Headers["Access-Control-Allow-Methods"])
+			}
+		})
+	}
+}
+
+func TestNewRejectsDuplicates(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a route twice did not panic")
+		}
+	}()
+	New([]Route{
+		{Method: "GET", Resource: "/notes", Handler: named("a")},
+		{Method: "get", Resource: "/notes", Handler: named("b")},
+	})
+}
@@ -0,0 +1,383 @@
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scoring weights for ranking
+const (
+	TitleWeight       = 3.0 // A term in the title counts as much as three in the content
+	PrefixMatchWeight = 0.5 // Prefix matches score half of exact matches
+)
+
+// MinTermLength is the shortest stemmed term that is indexed
+const MinTermLength = 2
+
+// snippetRadius is the number of characters kept on each side of the first match
+const snippetRadius = 60
+
+// stopWords are common English words that are not worth indexing
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "no": true, "not": true, "of": true,
+	"on": true, "or": true, "such": true, "that": true, "the": true, "their": true,
+	"then": true, "there": true, "these": true, "they": true, "this": true,
+	"to": true, "was": true, "will": true, "with": true,
+}
+
+// Token is a word found in a text together with its byte offsets
+type Token struct {
+	Term  string // Stemmed, lowercased form used for indexing
+	Word  string // Lowercased form as written, matched by prefixes
+	Start int
+	End   int
+}
+
+// Tokenize splits text into lowercased, stemmed tokens, skipping stop words
+func Tokenize(text string) []Token {
+	var tokens []Token
+
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		wordStart := start
+		word := strings.ToLower(text[start:end])
+		start = -1
+		if stopWords[word] {
+			return
+		}
+		term := Stem(word)
+		if len(term) < MinTermLength {
+			return
+		}
+		tokens = append(tokens, Token{Term: term, Word: word, Start: wordStart, End: end})
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+
+	return tokens
+}
+
+// Terms returns the frequency of every term in text
+func Terms(text string) map[string]int {
+	terms := make(map[string]int)
+	for _, token := range Tokenize(text) {
+		terms[token.Term]++
+	}
+	return terms
+}
+
+// Words returns the words of text that stemming changes, with their stem.
+// They are indexed next to the stems so that a partial word finds them:
+// "runn" is not a prefix of the stem "run", but it is of "running".
+func Words(text string) map[string]string {
+	words := make(map[string]string)
+	for _, token := range Tokenize(text) {
+		if token.Word != token.Term {
+			words[token.Word] = token.Term
+		}
+	}
+	return words
+}
+
+// QueryTerm is a word of a search query. It matches an indexed term equal to
+// its stem, or any indexed term or word starting with the word as typed,
+// which is not stemmed since it may be cut short.
+type QueryTerm struct {
+	Stem string
+	Word string
+}
+
+// QueryTerms returns the distinct terms of a search query in query order
+func QueryTerms(query string) []QueryTerm {
+	var terms []QueryTerm
+	seen := make(map[QueryTerm]bool)
+	for _, token := range Tokenize(query) {
+		term := QueryTerm{Stem: token.Term, Word: token.Word}
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// LookupPrefix is the prefix of the indexed terms that may match a query
+// term, the longest one its stem and word share
+func (q QueryTerm) LookupPrefix() string {
+	n := 0
+	for n < len(q.Stem) && n < len(q.Word) && q.Stem[n] == q.Word[n] {
+		n++
+	}
+	return q.Stem[:n]
+}
+
+// stemSuffixes are stripped in order, the first match wins
+var stemSuffixes = []struct {
+	suffix      string
+	replacement string
+}{
+	{"ational", "ate"},
+	{"ization", "ize"},
+	{"fulness", "ful"},
+	{"iveness", "ive"},
+	{"ousness", "ous"},
+	{"nesses", ""},
+	{"ments", ""},
+	{"ement", ""},
+	{"ness", ""},
+	{"ment", ""},
+	{"sses", "ss"},
+	{"ies", "y"},
+	{"ches", "ch"},
+	{"shes", "sh"},
+	{"xes", "x"},
+	{"ing", ""},
+	{"edly", ""},
+	{"ed", ""},
+	{"ly", ""},
+	{"s", ""},
+}
+
+// Stem reduces a lowercased word to its stem with a light suffix-stripping
+// algorithm. Words are never cut below three characters, and "ly" is only
+// stripped from what looks like an adverb, so that "family" stays whole.
+func Stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+
+	for _, rule := range stemSuffixes {
+		if !strings.HasSuffix(word, rule.suffix) {
+			continue
+		}
+		stem := word[:len(word)-len(rule.suffix)] + rule.replacement
+		if len(stem) < 3 || (rule.suffix == "s" && strings.HasSuffix(word, "ss")) {
+			continue
+		}
+		if rule.suffix == "ly" && !adverbStem(stem) {
+			continue
+		}
+		// "running" -> "runn" -> "run"
+		if (rule.suffix == "ing" || rule.suffix == "ed") && len(stem) > 3 && stem[len(stem)-1] == stem[len(stem)-2] &&
+			!strings.ContainsRune("lsz", rune(stem[len(stem)-1])) {
+			stem = stem[:len(stem)-1]
+		}
+		return stem
+	}
+
+	return word
+}
+
+// adverbStem reports whether stem is likely what is left of an adverb once
+// "ly" is stripped: at least four letters, not ending in a vowel other than
+// e, and not in a doubled letter as in "silly"
+func adverbStem(stem string) bool {
+	if len(stem) < 4 {
+		return false
+	}
+	last := stem[len(stem)-1]
+	if strings.IndexByte("aiouy", last) >= 0 {
+		return false
+	}
+	return last != stem[len(stem)-2]
+}
+
+// MatchTerm reports whether an indexed term, and the word it was indexed
+// from when stemming changed it, satisfies a query term, either exactly or
+// as a prefix
+func MatchTerm(term string, word string, query QueryTerm) (match bool, exact bool) {
+	if term == query.Stem {
+		return true, true
+	}
+	if strings.HasPrefix(term, query.Word) || word != "" && strings.HasPrefix(word, query.Word) {
+		return true, false
+	}
+	return false, false
+}
+
+// Posting is an entry of the inverted index: a term found in a note, with
+// the number of times it appears in the title and the content. Word is set
+// on the entries of words that stemming changes, whose frequencies are
+// those of their stem.
+type Posting struct {
+	NoteID      string
+	Term        string
+	Word        string
+	TitleFreq   int
+	ContentFreq int
+}
+
+// Rank scores the notes found by a query and returns their IDs, best first,
+// at most limit of them when limit is positive. postings[i] are the index
+// entries looked up for terms[i]; those that do not match are ignored. A
+// note scores the frequency of each matching term, dampened and weighted up
+// in the title, once per stem and query term, and is favoured when it
+// matches more of the query terms.
+func Rank(terms []QueryTerm, postings [][]Posting, limit int) ([]string, map[string]float64) {
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+
+	for i, term := range terms {
+		counted := make(map[[2]string]bool) // Note and stem
+		found := make(map[string]bool)
+		for _, posting := range postings[i] {
+			ok, exact := MatchTerm(posting.Term, posting.Word, term)
+			key := [2]string{posting.NoteID, posting.Term}
+			if !ok || counted[key] {
+				continue
+			}
+			weight := 1.0
+			if !exact {
+				weight = PrefixMatchWeight
+			}
+			if !found[posting.NoteID] {
+				found[posting.NoteID] = true
+				matched[posting.NoteID]++
+			}
+			counted[key] = true
+			scores[posting.NoteID] += weight * (TitleWeight*termWeight(posting.TitleFreq) + termWeight(posting.ContentFreq))
+		}
+	}
+
+	noteIDs := make([]string, 0, len(scores))
+	for noteID := range scores {
+		scores[noteID] *= float64(matched[noteID]) / float64(len(terms))
+		noteIDs = append(noteIDs, noteID)
+	}
+	sort.Slice(noteIDs, func(i, j int) bool {
+		if scores[noteIDs[i]] != scores[noteIDs[j]] {
+			return scores[noteIDs[i]] > scores[noteIDs[j]]
+		}
+		return noteIDs[i] < noteIDs[j]
+	})
+	if limit > 0 && len(noteIDs) > limit {
+		noteIDs = noteIDs[:limit]
+	}
+	return noteIDs, scores
+}
+
+// termWeight dampens the frequency of a term so that repeating a word does
+// not dominate the ranking
+func termWeight(freq int) float64 {
+	if freq <= 0 {
+		return 0
+	}
+	return 1 + math.Log(float64(freq))
+}
+
+// Highlight returns text HTML-escaped with every token matching one of the
+// query terms wrapped in <mark> tags
+func Highlight(text string, queryTerms []QueryTerm) string {
+	return highlightRange(text, queryTerms, 0, len(text))
+}
+
+// Snippet returns an HTML-escaped excerpt of text around the first match of
+// the query terms, with matches wrapped in <mark> tags
+func Snippet(text string, queryTerms []QueryTerm) string {
+	tokens := matchingTokens(text, queryTerms)
+
+	start, end := 0, len(text)
+	if len(tokens) > 0 {
+		start = tokens[0].Start - snippetRadius
+		end = tokens[0].End + snippetRadius
+	} else {
+		end = 2 * snippetRadius
+	}
+	start, end = clampToWords(text, start, end)
+	for start < end && isSpaceByte(text[start]) {
+		start++
+	}
+	for end > start && isSpaceByte(text[end-1]) {
+		end--
+	}
+
+	snippet := highlightRange(text, queryTerms, start, end)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// highlightRange escapes text[start:end] and marks matching tokens in it
+func highlightRange(text string, queryTerms []QueryTerm, start int, end int) string {
+	var b strings.Builder
+	pos := start
+	for _, token := range matchingTokens(text, queryTerms) {
+		if token.Start < start || token.End > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:token.Start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[token.Start:token.End]))
+		b.WriteString("</mark>")
+		pos = token.End
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	return b.String()
+}
+
+// matchingTokens returns the tokens of text matching any query term, in order
+func matchingTokens(text string, queryTerms []QueryTerm) []Token {
+	var matches []Token
+	for _, token := range Tokenize(text) {
+		for _, term := range queryTerms {
+			if ok, _ := MatchTerm(token.Term, token.Word, term); ok {
+				matches = append(matches, token)
+				break
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
+
+// clampToWords keeps a byte range inside text and moves its edges to word
+// boundaries so snippets do not cut words or UTF-8 sequences in half
+func clampToWords(text string, start int, end int) (int, int) {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+	for start > 0 && !isBoundary(text, start) {
+		start--
+	}
+	for end < len(text) && !isBoundary(text, end) {
+		end++
+	}
+	return start, end
+}
+
+// isBoundary reports whether position i in text sits right before whitespace
+// or right after it
+func isBoundary(text string, i int) bool {
+	return isSpaceByte(text[i]) || isSpaceByte(text[i-1])
+}
+
+// isSpaceByte reports whether b is ASCII whitespace, which never appears
+// inside a multi-byte UTF-8 sequence
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r'
+}
@@ -0,0 +1,229 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"run", "run"},
+		{"runs", "run"},
+		{"running", "run"},
+		{"stopped", "stop"},
+		{"falling", "fall"},
+		{"notes", "note"},
+		{"class", "class"},
+		{"classes", "class"},
+		{"families", "family"},
+		{"family", "family"},
+		{"early", "early"},
+		{"silly", "silly"},
+		{"quickly", "quick"},
+		{"lovely", "love"},
+		{"boxes", "box"},
+		{"churches", "church"},
+		{"happiness", "happi"},
+		{"payments", "pay"},
+		{"relational", "relate"},
+		{"organization", "organize"},
+		{"its", "its"},
+		{"is", "is"},
+	}
+	for _, tt := range tests {
+		if got := Stem(tt.word); got != tt.want {
+			t.Errorf("Stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	text := "The Café is running, and the 2 runners ran!"
+	want := []Token{
+		{Term: "café", Word: "café", Start: 4, End: 9},
+		{Term: "run", Word: "running", Start: 13, End: 20},
+		{Term: "runner", Word: "runners", Start: 32, End: 39},
+		{Term: "ran", Word: "ran", Start: 40, End: 43},
+	}
+	if got := Tokenize(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize(%q) = %+v, want %+v", text, got, want)
+	}
+	for _, token := range want {
+		if strings.ToLower(text[token.Start:token.End]) != token.Word {
+			t.Errorf("offsets of %q point at %q", token.Word, text[token.Start:token.End])
+		}
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	got := QueryTerms("Running the runs, RUNNING")
+	want := []QueryTerm{{Stem: "run", Word: "running"}, {Stem: "run", Word: "runs"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := QueryTerms("the of and"); len(got) != 0 {
+		t.Errorf("stop words gave %+v", got)
+	}
+}
+
+func TestMatchTerm(t *testing.T) {
+	tests := []struct {
+		name  string
+		term  string
+		word  string
+		query string
+		match bool
+		exact bool
+	}{
+		{"same stem", "run", "running", "runs", true, true},
+		{"stem prefix", "runner", "", "runn", true, false},
+		{"partial word of a stemmed word", "run", "running", "runn", true, false},
+		{"partial word of a stem", "run", "", "runn", false, false},
+		{"stem longer than the query", "family", "families", "fam", true, false},
+		{"no match", "walk", "walking", "run", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := QueryTerms(tt.query)[0]
+			match, exact := MatchTerm(tt.term, tt.word, query)
+			if match != tt.match || exact != tt.exact {
+				t.Errorf("MatchTerm(%q, %q, %+v) = %t, %t, want %t, %t", tt.term, tt.word, query, match, exact, tt.match, tt.exact)
+			}
+		})
+	}
+}
+
+func TestLookupPrefix(t *testing.T) {
+	for query, want := range map[string]string{
+		"running":  "run",
+		"runn":     "runn", // Found through the words
+		"families": "famil",
+		"notes":    "note",
+	} {
+		if got := QueryTerms(query)[0].LookupPrefix(); got != want {
+			t.Errorf("LookupPrefix of %q = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	got := Words("Running notes run")
+	want := map[string]string{"running": "run", "notes": "note"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		query string
+		want  string
+	}{
+		{"Running <fast>", "run", "<mark>Running</mark> &lt;fast&gt;"},
+		{"Runners & running", "runn", "<mark>Runners</mark> &amp; <mark>running</mark>"},
+		{"Milk, eggs", "bread", "Milk, eggs"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.text, QueryTerms(tt.query)); got != tt.want {
+			t.Errorf("Highlight(%q, %q) = %q, want %q", tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("lorem ipsum ", 20)
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"short text", "Buy milk", "Buy <mark>milk</mark>"},
+		{"match in the middle", long + "buy milk " + long,
+			"…" + strings.Repeat("lorem ipsum ", 5) + "buy <mark>milk</mark> " + strings.TrimSpace(strings.Repeat("lorem ipsum ", 5)) + "…"},
+		{"no match", long, strings.TrimSpace(strings.Repeat("lorem ipsum ", 10)) + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, QueryTerms("milk")); got != tt.want {
+				t.Errorf("got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+
+	// Edges move to word boundaries, never into a UTF-8 sequence
+	text := strings.Repeat("ééé ", 30) + "milk" + strings.Repeat(" üüü", 30)
+	got := Snippet(text, QueryTerms("milk"))
+	if !utf8.ValidString(got) || !strings.HasPrefix(got, "…ééé") || !strings.HasSuffix(got, "üüü…") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRank(t *testing.T) {
+	terms := QueryTerms("buy runn")
+
+	tests := []struct {
+		name     string
+		postings [][]Posting
+		limit    int
+		want     []string
+	}{
+		{
+			"title outweighs content",
+			[][]Posting{{
+				{NoteID: "content", Term: "buy", ContentFreq: 1},
+				{NoteID: "title", Term: "buy", TitleFreq: 1},
+			}, nil},
+			0, []string{"title", "content"},
+		},
+		{
+			"prefix of a stem or a word",
+			[][]Posting{nil, {
+				{NoteID: "prefix", Term: "runner", ContentFreq: 1},
+				{NoteID: "word", Term: "run", Word: "running", ContentFreq: 1},
+				{NoteID: "none", Term: "runway", ContentFreq: 5},
+			}},
+			0, []string{"prefix", "word"},
+		},
+		{
+			"more query terms outweigh frequency",
+			[][]Posting{
+				{{NoteID: "both", Term: "buy", ContentFreq: 1}, {NoteID: "frequent", Term: "buy", ContentFreq: 3}},
+				{{NoteID: "both", Term: "run", Word: "running", ContentFreq: 1}},
+			},
+			0, []string{"both", "frequent"},
+		},
+		{
+			"a stem counts once per query term",
+			[][]Posting{nil, {
+				{NoteID: "a", Term: "run", Word: "running", ContentFreq: 2},
+				{NoteID: "a", Term: "run", Word: "runnings", ContentFreq: 2},
+				{NoteID: "b", Term: "run", Word: "running", ContentFreq: 3},
+			}},
+			0, []string{"b", "a"},
+		},
+		{
+			"limit",
+			[][]Posting{{
+				{NoteID: "a", Term: "buy", ContentFreq: 1},
+				{NoteID: "b", Term: "buy", ContentFreq: 1},
+				{NoteID: "c", Term: "buy", TitleFreq: 1},
+			}, nil},
+			2, []string{"c", "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, scores := Rank(terms, tt.postings, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v (scores %v), want %v", got, scores, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// URLExpiry is how long presigned upload and download URLs are valid
+const URLExpiry = 15 * time.Minute
+
+// UploadWindow is how long after presigning an upload its file may still
+// arrive, a PUT started just before the URL expires has time to finish
+const UploadWindow = URLExpiry + 15*time.Minute
+
+// S3 clients
+var (
+	s3Client      *s3.Client
+	presignClient *s3.PresignClient
+)
+
+func init() {
+	// Configure AWS SDK for LocalStack
+	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		return aws.Endpoint{
+			URL:               "http://192.168.0.250:4566",
+			HostnameImmutable: true,
+		}, nil
+	})
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(),
+		config.WithRegion("us-east-1"),
+		config.WithEndpointResolverWithOptions(customResolver),
+		config.WithCredentialsProvider(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{
+				AccessKeyID:     "test",
+				SecretAccessKey: "test",
+			}, nil
+		})),
+	)
+	if err != nil {
+		log.Fatalf("Unable to load SDK config: %v", err)
+	}
+
+	// LocalStack serves buckets by path rather than by host name
+	s3Client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = true
+	})
+	presignClient = s3.NewPresignClient(s3Client, s3.WithPresignExpires(URLExpiry))
+}
+
+// PresignUpload returns a PUT request that uploads exactly one object of the
+// given type, size and base64 SHA-256 checksum to key
+func PresignUpload(ctx context.Context, key string, contentType string, size int64, checksum string) (*models.PresignedRequest, error) {
+	request, err := presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:         aws.String(os.Getenv("ATTACHMENTS_BUCKET")),
+		Key:            aws.String(key),
+		ContentType:    aws.String(contentType),
+		ContentLength:  size,
+		ChecksumSHA256: aws.String(checksum),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return presigned(request.URL, request.Method, request.SignedHeader), nil
+}
+
+// PresignDownload returns a GET request for key that saves the object as name
+func PresignDownload(ctx context.Context, key string, name string) (*models.PresignedRequest, error) {
+	request, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket:                     aws.String(os.Getenv("ATTACHMENTS_BUCKET")),
+		Key:                        aws.String(key),
+		ResponseContentDisposition: aws.String(mime.FormatMediaType("attachment", map[string]string{"filename": name})),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return presigned(request.URL, request.Method, nil), nil
+}
+
+// ObjectExists reports whether an object was uploaded to key
+func ObjectExists(ctx context.Context, key string) (bool, error) {
+	_, err := s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(os.Getenv("ATTACHMENTS_BUCKET")),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteObject removes an object, deleting a missing object is not an error
+func DeleteObject(ctx context.Context, key string) error {
+	_, err := s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(os.Getenv("ATTACHMENTS_BUCKET")),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// presigned converts a presigned request, leaving out the Host header that
+// HTTP clients set on their own
+func presigned(url string, method string, signed http.Header) *models.PresignedRequest {
+	request := &models.PresignedRequest{
+		URL:       url,
+		Method:    method,
+		ExpiresAt: time.Now().Add(URLExpiry).UTC().Format(time.RFC3339),
+	}
+	for name := range signed {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			continue
+		}
+		if request.Headers == nil {
+			request.Headers = make(map[string]string)
+		}
+		request.Headers[name] = signed.Get(name)
+	}
+	return request
+}
@@ -0,0 +1,132 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omidiyanto/mino/pkg/envelope"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// ErrDecryptImage wraps an image that could not be decrypted for now, such as
+// when KMS cannot be reached. Unlike a malformed image, retrying the record
+// can fix it.
+var ErrDecryptImage = errors.New("decrypting image")
+
+// DecodeRecord turns a DynamoDB stream record of the notes table into a NoteChange
+func DecodeRecord(record events.DynamoDBEventRecord) (NoteChange, error) {
+	change := NoteChange{
+		EventID:   record.EventID,
+		EventName: record.EventName,
+	}
+
+	switch record.EventName {
+	case EventInsert, EventModify, EventRemove:
+	default:
+		return change, fmt.Errorf("unknown event name: %q", record.EventName)
+	}
+
+	if len(record.Change.OldImage) > 0 {
+		note, err := decodeNote(record.Change.OldImage)
+		if err != nil {
+			return change, fmt.Errorf("decoding old image: %w", err)
+		}
+		change.OldNote = note
+	}
+
+	if len(record.Change.NewImage) > 0 {
+		note, err := decodeNote(record.Change.NewImage)
+		if err != nil {
+			return change, fmt.Errorf("decoding new image: %w", err)
+		}
+		change.NewNote = note
+	}
+
+	if change.OldNote == nil && change.NewNote == nil {
+		return change, fmt.Errorf("record %s has no images, is the stream view type NEW_AND_OLD_IMAGES?", record.EventID)
+	}
+
+	return change, nil
+}
+
+// decodeNote unmarshals a stream image into a note and decrypts it
+func decodeNote(image map[string]events.DynamoDBAttributeValue) (*models.Note, error) {
+	item, err := toAttributeValueMap(image)
+	if err != nil {
+		return nil, err
+	}
+
+	var note models.Note
+	if err := attributevalue.UnmarshalMap(item, &note); err != nil {
+		return nil, err
+	}
+
+	// Images carry the title and content as stored, encrypted at rest. A
+	// value that is malformed or does not authenticate never decrypts, other
+	// errors come from the keyring and may pass.
+	if err := envelope.Default.DecryptNote(context.Background(), &note); err != nil {
+		if errors.Is(err, envelope.ErrMalformed) || errors.Is(err, envelope.ErrDecrypt) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrDecryptImage, err)
+	}
+
+	return &note, nil
+}
+
+// toAttributeValueMap converts a stream image to the SDK attribute value type
+func toAttributeValueMap(image map[string]events.DynamoDBAttributeValue) (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, len(image))
+	for name, value := range image {
+		converted, err := toAttributeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		item[name] = converted
+	}
+	return item, nil
+}
+
+// toAttributeValue converts a single stream attribute value to the SDK type
+func toAttributeValue(value events.DynamoDBAttributeValue) (types.AttributeValue, error) {
+	switch value.DataType() {
+	case events.DataTypeString:
+		return &types.AttributeValueMemberS{Value: value.String()}, nil
+	case events.DataTypeNumber:
+		return &types.AttributeValueMemberN{Value: value.Number()}, nil
+	case events.DataTypeBoolean:
+		return &types.AttributeValueMemberBOOL{Value: value.Boolean()}, nil
+	case events.DataTypeBinary:
+		return &types.AttributeValueMemberB{Value: value.Binary()}, nil
+	case events.DataTypeNull:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case events.DataTypeStringSet:
+		return &types.AttributeValueMemberSS{Value: value.StringSet()}, nil
+	case events.DataTypeNumberSet:
+		return &types.AttributeValueMemberNS{Value: value.NumberSet()}, nil
+	case events.DataTypeBinarySet:
+		return &types.AttributeValueMemberBS{Value: value.BinarySet()}, nil
+	case events.DataTypeList:
+		list := make([]types.AttributeValue, 0, len(value.List()))
+		for _, element := range value.List() {
+			converted, err := toAttributeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, converted)
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	case events.DataTypeMap:
+		item, err := toAttributeValueMap(value.Map())
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberM{Value: item}, nil
+	default:
+		return nil, fmt.Errorf("unsupported data type %v", value.DataType())
+	}
+}
@@ -0,0 +1,221 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Stream event names sent by DynamoDB
+const (
+	EventInsert = "INSERT"
+	EventModify = "MODIFY"
+	EventRemove = "REMOVE"
+)
+
+// Default retry settings of a Dispatcher
+const (
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = 200 * time.Millisecond
+)
+
+// NoteChange is a decoded change to a note. OldNote is nil for inserts and
+// NewNote is nil for removals.
+type NoteChange struct {
+	EventID   string
+	EventName string
+	OldNote   *models.Note
+	NewNote   *models.Note
+}
+
+// Rewritten reports whether a MODIFY record rewrote a note without any change
+// its users can see, as re-encryption does. Every user change bumps the
+// version.
+func (c NoteChange) Rewritten() bool {
+	return c.EventName == EventModify && c.OldNote != nil && c.NewNote != nil && c.OldNote.Version == c.NewNote.Version
+}
+
+// Note returns the current state of the changed note, or its last state when
+// it was removed
+func (c NoteChange) Note() *models.Note {
+	if c.NewNote != nil {
+		return c.NewNote
+	}
+	return c.OldNote
+}
+
+// Projector keeps a piece of derived data in sync with the notes table
+type Projector interface {
+	// Name identifies the projector in logs and dead letters
+	Name() string
+	// Project applies one change. It must be idempotent, changes can be delivered more than once.
+	Project(ctx context.Context, change NoteChange) error
+}
+
+// ProjectorFunc adapts a function to the Projector interface
+type ProjectorFunc struct {
+	ProjectorName string
+	Func          func(ctx context.Context, change NoteChange) error
+}
+
+// Name returns the projector name
+func (p ProjectorFunc) Name() string {
+	return p.ProjectorName
+}
+
+// Project calls the wrapped function
+func (p ProjectorFunc) Project(ctx context.Context, change NoteChange) error {
+	return p.Func(ctx, change)
+}
+
+// DeadLetter records a change a projector gave up on
+type DeadLetter struct {
+	EventID   string `json:"eventId" dynamodbav:"eventId"`
+	Projector string `json:"projector" dynamodbav:"projector"`
+	EventName string `json:"eventName" dynamodbav:"eventName"`
+	Error     string `json:"error" dynamodbav:"error"`
+	Attempts  int    `json:"attempts" dynamodbav:"attempts"`
+	Record    string `json:"record" dynamodbav:"record"` // Raw stream record as JSON, for replay
+	FailedAt  string `json:"failedAt" dynamodbav:"failedAt"`
+}
+
+// DeadLetterSink stores dead letters
+type DeadLetterSink interface {
+	SendDeadLetter(ctx context.Context, letter DeadLetter) error
+}
+
+// Dispatcher decodes stream records and hands them to registered projectors
+type Dispatcher struct {
+	MaxAttempts int           // Attempts per projector and record before dead-lettering
+	Backoff     time.Duration // Delay before the first retry, doubled on each retry
+	DeadLetters DeadLetterSink
+
+	projectors []Projector
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+// NewDispatcher creates a dispatcher with the default retry settings
+func NewDispatcher(deadLetters DeadLetterSink) *Dispatcher {
+	return &Dispatcher{
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+		DeadLetters: deadLetters,
+		sleep:       sleepContext,
+	}
+}
+
+// Register adds a projector. Projectors run in registration order.
+func (d *Dispatcher) Register(projector Projector) {
+	d.projectors = append(d.projectors, projector)
+}
+
+// Handle processes a stream batch in order. It stops at the first record
+// whose change could neither be projected nor dead-lettered, and reports it
+// and every record after it as batch item failures. Lambda retries from the
+// first failure, so later changes are never applied before earlier ones.
+func (d *Dispatcher) Handle(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	var response events.DynamoDBEventResponse
+
+	for i, record := range event.Records {
+		if err := d.handleRecord(ctx, record); err != nil {
+			for _, unprocessed := range event.Records[i:] {
+				response.BatchItemFailures = append(response.BatchItemFailures, events.DynamoDBBatchItemFailure{
+					ItemIdentifier: unprocessed.Change.SequenceNumber,
+				})
+			}
+			break
+		}
+	}
+
+	return response, nil
+}
+
+// handleRecord decodes one record and runs every projector on it
+func (d *Dispatcher) handleRecord(ctx context.Context, record events.DynamoDBEventRecord) error {
+	change, err := DecodeRecord(record)
+	if errors.Is(err, ErrDecryptImage) {
+		// Left for Lambda to retry, the keyring may be back by then
+		return err
+	}
+	if err != nil {
+		// Retrying cannot fix a record that does not decode
+		return d.deadLetter(ctx, record, "decoder", 1, err)
+	}
+
+	for _, projector := range d.projectors {
+		attempts, err := d.project(ctx, projector, change)
+		if err == nil {
+			continue
+		}
+		if err := d.deadLetter(ctx, record, projector.Name(), attempts, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// project runs a projector with exponential backoff between attempts
+func (d *Dispatcher) project(ctx context.Context, projector Projector, change NoteChange) (int, error) {
+	maxAttempts := d.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	backoff := d.Backoff
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = projector.Project(ctx, change); err == nil {
+			return attempt, nil
+		}
+		if attempt == maxAttempts {
+			return attempt, err
+		}
+		if sleepErr := d.sleep(ctx, backoff); sleepErr != nil {
+			return attempt, err
+		}
+		backoff *= 2
+	}
+
+	return maxAttempts, err
+}
+
+// deadLetter hands a failed record to the dead letter sink
+func (d *Dispatcher) deadLetter(ctx context.Context, record events.DynamoDBEventRecord, projector string, attempts int, cause error) error {
+	if d.DeadLetters == nil {
+		return cause
+	}
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding dead letter: %w", err)
+	}
+
+	return d.DeadLetters.SendDeadLetter(ctx, DeadLetter{
+		EventID:   record.EventID,
+		Projector: projector,
+		EventName: record.EventName,
+		Error:     cause.Error(),
+		Attempts:  attempts,
+		Record:    string(raw),
+		FailedAt:  models.GetTimeNow(),
+	})
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
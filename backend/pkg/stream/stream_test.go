@@ -0,0 +1,293 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// loadEvent reads a recorded stream event from testdata
+func loadEvent(t *testing.T, name string) events.DynamoDBEvent {
+	t.Helper()
+
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event events.DynamoDBEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+// recordingSink keeps dead letters in memory
+type recordingSink struct {
+	letters []DeadLetter
+	err     error
+}
+
+func (s *recordingSink) SendDeadLetter(ctx context.Context, letter DeadLetter) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.letters = append(s.letters, letter)
+	return nil
+}
+
+// newTestDispatcher returns a dispatcher that does not wait between retries
+func newTestDispatcher(sink DeadLetterSink) *Dispatcher {
+	d := NewDispatcher(sink)
+	d.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	return d
+}
+
+func TestDecodeRecord(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		eventName string
+		hasOld    bool
+		hasNew    bool
+	}{
+		{"notes-insert.json", EventInsert, false, true},
+		{"notes-modify.json", EventModify, true, true},
+		{"notes-remove.json", EventRemove, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			event := loadEvent(t, tt.fixture)
+
+			change, err := DecodeRecord(event.Records[0])
+			if err != nil {
+				t.Fatalf("DecodeRecord: %v", err)
+			}
+			if change.EventName != tt.eventName {
+				t.Errorf("EventName = %q, want %q", change.EventName, tt.eventName)
+			}
+			if (change.OldNote != nil) != tt.hasOld {
+				t.Errorf("OldNote present = %v, want %v", change.OldNote != nil, tt.hasOld)
+			}
+			if (change.NewNote != nil) != tt.hasNew {
+				t.Errorf("NewNote present = %v, want %v", change.NewNote != nil, tt.hasNew)
+			}
+			if got := change.Note().NoteID; got != "3f1c2a9e-8d4b-4c6e-9a71-0b2d5e6f7a81" {
+				t.Errorf("NoteID = %q", got)
+			}
+		})
+	}
+}
+
+func TestDecodeRecordModifyImages(t *testing.T) {
+	change, err := DecodeRecord(loadEvent(t, "notes-modify.json").Records[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if change.OldNote.Pinned || !change.NewNote.Pinned {
+		t.Errorf("pinned old/new = %v/%v, want false/true", change.OldNote.Pinned, change.NewNote.Pinned)
+	}
+	if change.OldNote.NotebookID != "" || change.NewNote.NotebookID == "" {
+		t.Errorf("notebookId old/new = %q/%q", change.OldNote.NotebookID, change.NewNote.NotebookID)
+	}
+	if change.NewNote.Content != "Milk, eggs, coffee beans and bread" {
+		t.Errorf("Content = %q", change.NewNote.Content)
+	}
+}
+
+func TestDecodeRecordUnknownEvent(t *testing.T) {
+	record := loadEvent(t, "notes-insert.json").Records[0]
+	record.EventName = "TRUNCATE"
+
+	if _, err := DecodeRecord(record); err == nil {
+		t.Fatal("expected an error for an unknown event name")
+	}
+}
+
+func TestDispatcherRunsProjectorsInOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) Projector {
+		return ProjectorFunc{ProjectorName: name, Func: func(ctx context.Context, change NoteChange) error {
+			calls = append(calls, name+":"+change.EventName)
+			return nil
+		}}
+	}
+
+	sink := &recordingSink{}
+	d := newTestDispatcher(sink)
+	d.Register(record("first"))
+	d.Register(record("second"))
+
+	for _, fixture := range []string{"notes-insert.json", "notes-modify.json", "notes-remove.json"} {
+		response, err := d.Handle(context.Background(), loadEvent(t, fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.BatchItemFailures) != 0 {
+			t.Fatalf("%s: unexpected failures %v", fixture, response.BatchItemFailures)
+		}
+	}
+
+	want := []string{
+		"first:INSERT", "second:INSERT",
+		"first:MODIFY", "second:MODIFY",
+		"first:REMOVE", "second:REMOVE",
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("calls = %v, want %v", calls, want)
+		}
+	}
+	if len(sink.letters) != 0 {
+		t.Errorf("unexpected dead letters: %v", sink.letters)
+	}
+}
+
+func TestDispatcherRetriesThenDeadLetters(t *testing.T) {
+	attempts := 0
+	sink := &recordingSink{}
+	d := newTestDispatcher(sink)
+	d.Register(ProjectorFunc{ProjectorName: "flaky", Func: func(ctx context.Context, change NoteChange) error {
+		attempts++
+		return errors.New("boom")
+	}})
+
+	response, err := d.Handle(context.Background(), loadEvent(t, "notes-insert.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != DefaultMaxAttempts {
+		t.Errorf("attempts = %d, want %d", attempts, DefaultMaxAttempts)
+	}
+	if len(response.BatchItemFailures) != 0 {
+		t.Errorf("dead-lettered record should not be reported as failed: %v", response.BatchItemFailures)
+	}
+	if len(sink.letters) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(sink.letters))
+	}
+
+	letter := sink.letters[0]
+	if letter.Projector != "flaky" || letter.Error != "boom" || letter.Attempts != DefaultMaxAttempts {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+
+	// The stored record can be replayed
+	var replay events.DynamoDBEventRecord
+	if err := json.Unmarshal([]byte(letter.Record), &replay); err != nil {
+		t.Fatalf("dead letter record does not decode: %v", err)
+	}
+	if _, err := DecodeRecord(replay); err != nil {
+		t.Errorf("replayed record: %v", err)
+	}
+}
+
+func TestDispatcherRecoversAfterRetry(t *testing.T) {
+	attempts := 0
+	sink := &recordingSink{}
+	d := newTestDispatcher(sink)
+	d.Register(ProjectorFunc{ProjectorName: "flaky", Func: func(ctx context.Context, change NoteChange) error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("throttled")
+		}
+		return nil
+	}})
+
+	if _, err := d.Handle(context.Background(), loadEvent(t, "notes-modify.json")); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	if len(sink.letters) != 0 {
+		t.Errorf("unexpected dead letters: %v", sink.letters)
+	}
+}
+
+func TestDispatcherReportsFailureWhenDeadLetterFails(t *testing.T) {
+	d := newTestDispatcher(&recordingSink{err: errors.New("table unavailable")})
+	d.Register(ProjectorFunc{ProjectorName: "broken", Func: func(ctx context.Context, change NoteChange) error {
+		return errors.New("boom")
+	}})
+
+	response, err := d.Handle(context.Background(), loadEvent(t, "notes-remove.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != "111100000000000000003" {
+		t.Errorf("BatchItemFailures = %v", response.BatchItemFailures)
+	}
+}
+
+func TestDispatcherRetriesUndecryptableImages(t *testing.T) {
+	// The title is encrypted but the keyring is unavailable, as when KMS
+	// cannot be reached
+	event := loadEvent(t, "notes-insert.json")
+	event.Records[0].Change.NewImage["title"] = events.NewStringAttribute("enc1:v1:d3JhcHBlZA:c2VhbGVk")
+
+	sink := &recordingSink{}
+	d := newTestDispatcher(sink)
+	d.Register(ProjectorFunc{ProjectorName: "search", Func: func(ctx context.Context, change NoteChange) error {
+		t.Error("projector ran on a note that did not decrypt")
+		return nil
+	}})
+
+	response, err := d.Handle(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != "111100000000000000001" {
+		t.Errorf("BatchItemFailures = %v", response.BatchItemFailures)
+	}
+	if len(sink.letters) != 0 {
+		t.Errorf("undecryptable record was dead-lettered: %v", sink.letters)
+	}
+}
+
+func TestDispatcherStopsAtFirstFailure(t *testing.T) {
+	// A batch of three changes where the second can neither be projected nor
+	// dead-lettered
+	var event events.DynamoDBEvent
+	for _, fixture := range []string{"notes-insert.json", "notes-modify.json", "notes-remove.json"} {
+		event.Records = append(event.Records, loadEvent(t, fixture).Records...)
+	}
+
+	var projected []string
+	d := newTestDispatcher(&recordingSink{err: errors.New("table unavailable")})
+	d.Register(ProjectorFunc{ProjectorName: "reminders", Func: func(ctx context.Context, change NoteChange) error {
+		if change.EventName == EventModify {
+			return errors.New("boom")
+		}
+		projected = append(projected, change.EventName)
+		return nil
+	}})
+
+	response, err := d.Handle(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The removal after the failure must wait for the modification
+	if len(projected) != 1 || projected[0] != EventInsert {
+		t.Errorf("projected %v, want only the insert", projected)
+	}
+	var failed []string
+	for _, failure := range response.BatchItemFailures {
+		failed = append(failed, failure.ItemIdentifier)
+	}
+	want := []string{"111100000000000000002", "111100000000000000003"}
+	if len(failed) != len(want) || failed[0] != want[0] || failed[1] != want[1] {
+		t.Errorf("BatchItemFailures = %v, want %v", failed, want)
+	}
+}
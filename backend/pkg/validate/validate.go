@@ -0,0 +1,332 @@
+// Package validate decodes request bodies strictly and checks them against
+// declarative rules in struct tags:
+//
+//	Title string `json:"title" validate:"required,max=500"`
+//
+// Rules are separated by commas:
+//
+//	required      not empty, and not only whitespace for strings
+//	min=N, max=N  length in characters of a string, elements of a slice or
+//	              value of a number
+//	maxbytes=N    length in bytes of a string
+//	oneof=A B     one of the space separated values
+//	format=F      email, uuid, rfc3339 or base64
+//
+// Every rule but required passes for empty values, so that optional fields
+// are only checked when set. Nested structs, pointers to structs and slices of
+// structs are checked too, a tag of "-" skips a field.
+package validate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// MaxBodyBytes caps the request bodies Decode accepts
+const MaxBodyBytes = 1 << 20
+
+// Error codes, returned to clients in the code field
+const (
+	CodeValidationFailed = "validation_failed"
+	CodeBodyTooLarge     = "body_too_large"
+	CodeInvalidJSON      = "invalid_json"
+)
+
+// ErrBodyTooLarge is returned by Decode for a body over MaxBodyBytes
+var ErrBodyTooLarge = fmt.Errorf("request body cannot be larger than %d bytes", MaxBodyBytes)
+
+// Error lists every rule a value breaks
+type Error struct {
+	Errors []models.FieldError
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fieldErr.Field + " " + fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Decode decodes a JSON request body into v and checks it with Struct. It
+// rejects bodies over MaxBodyBytes, unknown fields and trailing data.
+func Decode(body string, v interface{}) error {
+	if len(body) > MaxBodyBytes {
+		return ErrBodyTooLarge
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("request body must hold a single JSON value")
+	}
+
+	return Struct(v)
+}
+
+// decodeError turns unknown fields and values of the wrong type into field
+// errors, other decoding errors mean the body is not JSON
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &Error{Errors: []models.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: "must be " + typeName(typeErr.Type),
+		}}}
+	}
+
+	// encoding/json has no error type for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field, _ = strconv.Unquote(field)
+		return &Error{Errors: []models.FieldError{{
+			Field:   field,
+			Rule:    "unknown",
+			Message: "is not a known field",
+		}}}
+	}
+
+	if errors.Is(err, io.EOF) {
+		return errors.New("request body is empty")
+	}
+	return err
+}
+
+// typeName names a Go type the way a JSON client knows it
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// Struct checks a struct, or a pointer to one, against the rules in its
+// validate tags. It returns an *Error listing every broken rule.
+func Struct(v interface{}) error {
+	var fieldErrs []models.FieldError
+	checkStruct(reflect.ValueOf(v), "", &fieldErrs)
+	if len(fieldErrs) > 0 {
+		return &Error{Errors: fieldErrs}
+	}
+	return nil
+}
+
+// checkStruct checks the fields of a struct, path is the JSON path of the
+// struct itself
+func checkStruct(value reflect.Value, path string, fieldErrs *[]models.FieldError) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("validate")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+
+		fieldValue := value.Field(i)
+		if tag != "" {
+			for _, rule := range strings.Split(tag, ",") {
+				if message := check(fieldValue, rule); message != "" {
+					ruleName, _, _ := strings.Cut(rule, "=")
+					*fieldErrs = append(*fieldErrs, models.FieldError{Field: name, Rule: ruleName, Message: message})
+				}
+			}
+		}
+		checkNested(fieldValue, name, fieldErrs)
+	}
+}
+
+// checkNested checks the structs a field holds
+func checkNested(value reflect.Value, path string, fieldErrs *[]models.FieldError) {
+	switch value.Kind() {
+	case reflect.Struct, reflect.Pointer:
+		checkStruct(value, path, fieldErrs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			checkStruct(value.Index(i), fmt.Sprintf("%s[%d]", path, i), fieldErrs)
+		}
+	}
+}
+
+// jsonName is the name of a field in JSON
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// check checks a value against one rule and returns what is wrong with it,
+// or an empty string
+func check(value reflect.Value, rule string) string {
+	name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+	if name == "required" {
+		if isEmpty(value) {
+			return "is required"
+		}
+		return ""
+	}
+	if isEmpty(value) {
+		return ""
+	}
+	for value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: %s needs a number, not %q", name, param))
+		}
+		size, unit := measure(value)
+		if name == "min" && size < limit {
+			return fmt.Sprintf("must be at least %s%s", param, unit)
+		}
+		if name == "max" && size > limit {
+			return fmt.Sprintf("must be at most %s%s", param, unit)
+		}
+	case "maxbytes":
+		limit, err := strconv.Atoi(param)
+		if err != nil {
+			panic(fmt.Sprintf("validate: maxbytes needs a number, not %q", param))
+		}
+		if len(value.String()) > limit {
+			return fmt.Sprintf("must be at most %d bytes", limit)
+		}
+	case "oneof":
+		allowed := strings.Fields(param)
+		for _, option := range allowed {
+			if value.String() == option {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(allowed, ", ")
+	case "format":
+		return checkFormat(value.String(), param)
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q", name))
+	}
+	return ""
+}
+
+// isEmpty reports whether a value is missing: a zero value, an empty slice
+// or a string of whitespace
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+// measure returns the size min and max compare with, and its unit
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), " elements"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	default:
+		panic(fmt.Sprintf("validate: cannot measure a %s", value.Kind()))
+	}
+}
+
+// checkFormat checks a string against a named format
+func checkFormat(value string, format string) string {
+	switch format {
+	case "email":
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Address != value || !strings.Contains(value, "@") {
+			return "must be an email address"
+		}
+	case "uuid":
+		if _, err := uuid.Parse(value); err != nil {
+			return "must be a UUID"
+		}
+	case "rfc3339":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return "must be an RFC3339 time"
+		}
+	case "base64":
+		if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+			return "must be base64"
+		}
+	default:
+		panic(fmt.Sprintf("validate: unknown format %q", format))
+	}
+	return ""
+}
+
+// Failure maps an error of Decode or Struct to a status code and response:
+// 422 with every field error, 413 for a body over the cap and 400 for a body
+// that is not JSON
+func Failure(err error) (int, models.APIResponse) {
+	var validationErr *Error
+	switch {
+	case errors.As(err, &validationErr):
+		return 422, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + validationErr.Error(),
+			Code:    CodeValidationFailed,
+			Errors:  validationErr.Errors,
+		}
+	case errors.Is(err, ErrBodyTooLarge):
+		return 413, models.APIResponse{Success: false, Message: err.Error(), Code: CodeBodyTooLarge}
+	default:
+		return 400, models.APIResponse{Success: false, Message: "Invalid request: " + err.Error(), Code: CodeInvalidJSON}
+	}
+}
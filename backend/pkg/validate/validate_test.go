@@ -0,0 +1,116 @@
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// fields returns the field and rule of every error, in order
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+
+	var validationErr *Error
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got error %v, want *Error", err)
+	}
+	var got []string
+	for _, fieldErr := range validationErr.Errors {
+		got = append(got, fieldErr.Field+":"+fieldErr.Rule)
+	}
+	return got
+}
+
+func TestDecodeNote(t *testing.T) {
+	var note models.Note
+	err := Decode(`{"title":"Groceries","content":"Milk","format":"markdown","remindAt":"2030-01-02T15:04:05Z"}`, &note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Title != "Groceries" || note.Format != "markdown" {
+		t.Errorf("decoded %+v", note)
+	}
+}
+
+func TestDecodeListsEveryError(t *testing.T) {
+	var note models.Note
+	err := Decode(`{
+		"title": "  ",
+		"format": "html",
+		"type": "checklist",
+		"remindAt": "tomorrow",
+		"items": [{"text": "Milk"}, {"text": ""}],
+		"encrypted": {"algorithm": "AES-256-GCM", "keyId": "my-key", "nonce": "", "ciphertext": "AAAA"}
+	}`, &note)
+
+	got := strings.Join(fields(t, err), " ")
+	want := "title:required format:oneof items[1].text:required remindAt:format encrypted.keyId:format encrypted.nonce:required"
+	if got != want {
+		t.Errorf("got errors %s\nwant %s", got, want)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+	}{
+		{"unknown field", `{"email":"a@example.com","password":"secret123","admin":true}`, 422, "admin"},
+		{"wrong type", `{"email":"a@example.com","password":12345678}`, 422, "password"},
+		{"not JSON", `{"email":`, 400, ""},
+		{"empty", ``, 400, ""},
+		{"trailing data", `{"email":"a@example.com","password":"secret123"} {}`, 400, ""},
+		{"too large", `{"email":"` + strings.Repeat("a", MaxBodyBytes) + `"}`, 413, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var registration models.UserRegistration
+			err := Decode(tt.body, &registration)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+
+			status, response := Failure(err)
+			if status != tt.status {
+				t.Errorf("got status %d, want %d", status, tt.status)
+			}
+			if tt.field != "" && (len(response.Errors) != 1 || response.Errors[0].Field != tt.field) {
+				t.Errorf("got errors %+v, want one for %s", response.Errors, tt.field)
+			}
+		})
+	}
+}
+
+func TestRegistrationRules(t *testing.T) {
+	tests := []struct {
+		name         string
+		registration models.UserRegistration
+		want         []string
+	}{
+		{"valid", models.UserRegistration{Email: "ada@example.com", Password: "correct horse"}, nil},
+		{"missing", models.UserRegistration{}, []string{"email:required", "password:required"}},
+		{"not an email", models.UserRegistration{Email: "ada", Password: "correct horse"}, []string{"email:format"}},
+		{"short password", models.UserRegistration{Email: "ada@example.com", Password: "short"}, []string{"password:min"}},
+		{"long password", models.UserRegistration{Email: "ada@example.com", Password: strings.Repeat("é", 40)}, []string{"password:maxbytes"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Struct(tt.registration)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				return
+			}
+			if got := strings.Join(fields(t, err), " "); got != strings.Join(tt.want, " ") {
+				t.Errorf("got errors %s, want %s", got, strings.Join(tt.want, " "))
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/omidiyanto/mino/pkg/db"
+	"github.com/omidiyanto/mino/pkg/logging"
+	"github.com/omidiyanto/mino/pkg/models"
+)
+
+// Events a webhook can subscribe to
+const (
+	EventNoteCreated = "note.created"
+	EventNoteUpdated = "note.updated"
+	EventNoteDeleted = "note.deleted"
+)
+
+// Events lists every event in a stable order
+var Events = []string{EventNoteCreated, EventNoteUpdated, EventNoteDeleted}
+
+// Delivery settings
+const (
+	MaxAttempts          = 8                // Attempts per delivery before it fails for good
+	DisableAfterFailures = 20               // Consecutive failed attempts after which a webhook is disabled
+	BaseBackoff          = 30 * time.Second // Delay before the first retry, doubled on each retry
+	MaxBackoff           = 6 * time.Hour
+	Lease                = 2 * time.Minute // How long an attempt may take before another dispatcher retries it
+	Timeout              = 10 * time.Second
+	HistoryRetention     = 30 * 24 * time.Hour
+)
+
+// Request headers sent with every delivery
+const (
+	HeaderEvent     = "X-MiNo-Event"
+	HeaderDelivery  = "X-MiNo-Delivery"
+	HeaderTimestamp = "X-MiNo-Timestamp"
+	HeaderSignature = "X-MiNo-Signature"
+)
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	DeliveryID string       `json:"deliveryId"`
+	Event      string       `json:"event"`
+	OccurredAt string       `json:"occurredAt"`
+	Note       *models.Note `json:"note"` // Last state of the note for note.deleted
+}
+
+// ValidEvent reports whether event is a known event name
+func ValidEvent(event string) bool {
+	for _, known := range Events {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeEvents checks a list of subscribed events and drops duplicates,
+// which a DynamoDB string set does not accept
+func NormalizeEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("at least one event is required")
+	}
+
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if !ValidEvent(event) {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			normalized = append(normalized, event)
+		}
+	}
+	return normalized, nil
+}
+
+// ErrForbiddenAddress is returned when a delivery would connect to an
+// address that is not on the public internet
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// nonPublic are the special-purpose ranges deliveries may not reach besides
+// the private, loopback, link-local and multicast ones
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // This network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which maps IPv4 addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"), // 6to4, which maps IPv4 addresses
+}
+
+// PublicAddr reports whether a delivery may connect to an IP address
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublic {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidURL reports whether rawURL is an absolute http or https URL without
+// credentials, whose host is not an IP address or name that is obviously not
+// public. Names are only resolved when a delivery connects.
+func ValidURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return PublicAddr(addr)
+	}
+	return true
+}
+
+// Subscribed reports whether a webhook wants an event
+func Subscribed(webhook models.Webhook, event string) bool {
+	for _, subscribed := range webhook.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign computes the signature of a delivery: the hex HMAC-SHA256, keyed with
+// the webhook secret, of the timestamp, a dot and the body. Receivers compare
+// it to the X-MiNo-Signature header after the "sha256=" prefix and should
+// reject old timestamps to prevent replays.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay after a failed attempt
+func Backoff(attempts int) time.Duration {
+	delay := BaseBackoff
+	for i := 1; i < attempts && delay < MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > MaxBackoff {
+		delay = MaxBackoff
+	}
+	return delay
+}
+
+// NewDelivery builds the pending delivery of an event to a webhook. The
+// delivery ID is derived from the stream event so that a replayed record
+// does not create a second delivery.
+func NewDelivery(webhook models.Webhook, eventID string, event string, note models.Note, now time.Time) (*models.WebhookDelivery, error) {
+	deliveryID := eventID + "-" + webhook.WebhookID
+	body, err := json.Marshal(Payload{
+		DeliveryID: deliveryID,
+		Event:      event,
+		OccurredAt: now.UTC().Format(time.RFC3339),
+		Note:       &note,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookDelivery{
+		WebhookID:     webhook.WebhookID,
+		DeliveryID:    deliveryID,
+		UserID:        webhook.UserID,
+		Event:         event,
+		NoteID:        note.NoteID,
+		Payload:       string(body),
+		NextAttemptAt: now.UTC().Format(time.RFC3339),
+		CreatedAt:     now.UTC().Format(time.RFC3339),
+		TTL:           now.Add(HistoryRetention).Unix(),
+	}, nil
+}
+
+// Client sends deliveries over HTTP. It only connects to public addresses,
+// checked on the address actually dialled so that a name resolving to a
+// private address, at any time, is refused. Proxies are not used and
+// redirects are not followed, a redirect fails the attempt.
+var Client = &http.Client{
+	Timeout: Timeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: Timeout,
+			Control: dialControl,
+		}).DialContext,
+		TLSHandshakeTimeout: Timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(request *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// dialControl refuses connections to addresses that are not public
+func dialControl(network string, address string, conn syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// Deliver makes one attempt at a claimed delivery and records the outcome:
+// the delivery succeeds, is scheduled for a retry with exponential backoff,
+// or fails for good after MaxAttempts. Failed attempts count towards
+// disabling the webhook. The returned error only reports storage failures.
+func Deliver(ctx context.Context, webhook models.Webhook, delivery *models.WebhookDelivery) error {
+	now := time.Now().UTC()
+	delivery.Attempts++
+
+	if !webhook.Active {
+		delivery.Status = db.DeliveryFailed
+		delivery.LastError = "webhook is disabled"
+		delivery.CompletedAt = now.Format(time.RFC3339)
+		return db.SaveDelivery(delivery)
+	}
+
+	statusCode, sendErr := send(ctx, webhook, delivery, now)
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	if sendErr == nil {
+		delivery.Status = db.DeliverySucceeded
+		delivery.CompletedAt = now.Format(time.RFC3339)
+		if err := db.SaveDelivery(delivery); err != nil {
+			return err
+		}
+		return db.RecordWebhookSuccess(webhook.WebhookID, webhook.UserID)
+	}
+
+	delivery.LastError = sendErr.Error()
+	if delivery.Attempts >= MaxAttempts {
+		delivery.Status = db.DeliveryFailed
+		delivery.CompletedAt = now.Format(time.RFC3339)
+	} else {
+		delivery.Status = db.DeliveryPending
+		delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts)).Format(time.RFC3339)
+	}
+	if err := db.SaveDelivery(delivery); err != nil {
+		return err
+	}
+
+	disabled, err := db.RecordWebhookFailure(webhook.WebhookID, webhook.UserID, DisableAfterFailures)
+	if disabled {
+		logging.FromContext(ctx).Warn("webhook disabled", "webhookId", webhook.WebhookID, "failuresInARow", DisableAfterFailures)
+	}
+	return err
+}
+
+// send posts the signed payload, any status other than 2xx is an error
+func send(ctx context.Context, webhook models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "MiNo-Webhooks/1.0")
+	request.Header.Set(HeaderEvent, delivery.Event)
+	request.Header.Set(HeaderDelivery, delivery.DeliveryID)
+	request.Header.Set(HeaderTimestamp, timestamp)
+	request.Header.Set(HeaderSignature, "sha256="+Sign(webhook.Secret, timestamp, body))
+
+	response, err := Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook answered %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
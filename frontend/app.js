@@ -3,10 +3,13 @@
 // Configuration
 const API_ID = 'tunulj4rcl';
 const API_URL = 'http://192.168.0.250:4566/restapis/'+API_ID+'/dev/_user_request_/'; // LocalStack API URL format
+const WS_URL = ''; // WebSocket endpoint for live updates, set by mino.sh
 let currentUser = null;
 let userToken = null;
 let notes = [];
 let currentNoteId = null;
+let socket = null;
+let socketRetryDelay = 1000;
 
 // DOM Elements
 const authContainer = document.getElementById('auth-container');
@@ -121,6 +124,51 @@ async function showNotesSection() {
     notesContainer.classList.remove('hidden');
     updateAuthActions(true);
     await fetchNotes(); // Use await to ensure notes are loaded before continuing
+    connectRealtime();
+}
+
+// Connect to the WebSocket API so that changes made in other tabs and devices show up
+function connectRealtime() {
+    if (!WS_URL || !userToken || socket) {
+        return;
+    }
+
+    // The token goes in the subprotocol header, never in the URL
+    socket = new WebSocket(WS_URL, ['mino', userToken]);
+
+    socket.addEventListener('open', () => {
+        socketRetryDelay = 1000;
+    });
+
+    socket.addEventListener('message', async (event) => {
+        let message;
+        try {
+            message = JSON.parse(event.data);
+        } catch (error) {
+            return;
+        }
+        if (message.type && message.type.startsWith('note.')) {
+            await fetchNotes();
+        }
+    });
+
+    socket.addEventListener('close', () => {
+        socket = null;
+        // Reconnect with a growing delay while the user is logged in
+        if (userToken) {
+            setTimeout(connectRealtime, socketRetryDelay);
+            socketRetryDelay = Math.min(socketRetryDelay * 2, 60000);
+        }
+    });
+}
+
+// Close the WebSocket connection
+function disconnectRealtime() {
+    if (socket) {
+        const closing = socket;
+        socket = null;
+        closing.close();
+    }
 }
 
 // Update auth actions in header
@@ -221,6 +269,7 @@ async function handleRegister(e) {
 // Handle logout
 function handleLogout() {
     userToken = null;
+    disconnectRealtime();
     currentUser = null;
     notes = [];
     localStorage.removeItem('token');
@@ -260,7 +309,7 @@ async function fetchNotes() {
     if (!userToken) return;
     
     try {
-        const response = await fetch(`${API_URL}notes`, {
+        const response = await fetch(`${API_URL}notes?render=html`, {
             headers: {
                 'Authorization': `Bearer ${userToken}`
             }
@@ -280,6 +329,14 @@ async function fetchNotes() {
     }
 }
 
+// Body of a note card, encrypted notes can only be read in a client holding the key
+function noteBodyHtml(note) {
+    if (note.encrypted) {
+        return '<i class="fas fa-lock mr-1"></i>Encrypted note';
+    }
+    return note.contentHtml !== undefined ? note.contentHtml : escapeHtml(note.content);
+}
+
 // Render notes in grid
 function renderNotes() {
     if (notes.length === 0) {
@@ -294,13 +351,13 @@ function renderNotes() {
     notesGrid.innerHTML = notes.map(note => `
         <div class="note-card bg-gray-800 rounded-lg border border-gray-700 p-4 shadow-md overflow-hidden">
             <h3 class="text-lg font-medium mb-2 text-white">${escapeHtml(note.title)}</h3>
-            <p class="text-gray-400 text-sm mb-4 note-content">${escapeHtml(note.content)}</p>
+            <div class="text-gray-400 text-sm mb-4 note-content">${noteBodyHtml(note)}</div>
             <div class="flex justify-between items-center border-t border-gray-700 pt-3">
                 <span class="text-gray-500 text-xs">${formatDate(note.updatedAt)}</span>
                 <div class="note-actions flex gap-2">
-                    <button class="edit-note text-gray-400 hover:text-white" data-id="${note.noteId}">
+                    ${note.encrypted ? '' : `<button class="edit-note text-gray-400 hover:text-white" data-id="${note.noteId}">
                         <i class="fas fa-edit"></i>
-                    </button>
+                    </button>`}
                     <button class="delete-note text-gray-400 hover:text-red-400" data-id="${note.noteId}">
                         <i class="fas fa-trash-alt"></i>
                     </button>
@@ -340,6 +397,9 @@ function showNoteEditor(noteId = null) {
                 '<div class="mb-4">' +
                 '<label for="swal-note-content" class="block text-left mb-1">Content</label>' +
                 `<textarea id="swal-note-content" rows="8" class="w-full px-4 py-2 rounded-lg resize-none bg-gray-700 text-white border border-gray-600">${escapeHtml(note.content)}</textarea>` +
+                '</div>' +
+                '<div class="mb-4 text-left">' +
+                `<label><input id="swal-note-markdown" type="checkbox" class="mr-2"${note.format === 'markdown' ? ' checked' : ''}>Markdown</label>` +
                 '</div>',
             focusConfirm: false,
             showCancelButton: true,
@@ -348,16 +408,17 @@ function showNoteEditor(noteId = null) {
             preConfirm: () => {
                 const title = document.getElementById('swal-note-title').value;
                 const content = document.getElementById('swal-note-content').value;
+                const format = document.getElementById('swal-note-markdown').checked ? 'markdown' : 'plain';
                 if (!title) {
                     Swal.showValidationMessage('Title is required');
                     return false;
                 }
-                return { title, content };
+                return { title, content, format };
             }
         }).then((result) => {
             if (result.isConfirmed) {
-                const { title, content } = result.value;
-                saveNote(title, content, noteId);
+                const { title, content, format } = result.value;
+                saveNote(title, content, format, noteId);
             }
         });
     } else {
@@ -372,6 +433,9 @@ function showNoteEditor(noteId = null) {
                 '<div class="mb-4">' +
                 '<label for="swal-note-content" class="block text-left mb-1">Content</label>' +
                 '<textarea id="swal-note-content" rows="8" class="w-full px-4 py-2 rounded-lg resize-none bg-gray-700 text-white border border-gray-600"></textarea>' +
+                '</div>' +
+                '<div class="mb-4 text-left">' +
+                '<label><input id="swal-note-markdown" type="checkbox" class="mr-2">Markdown</label>' +
                 '</div>',
             focusConfirm: false,
             showCancelButton: true,
@@ -380,16 +444,17 @@ function showNoteEditor(noteId = null) {
             preConfirm: () => {
                 const title = document.getElementById('swal-note-title').value;
                 const content = document.getElementById('swal-note-content').value;
+                const format = document.getElementById('swal-note-markdown').checked ? 'markdown' : 'plain';
                 if (!title) {
                     Swal.showValidationMessage('Title is required');
                     return false;
                 }
-                return { title, content };
+                return { title, content, format };
             }
         }).then((result) => {
             if (result.isConfirmed) {
-                const { title, content } = result.value;
-                saveNote(title, content);
+                const { title, content, format } = result.value;
+                saveNote(title, content, format);
             }
         });
     }
@@ -490,7 +555,7 @@ async function deleteNote(noteId) {
 }
 
 // Save note function for SweetAlert2
-async function saveNote(title, content, noteId = null) {
+async function saveNote(title, content, format = '', noteId = null) {
     const isNewNote = !noteId;
     
     try {
@@ -507,7 +572,7 @@ async function saveNote(title, content, noteId = null) {
         console.log('Saving note to URL:', url);
         console.log('Method:', method);
         console.log('Token:', userToken);
-        console.log('Data:', { title, content });
+        console.log('Data:', { title, content, format });
         
         // Show loading indicator
         Swal.fire({
@@ -526,7 +591,7 @@ async function saveNote(title, content, noteId = null) {
                 'Content-Type': 'application/json',
                 'Authorization': `Bearer ${userToken}`
             },
-            body: JSON.stringify({ title, content })
+            body: JSON.stringify({ title, content, format })
         });
         
         console.log('Response status:', response.status);
@@ -586,7 +651,7 @@ async function handleSaveNote(e) {
     const content = noteContent.value;
     const noteId = noteIdField.value;
     
-    await saveNote(title, content, noteId);
+    await saveNote(title, content, '', noteId);
     hideNoteEditor();
 }
 
@@ -14,12 +14,16 @@ provider "aws" {
   
   # LocalStack configuration
   endpoints {
-    apigateway     = "http://192.168.0.250:4566"
-    dynamodb       = "http://192.168.0.250:4566"
-    lambda         = "http://192.168.0.250:4566"
-    s3             = "http://192.168.0.250:4566"
-    iam            = "http://192.168.0.250:4566"
-    cloudwatchlogs = "http://192.168.0.250:4566"
+    apigateway       = "http://192.168.0.250:4566"
+    apigatewayv2     = "http://192.168.0.250:4566"
+    dynamodb         = "http://192.168.0.250:4566"
+    dynamodbstreams  = "http://192.168.0.250:4566"
+    lambda           = "http://192.168.0.250:4566"
+    s3               = "http://192.168.0.250:4566"
+    iam              = "http://192.168.0.250:4566"
+    kms              = "http://192.168.0.250:4566"
+    cloudwatchlogs   = "http://192.168.0.250:4566"
+    cloudwatchevents = "http://192.168.0.250:4566"
   }
   
   skip_credentials_validation = true
@@ -38,15 +42,68 @@ module "dynamodb" {
   source = "./modules/dynamodb"
 }
 
+module "kms" {
+  source = "./modules/kms"
+}
+
 module "lambda" {
   source = "./modules/lambda"
-  depends_on = [module.dynamodb]
+  notes_stream_arn = module.dynamodb.notes_stream_arn
+  log_level        = var.log_level
+  depends_on = [module.dynamodb, module.kms]
+}
+
+# "functions" deploys a Lambda function per REST route, "router" sends every
+# route to the single mino_api function
+variable "api_mode" {
+  type    = string
+  default = "functions"
+
+  validation {
+    condition     = contains(["functions", "router"], var.api_mode)
+    error_message = "api_mode must be functions or router."
+  }
+}
+
+# Level of the Lambda logs, debug also logs the headers and query of every
+# request, with credentials redacted
+variable "log_level" {
+  type    = string
+  default = "info"
+
+  validation {
+    condition     = contains(["debug", "info", "warn", "error"], var.log_level)
+    error_message = "log_level must be debug, info, warn or error."
+  }
+}
+
+locals {
+  rest_routes = [
+    "auth", "register", "get_notes", "create_note", "update_note", "delete_note",
+    "get_notebooks", "create_notebook", "update_notebook", "delete_notebook",
+    "move_note", "update_note_flags", "search_notes", "patch_note", "get_note",
+    "share_note", "get_note_shares", "revoke_share", "get_shared_notes",
+    "create_share_link", "get_share_links", "revoke_share_link", "get_public_note",
+    "add_checklist_item", "update_checklist_item", "reorder_checklist_items",
+    "remove_checklist_item", "create_webhook", "get_webhooks", "update_webhook",
+    "delete_webhook", "get_webhook_deliveries", "pull_changes", "push_changes",
+    "create_attachment", "get_attachments", "delete_attachment", "create_key",
+    "get_keys", "update_key", "get_openapi",
+  ]
+
+  api_invoke_arns = var.api_mode == "router" ? merge(module.lambda.lambda_invoke_arns, {
+    for route in local.rest_routes : route => module.lambda.lambda_invoke_arns["api"]
+  }) : module.lambda.lambda_invoke_arns
+
+  api_function_names = var.api_mode == "router" ? merge(module.lambda.lambda_function_names, {
+    for route in local.rest_routes : route => module.lambda.lambda_function_names["api"]
+  }) : module.lambda.lambda_function_names
 }
 
 module "apigateway" {
   source = "./modules/apigateway"
-  lambda_invoke_arns = module.lambda.lambda_invoke_arns
-  lambda_function_names = module.lambda.lambda_function_names
+  lambda_invoke_arns = local.api_invoke_arns
+  lambda_function_names = local.api_function_names
 }
 
 # Output the frontend URL
@@ -57,4 +114,9 @@ output "frontend_url" {
 # Output the API Gateway endpoint
 output "api_endpoint" {
   value = module.apigateway.api_endpoint
-} 
\ No newline at end of file
+}
+
+# Output the WebSocket endpoint
+output "websocket_endpoint" {
+  value = module.apigateway.websocket_endpoint
+}
@@ -56,8 +56,8 @@ resource "aws_api_gateway_integration_response" "cors_integration_response" {
   status_code = aws_api_gateway_method_response.cors_response.status_code
   
   response_parameters = {
-    "method.response.header.Access-Control-Allow-Headers" = "'Content-Type,Authorization,X-Amz-Date,X-Api-Key'"
-    "method.response.header.Access-Control-Allow-Methods" = "'GET,POST,PUT,DELETE,OPTIONS'"
+    "method.response.header.Access-Control-Allow-Headers" = "'Content-Type,Authorization,X-Amz-Date,X-Api-Key,If-None-Match,If-Modified-Since,X-Share-Password,X-Request-ID'"
+    "method.response.header.Access-Control-Allow-Methods" = "'GET,POST,PUT,PATCH,DELETE,OPTIONS'"
     "method.response.header.Access-Control-Allow-Origin"  = "'*'"
   }
 
@@ -219,6 +219,908 @@ resource "aws_api_gateway_integration" "delete_note_lambda" {
   ]
 }
 
+# Notebooks endpoints
+resource "aws_api_gateway_resource" "notebooks" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_rest_api.mino_api.root_resource_id
+  path_part   = "notebooks"
+}
+
+# GET /notebooks - Get all notebooks
+resource "aws_api_gateway_method" "get_notebooks" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.notebooks.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "get_notebooks_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.notebooks.id
+  http_method             = aws_api_gateway_method.get_notebooks.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_notebooks"]
+  
+  depends_on = [
+    aws_api_gateway_method.get_notebooks
+  ]
+}
+
+# POST /notebooks - Create a notebook
+resource "aws_api_gateway_method" "create_notebook" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.notebooks.id
+  http_method   = "POST"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "create_notebook_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.notebooks.id
+  http_method             = aws_api_gateway_method.create_notebook.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["create_notebook"]
+  
+  depends_on = [
+    aws_api_gateway_method.create_notebook
+  ]
+}
+
+# Notebook resource with ID
+resource "aws_api_gateway_resource" "notebook" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.notebooks.id
+  path_part   = "{notebookId}"
+}
+
+# PUT /notebooks/{notebookId} - Rename a notebook
+resource "aws_api_gateway_method" "update_notebook" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.notebook.id
+  http_method   = "PUT"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "update_notebook_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.notebook.id
+  http_method             = aws_api_gateway_method.update_notebook.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["update_notebook"]
+  
+  depends_on = [
+    aws_api_gateway_method.update_notebook
+  ]
+}
+
+# DELETE /notebooks/{notebookId} - Delete a notebook
+resource "aws_api_gateway_method" "delete_notebook" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.notebook.id
+  http_method   = "DELETE"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "delete_notebook_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.notebook.id
+  http_method             = aws_api_gateway_method.delete_notebook.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["delete_notebook"]
+  
+  depends_on = [
+    aws_api_gateway_method.delete_notebook
+  ]
+}
+
+# Notebook of a single note
+resource "aws_api_gateway_resource" "note_notebook" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note.id
+  path_part   = "notebook"
+}
+
+# PUT /notes/{noteId}/notebook - Move a note to another notebook
+resource "aws_api_gateway_method" "move_note" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_notebook.id
+  http_method   = "PUT"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "move_note_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_notebook.id
+  http_method             = aws_api_gateway_method.move_note.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["move_note"]
+  
+  depends_on = [
+    aws_api_gateway_method.move_note
+  ]
+}
+
+# Flags of a single note
+resource "aws_api_gateway_resource" "note_flags" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note.id
+  path_part   = "flags"
+}
+
+# PUT /notes/{noteId}/flags - Pin, archive or favorite a note
+resource "aws_api_gateway_method" "update_note_flags" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_flags.id
+  http_method   = "PUT"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "update_note_flags_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_flags.id
+  http_method             = aws_api_gateway_method.update_note_flags.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["update_note_flags"]
+  
+  depends_on = [
+    aws_api_gateway_method.update_note_flags
+  ]
+}
+
+# Search endpoint
+resource "aws_api_gateway_resource" "notes_search" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.notes.id
+  path_part   = "search"
+}
+
+# GET /notes/search - Full-text search across notes
+resource "aws_api_gateway_method" "search_notes" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.notes_search.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "search_notes_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.notes_search.id
+  http_method             = aws_api_gateway_method.search_notes.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["search_notes"]
+  
+  depends_on = [
+    aws_api_gateway_method.search_notes
+  ]
+}
+
+# PATCH /notes/{noteId} - Partially update a note
+resource "aws_api_gateway_method" "patch_note" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note.id
+  http_method   = "PATCH"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "patch_note_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note.id
+  http_method             = aws_api_gateway_method.patch_note.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["patch_note"]
+  
+  depends_on = [
+    aws_api_gateway_method.patch_note
+  ]
+}
+
+# GET /notes/{noteId} - Get a single note
+resource "aws_api_gateway_method" "get_note" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "get_note_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note.id
+  http_method             = aws_api_gateway_method.get_note.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_note"]
+  
+  depends_on = [
+    aws_api_gateway_method.get_note
+  ]
+}
+
+# Notes shared with the caller
+resource "aws_api_gateway_resource" "notes_shared_with_me" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.notes.id
+  path_part   = "shared-with-me"
+}
+
+# GET /notes/shared-with-me - List notes shared with the user
+resource "aws_api_gateway_method" "get_shared_notes" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.notes_shared_with_me.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "get_shared_notes_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.notes_shared_with_me.id
+  http_method             = aws_api_gateway_method.get_shared_notes.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_shared_notes"]
+  
+  depends_on = [
+    aws_api_gateway_method.get_shared_notes
+  ]
+}
+
+# Shares of a single note
+resource "aws_api_gateway_resource" "note_shares" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note.id
+  path_part   = "shares"
+}
+
+# POST /notes/{noteId}/shares - Share a note with another user
+resource "aws_api_gateway_method" "share_note" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_shares.id
+  http_method   = "POST"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "share_note_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_shares.id
+  http_method             = aws_api_gateway_method.share_note.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["share_note"]
+  
+  depends_on = [
+    aws_api_gateway_method.share_note
+  ]
+}
+
+# GET /notes/{noteId}/shares - List who a note is shared with
+resource "aws_api_gateway_method" "get_note_shares" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_shares.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "get_note_shares_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_shares.id
+  http_method             = aws_api_gateway_method.get_note_shares.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_note_shares"]
+  
+  depends_on = [
+    aws_api_gateway_method.get_note_shares
+  ]
+}
+
+# Share with a single user
+resource "aws_api_gateway_resource" "note_share" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note_shares.id
+  path_part   = "{userId}"
+}
+
+# DELETE /notes/{noteId}/shares/{userId} - Revoke a share
+resource "aws_api_gateway_method" "revoke_share" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_share.id
+  http_method   = "DELETE"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "revoke_share_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_share.id
+  http_method             = aws_api_gateway_method.revoke_share.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["revoke_share"]
+  
+  depends_on = [
+    aws_api_gateway_method.revoke_share
+  ]
+}
+
+# Public share links of a single note
+resource "aws_api_gateway_resource" "note_share_link" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note.id
+  path_part   = "share-link"
+}
+
+# POST /notes/{noteId}/share-link - Create a public read-only link
+resource "aws_api_gateway_method" "create_share_link" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_share_link.id
+  http_method   = "POST"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "create_share_link_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_share_link.id
+  http_method             = aws_api_gateway_method.create_share_link.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["create_share_link"]
+  
+  depends_on = [
+    aws_api_gateway_method.create_share_link
+  ]
+}
+
+# Public share links of the user
+resource "aws_api_gateway_resource" "share_links" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_rest_api.mino_api.root_resource_id
+  path_part   = "share-links"
+}
+
+# GET /share-links - List public links, optionally of one note
+resource "aws_api_gateway_method" "get_share_links" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.share_links.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "get_share_links_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.share_links.id
+  http_method             = aws_api_gateway_method.get_share_links.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_share_links"]
+  
+  depends_on = [
+    aws_api_gateway_method.get_share_links
+  ]
+}
+
+resource "aws_api_gateway_resource" "share_link" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.share_links.id
+  path_part   = "{linkId}"
+}
+
+# DELETE /share-links/{linkId} - Revoke a public link
+resource "aws_api_gateway_method" "revoke_share_link" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.share_link.id
+  http_method   = "DELETE"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "revoke_share_link_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.share_link.id
+  http_method             = aws_api_gateway_method.revoke_share_link.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["revoke_share_link"]
+  
+  depends_on = [
+    aws_api_gateway_method.revoke_share_link
+  ]
+}
+
+# Unauthenticated access to published notes
+resource "aws_api_gateway_resource" "public" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_rest_api.mino_api.root_resource_id
+  path_part   = "public"
+}
+
+resource "aws_api_gateway_resource" "public_note" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.public.id
+  path_part   = "{token}"
+}
+
+# GET /public/{token} - Read a published note without signing in
+resource "aws_api_gateway_method" "get_public_note" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.public_note.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "get_public_note_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.public_note.id
+  http_method             = aws_api_gateway_method.get_public_note.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_public_note"]
+  
+  depends_on = [
+    aws_api_gateway_method.get_public_note
+  ]
+}
+
+# Checklist items of a single note
+resource "aws_api_gateway_resource" "note_items" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note.id
+  path_part   = "items"
+}
+
+# POST /notes/{noteId}/items - Add a checklist item
+resource "aws_api_gateway_method" "add_checklist_item" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_items.id
+  http_method   = "POST"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "add_checklist_item_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_items.id
+  http_method             = aws_api_gateway_method.add_checklist_item.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["add_checklist_item"]
+  
+  depends_on = [
+    aws_api_gateway_method.add_checklist_item
+  ]
+}
+
+resource "aws_api_gateway_resource" "note_items_order" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note_items.id
+  path_part   = "order"
+}
+
+# PUT /notes/{noteId}/items/order - Reorder checklist items
+resource "aws_api_gateway_method" "reorder_checklist_items" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_items_order.id
+  http_method   = "PUT"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "reorder_checklist_items_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_items_order.id
+  http_method             = aws_api_gateway_method.reorder_checklist_items.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["reorder_checklist_items"]
+  
+  depends_on = [
+    aws_api_gateway_method.reorder_checklist_items
+  ]
+}
+
+resource "aws_api_gateway_resource" "note_item" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note_items.id
+  path_part   = "{itemId}"
+}
+
+# PUT /notes/{noteId}/items/{itemId} - Edit or toggle a checklist item
+resource "aws_api_gateway_method" "update_checklist_item" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_item.id
+  http_method   = "PUT"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "update_checklist_item_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_item.id
+  http_method             = aws_api_gateway_method.update_checklist_item.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["update_checklist_item"]
+  
+  depends_on = [
+    aws_api_gateway_method.update_checklist_item
+  ]
+}
+
+# DELETE /notes/{noteId}/items/{itemId} - Remove a checklist item
+resource "aws_api_gateway_method" "remove_checklist_item" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_item.id
+  http_method   = "DELETE"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "remove_checklist_item_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_item.id
+  http_method             = aws_api_gateway_method.remove_checklist_item.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["remove_checklist_item"]
+  
+  depends_on = [
+    aws_api_gateway_method.remove_checklist_item
+  ]
+}
+
+# Webhooks resources
+resource "aws_api_gateway_resource" "webhooks" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_rest_api.mino_api.root_resource_id
+  path_part   = "webhooks"
+}
+
+resource "aws_api_gateway_resource" "webhook" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.webhooks.id
+  path_part   = "{webhookId}"
+}
+
+resource "aws_api_gateway_resource" "webhook_deliveries" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.webhook.id
+  path_part   = "deliveries"
+}
+
+# POST /webhooks
+resource "aws_api_gateway_method" "webhooks_post" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.webhooks.id
+  http_method   = "POST"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "webhooks_post_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.webhooks.id
+  http_method             = aws_api_gateway_method.webhooks_post.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["create_webhook"]
+  
+  depends_on = [
+    aws_api_gateway_method.webhooks_post
+  ]
+}
+
+# GET /webhooks
+resource "aws_api_gateway_method" "webhooks_get" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.webhooks.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "webhooks_get_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.webhooks.id
+  http_method             = aws_api_gateway_method.webhooks_get.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_webhooks"]
+  
+  depends_on = [
+    aws_api_gateway_method.webhooks_get
+  ]
+}
+
+# PUT /webhooks/{webhookId}
+resource "aws_api_gateway_method" "webhook_put" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.webhook.id
+  http_method   = "PUT"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "webhook_put_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.webhook.id
+  http_method             = aws_api_gateway_method.webhook_put.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["update_webhook"]
+  
+  depends_on = [
+    aws_api_gateway_method.webhook_put
+  ]
+}
+
+# DELETE /webhooks/{webhookId}
+resource "aws_api_gateway_method" "webhook_delete" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.webhook.id
+  http_method   = "DELETE"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "webhook_delete_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.webhook.id
+  http_method             = aws_api_gateway_method.webhook_delete.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["delete_webhook"]
+  
+  depends_on = [
+    aws_api_gateway_method.webhook_delete
+  ]
+}
+
+# GET /webhooks/{webhookId}/deliveries
+resource "aws_api_gateway_method" "webhook_deliveries_get" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.webhook_deliveries.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "webhook_deliveries_get_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.webhook_deliveries.id
+  http_method             = aws_api_gateway_method.webhook_deliveries_get.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_webhook_deliveries"]
+  
+  depends_on = [
+    aws_api_gateway_method.webhook_deliveries_get
+  ]
+}
+
+# Sync resource
+resource "aws_api_gateway_resource" "sync" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_rest_api.mino_api.root_resource_id
+  path_part   = "sync"
+}
+
+# GET /sync
+resource "aws_api_gateway_method" "sync_get" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.sync.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "sync_get_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.sync.id
+  http_method             = aws_api_gateway_method.sync_get.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["pull_changes"]
+  
+  depends_on = [
+    aws_api_gateway_method.sync_get
+  ]
+}
+
+# POST /sync
+resource "aws_api_gateway_method" "sync_post" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.sync.id
+  http_method   = "POST"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "sync_post_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.sync.id
+  http_method             = aws_api_gateway_method.sync_post.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["push_changes"]
+  
+  depends_on = [
+    aws_api_gateway_method.sync_post
+  ]
+}
+
+# Attachments resource
+resource "aws_api_gateway_resource" "note_attachments" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note.id
+  path_part   = "attachments"
+}
+
+resource "aws_api_gateway_resource" "note_attachment" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.note_attachments.id
+  path_part   = "{attachmentId}"
+}
+
+# Create attachment method
+resource "aws_api_gateway_method" "create_attachment" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_attachments.id
+  http_method   = "POST"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "create_attachment_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_attachments.id
+  http_method             = aws_api_gateway_method.create_attachment.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["create_attachment"]
+  
+  depends_on = [
+    aws_api_gateway_method.create_attachment
+  ]
+}
+
+# Get attachments method
+resource "aws_api_gateway_method" "get_attachments" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_attachments.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "get_attachments_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_attachments.id
+  http_method             = aws_api_gateway_method.get_attachments.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_attachments"]
+  
+  depends_on = [
+    aws_api_gateway_method.get_attachments
+  ]
+}
+
+# Delete attachment method
+resource "aws_api_gateway_method" "delete_attachment" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.note_attachment.id
+  http_method   = "DELETE"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "delete_attachment_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.note_attachment.id
+  http_method             = aws_api_gateway_method.delete_attachment.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["delete_attachment"]
+  
+  depends_on = [
+    aws_api_gateway_method.delete_attachment
+  ]
+}
+
+# Encryption keys resource
+resource "aws_api_gateway_resource" "keys" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_rest_api.mino_api.root_resource_id
+  path_part   = "keys"
+}
+
+resource "aws_api_gateway_resource" "key" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_resource.keys.id
+  path_part   = "{keyId}"
+}
+
+# Create encryption key method
+resource "aws_api_gateway_method" "create_key" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.keys.id
+  http_method   = "POST"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "create_key_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.keys.id
+  http_method             = aws_api_gateway_method.create_key.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["create_key"]
+  
+  depends_on = [
+    aws_api_gateway_method.create_key
+  ]
+}
+
+# Get encryption keys method
+resource "aws_api_gateway_method" "get_keys" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.keys.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "get_keys_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.keys.id
+  http_method             = aws_api_gateway_method.get_keys.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_keys"]
+  
+  depends_on = [
+    aws_api_gateway_method.get_keys
+  ]
+}
+
+# Update encryption key method
+resource "aws_api_gateway_method" "update_key" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.key.id
+  http_method   = "PUT"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "update_key_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.key.id
+  http_method             = aws_api_gateway_method.update_key.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["update_key"]
+  
+  depends_on = [
+    aws_api_gateway_method.update_key
+  ]
+}
+
+# OpenAPI document
+resource "aws_api_gateway_resource" "openapi" {
+  rest_api_id = aws_api_gateway_rest_api.mino_api.id
+  parent_id   = aws_api_gateway_rest_api.mino_api.root_resource_id
+  path_part   = "openapi.json"
+}
+
+# Serve the OpenAPI document
+resource "aws_api_gateway_method" "get_openapi" {
+  rest_api_id   = aws_api_gateway_rest_api.mino_api.id
+  resource_id   = aws_api_gateway_resource.openapi.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_integration" "get_openapi_lambda" {
+  rest_api_id             = aws_api_gateway_rest_api.mino_api.id
+  resource_id             = aws_api_gateway_resource.openapi.id
+  http_method             = aws_api_gateway_method.get_openapi.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arns["get_openapi"]
+  
+  depends_on = [
+    aws_api_gateway_method.get_openapi
+  ]
+}
+
 # Deploy the API
 resource "aws_api_gateway_deployment" "deployment" {
   depends_on = [
@@ -228,6 +1130,41 @@ resource "aws_api_gateway_deployment" "deployment" {
     aws_api_gateway_integration.create_note_lambda,
     aws_api_gateway_integration.update_note_lambda,
     aws_api_gateway_integration.delete_note_lambda,
+    aws_api_gateway_integration.get_notebooks_lambda,
+    aws_api_gateway_integration.create_notebook_lambda,
+    aws_api_gateway_integration.update_notebook_lambda,
+    aws_api_gateway_integration.delete_notebook_lambda,
+    aws_api_gateway_integration.move_note_lambda,
+    aws_api_gateway_integration.update_note_flags_lambda,
+    aws_api_gateway_integration.search_notes_lambda,
+    aws_api_gateway_integration.patch_note_lambda,
+    aws_api_gateway_integration.get_note_lambda,
+    aws_api_gateway_integration.get_shared_notes_lambda,
+    aws_api_gateway_integration.share_note_lambda,
+    aws_api_gateway_integration.get_note_shares_lambda,
+    aws_api_gateway_integration.revoke_share_lambda,
+    aws_api_gateway_integration.create_share_link_lambda,
+    aws_api_gateway_integration.get_share_links_lambda,
+    aws_api_gateway_integration.revoke_share_link_lambda,
+    aws_api_gateway_integration.get_public_note_lambda,
+    aws_api_gateway_integration.add_checklist_item_lambda,
+    aws_api_gateway_integration.reorder_checklist_items_lambda,
+    aws_api_gateway_integration.update_checklist_item_lambda,
+    aws_api_gateway_integration.remove_checklist_item_lambda,
+    aws_api_gateway_integration.webhooks_post_lambda,
+    aws_api_gateway_integration.webhooks_get_lambda,
+    aws_api_gateway_integration.webhook_put_lambda,
+    aws_api_gateway_integration.webhook_delete_lambda,
+    aws_api_gateway_integration.webhook_deliveries_get_lambda,
+    aws_api_gateway_integration.sync_get_lambda,
+    aws_api_gateway_integration.sync_post_lambda,
+    aws_api_gateway_integration.create_attachment_lambda,
+    aws_api_gateway_integration.get_attachments_lambda,
+    aws_api_gateway_integration.delete_attachment_lambda,
+    aws_api_gateway_integration.create_key_lambda,
+    aws_api_gateway_integration.get_keys_lambda,
+    aws_api_gateway_integration.update_key_lambda,
+    aws_api_gateway_integration.get_openapi_lambda,
     aws_api_gateway_integration_response.cors_integration_response
   ]
   
@@ -241,12 +1178,72 @@ resource "aws_api_gateway_deployment" "deployment" {
       aws_api_gateway_resource.register.id,
       aws_api_gateway_resource.notes.id,
       aws_api_gateway_resource.note.id,
+      aws_api_gateway_resource.notebooks.id,
+      aws_api_gateway_resource.notebook.id,
+      aws_api_gateway_resource.note_notebook.id,
+      aws_api_gateway_resource.note_flags.id,
+      aws_api_gateway_resource.notes_search.id,
+      aws_api_gateway_resource.notes_shared_with_me.id,
+      aws_api_gateway_resource.note_shares.id,
+      aws_api_gateway_resource.note_share.id,
+      aws_api_gateway_resource.note_share_link.id,
+      aws_api_gateway_resource.share_links.id,
+      aws_api_gateway_resource.share_link.id,
+      aws_api_gateway_resource.public.id,
+      aws_api_gateway_resource.public_note.id,
+      aws_api_gateway_resource.note_items.id,
+      aws_api_gateway_resource.note_items_order.id,
+      aws_api_gateway_resource.note_item.id,
+      aws_api_gateway_resource.webhooks.id,
+      aws_api_gateway_resource.webhook.id,
+      aws_api_gateway_resource.webhook_deliveries.id,
+      aws_api_gateway_resource.sync.id,
+      aws_api_gateway_resource.note_attachments.id,
+      aws_api_gateway_resource.note_attachment.id,
+      aws_api_gateway_resource.keys.id,
+      aws_api_gateway_resource.key.id,
+      aws_api_gateway_resource.openapi.id,
       aws_api_gateway_method.auth_post.id,
       aws_api_gateway_method.register_post.id,
       aws_api_gateway_method.get_notes.id,
       aws_api_gateway_method.create_note.id,
       aws_api_gateway_method.update_note.id,
-      aws_api_gateway_method.delete_note.id
+      aws_api_gateway_method.delete_note.id,
+      aws_api_gateway_method.get_notebooks.id,
+      aws_api_gateway_method.create_notebook.id,
+      aws_api_gateway_method.update_notebook.id,
+      aws_api_gateway_method.delete_notebook.id,
+      aws_api_gateway_method.move_note.id,
+      aws_api_gateway_method.update_note_flags.id,
+      aws_api_gateway_method.search_notes.id,
+      aws_api_gateway_method.patch_note.id,
+      aws_api_gateway_method.get_note.id,
+      aws_api_gateway_method.get_shared_notes.id,
+      aws_api_gateway_method.share_note.id,
+      aws_api_gateway_method.get_note_shares.id,
+      aws_api_gateway_method.revoke_share.id,
+      aws_api_gateway_method.create_share_link.id,
+      aws_api_gateway_method.get_share_links.id,
+      aws_api_gateway_method.revoke_share_link.id,
+      aws_api_gateway_method.get_public_note.id,
+      aws_api_gateway_method.add_checklist_item.id,
+      aws_api_gateway_method.reorder_checklist_items.id,
+      aws_api_gateway_method.update_checklist_item.id,
+      aws_api_gateway_method.remove_checklist_item.id,
+      aws_api_gateway_method.webhooks_post.id,
+      aws_api_gateway_method.webhooks_get.id,
+      aws_api_gateway_method.webhook_put.id,
+      aws_api_gateway_method.webhook_delete.id,
+      aws_api_gateway_method.webhook_deliveries_get.id,
+      aws_api_gateway_method.sync_get.id,
+      aws_api_gateway_method.sync_post.id,
+      aws_api_gateway_method.create_attachment.id,
+      aws_api_gateway_method.get_attachments.id,
+      aws_api_gateway_method.delete_attachment.id,
+      aws_api_gateway_method.create_key.id,
+      aws_api_gateway_method.get_keys.id,
+      aws_api_gateway_method.update_key.id,
+      aws_api_gateway_method.get_openapi.id
     ]))
   }
   
@@ -257,7 +1254,7 @@ resource "aws_api_gateway_deployment" "deployment" {
 
 # Lambda permissions
 resource "aws_lambda_permission" "apigw_auth" {
-  statement_id  = "AllowExecutionFromAPIGateway"
+  statement_id  = "AllowExecutionFromAPIGateway_auth"
   action        = "lambda:InvokeFunction"
   function_name = var.lambda_function_names["auth"]
   principal     = "apigateway.amazonaws.com"
@@ -265,7 +1262,7 @@ resource "aws_lambda_permission" "apigw_auth" {
 }
 
 resource "aws_lambda_permission" "apigw_register" {
-  statement_id  = "AllowExecutionFromAPIGateway"
+  statement_id  = "AllowExecutionFromAPIGateway_register"
   action        = "lambda:InvokeFunction"
   function_name = var.lambda_function_names["register"]
   principal     = "apigateway.amazonaws.com"
@@ -273,7 +1270,7 @@ resource "aws_lambda_permission" "apigw_register" {
 }
 
 resource "aws_lambda_permission" "apigw_get_notes" {
-  statement_id  = "AllowExecutionFromAPIGateway"
+  statement_id  = "AllowExecutionFromAPIGateway_get_notes"
   action        = "lambda:InvokeFunction"
   function_name = var.lambda_function_names["get_notes"]
   principal     = "apigateway.amazonaws.com"
@@ -281,7 +1278,7 @@ resource "aws_lambda_permission" "apigw_get_notes" {
 }
 
 resource "aws_lambda_permission" "apigw_create_note" {
-  statement_id  = "AllowExecutionFromAPIGateway"
+  statement_id  = "AllowExecutionFromAPIGateway_create_note"
   action        = "lambda:InvokeFunction"
   function_name = var.lambda_function_names["create_note"]
   principal     = "apigateway.amazonaws.com"
@@ -289,7 +1286,7 @@ resource "aws_lambda_permission" "apigw_create_note" {
 }
 
 resource "aws_lambda_permission" "apigw_update_note" {
-  statement_id  = "AllowExecutionFromAPIGateway"
+  statement_id  = "AllowExecutionFromAPIGateway_update_note"
   action        = "lambda:InvokeFunction"
   function_name = var.lambda_function_names["update_note"]
   principal     = "apigateway.amazonaws.com"
@@ -297,9 +1294,362 @@ resource "aws_lambda_permission" "apigw_update_note" {
 }
 
 resource "aws_lambda_permission" "apigw_delete_note" {
-  statement_id  = "AllowExecutionFromAPIGateway"
+  statement_id  = "AllowExecutionFromAPIGateway_delete_note"
   action        = "lambda:InvokeFunction"
   function_name = var.lambda_function_names["delete_note"]
   principal     = "apigateway.amazonaws.com"
   source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.delete_note.http_method}${aws_api_gateway_resource.note.path}"
-} 
\ No newline at end of file
+} 
+
+resource "aws_lambda_permission" "apigw_get_notebooks" {
+  statement_id  = "AllowExecutionFromAPIGateway_get_notebooks"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_notebooks"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.get_notebooks.http_method}${aws_api_gateway_resource.notebooks.path}"
+}
+
+resource "aws_lambda_permission" "apigw_create_notebook" {
+  statement_id  = "AllowExecutionFromAPIGateway_create_notebook"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["create_notebook"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.create_notebook.http_method}${aws_api_gateway_resource.notebooks.path}"
+}
+
+resource "aws_lambda_permission" "apigw_update_notebook" {
+  statement_id  = "AllowExecutionFromAPIGateway_update_notebook"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["update_notebook"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.update_notebook.http_method}${aws_api_gateway_resource.notebook.path}"
+}
+
+resource "aws_lambda_permission" "apigw_delete_notebook" {
+  statement_id  = "AllowExecutionFromAPIGateway_delete_notebook"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["delete_notebook"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.delete_notebook.http_method}${aws_api_gateway_resource.notebook.path}"
+}
+
+resource "aws_lambda_permission" "apigw_move_note" {
+  statement_id  = "AllowExecutionFromAPIGateway_move_note"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["move_note"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.move_note.http_method}${aws_api_gateway_resource.note_notebook.path}"
+}
+
+resource "aws_lambda_permission" "apigw_update_note_flags" {
+  statement_id  = "AllowExecutionFromAPIGateway_update_note_flags"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["update_note_flags"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.update_note_flags.http_method}${aws_api_gateway_resource.note_flags.path}"
+}
+
+resource "aws_lambda_permission" "apigw_search_notes" {
+  statement_id  = "AllowExecutionFromAPIGateway_search_notes"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["search_notes"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.search_notes.http_method}${aws_api_gateway_resource.notes_search.path}"
+}
+
+resource "aws_lambda_permission" "apigw_patch_note" {
+  statement_id  = "AllowExecutionFromAPIGateway_patch_note"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["patch_note"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.patch_note.http_method}${aws_api_gateway_resource.note.path}"
+}
+
+resource "aws_lambda_permission" "apigw_get_note" {
+  statement_id  = "AllowExecutionFromAPIGateway_get_note"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_note"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.get_note.http_method}${aws_api_gateway_resource.note.path}"
+}
+
+resource "aws_lambda_permission" "apigw_get_shared_notes" {
+  statement_id  = "AllowExecutionFromAPIGateway_get_shared_notes"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_shared_notes"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.get_shared_notes.http_method}${aws_api_gateway_resource.notes_shared_with_me.path}"
+}
+
+resource "aws_lambda_permission" "apigw_share_note" {
+  statement_id  = "AllowExecutionFromAPIGateway_share_note"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["share_note"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.share_note.http_method}${aws_api_gateway_resource.note_shares.path}"
+}
+
+resource "aws_lambda_permission" "apigw_get_note_shares" {
+  statement_id  = "AllowExecutionFromAPIGateway_get_note_shares"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_note_shares"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.get_note_shares.http_method}${aws_api_gateway_resource.note_shares.path}"
+}
+
+resource "aws_lambda_permission" "apigw_revoke_share" {
+  statement_id  = "AllowExecutionFromAPIGateway_revoke_share"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["revoke_share"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.revoke_share.http_method}${aws_api_gateway_resource.note_share.path}"
+}
+
+resource "aws_lambda_permission" "apigw_create_share_link" {
+  statement_id  = "AllowExecutionFromAPIGateway_create_share_link"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["create_share_link"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.create_share_link.http_method}${aws_api_gateway_resource.note_share_link.path}"
+}
+
+resource "aws_lambda_permission" "apigw_get_share_links" {
+  statement_id  = "AllowExecutionFromAPIGateway_get_share_links"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_share_links"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.get_share_links.http_method}${aws_api_gateway_resource.share_links.path}"
+}
+
+resource "aws_lambda_permission" "apigw_revoke_share_link" {
+  statement_id  = "AllowExecutionFromAPIGateway_revoke_share_link"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["revoke_share_link"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.revoke_share_link.http_method}${aws_api_gateway_resource.share_link.path}"
+}
+
+resource "aws_lambda_permission" "apigw_get_public_note" {
+  statement_id  = "AllowExecutionFromAPIGateway_get_public_note"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_public_note"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.get_public_note.http_method}${aws_api_gateway_resource.public_note.path}"
+}
+
+resource "aws_lambda_permission" "apigw_add_checklist_item" {
+  statement_id  = "AllowExecutionFromAPIGateway_add_checklist_item"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["add_checklist_item"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.add_checklist_item.http_method}${aws_api_gateway_resource.note_items.path}"
+}
+
+resource "aws_lambda_permission" "apigw_reorder_checklist_items" {
+  statement_id  = "AllowExecutionFromAPIGateway_reorder_checklist_items"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["reorder_checklist_items"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.reorder_checklist_items.http_method}${aws_api_gateway_resource.note_items_order.path}"
+}
+
+resource "aws_lambda_permission" "apigw_update_checklist_item" {
+  statement_id  = "AllowExecutionFromAPIGateway_update_checklist_item"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["update_checklist_item"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.update_checklist_item.http_method}${aws_api_gateway_resource.note_item.path}"
+}
+
+resource "aws_lambda_permission" "apigw_remove_checklist_item" {
+  statement_id  = "AllowExecutionFromAPIGateway_remove_checklist_item"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["remove_checklist_item"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.remove_checklist_item.http_method}${aws_api_gateway_resource.note_item.path}"
+}
+
+resource "aws_lambda_permission" "apigw_webhooks_post" {
+  statement_id  = "AllowExecutionFromAPIGateway_webhooks_post"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["create_webhook"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.webhooks_post.http_method}${aws_api_gateway_resource.webhooks.path}"
+}
+
+resource "aws_lambda_permission" "apigw_webhooks_get" {
+  statement_id  = "AllowExecutionFromAPIGateway_webhooks_get"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_webhooks"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.webhooks_get.http_method}${aws_api_gateway_resource.webhooks.path}"
+}
+
+resource "aws_lambda_permission" "apigw_webhook_put" {
+  statement_id  = "AllowExecutionFromAPIGateway_webhook_put"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["update_webhook"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.webhook_put.http_method}${aws_api_gateway_resource.webhook.path}"
+}
+
+resource "aws_lambda_permission" "apigw_webhook_delete" {
+  statement_id  = "AllowExecutionFromAPIGateway_webhook_delete"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["delete_webhook"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.webhook_delete.http_method}${aws_api_gateway_resource.webhook.path}"
+}
+
+resource "aws_lambda_permission" "apigw_webhook_deliveries_get" {
+  statement_id  = "AllowExecutionFromAPIGateway_webhook_deliveries_get"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_webhook_deliveries"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.webhook_deliveries_get.http_method}${aws_api_gateway_resource.webhook_deliveries.path}"
+}
+
+# WebSocket API pushing note changes to connected clients
+resource "aws_apigatewayv2_api" "mino_websocket" {
+  name                       = "mino-websocket"
+  protocol_type              = "WEBSOCKET"
+  route_selection_expression = "$request.body.action"
+}
+
+resource "aws_apigatewayv2_integration" "websocket_connect" {
+  api_id           = aws_apigatewayv2_api.mino_websocket.id
+  integration_type = "AWS_PROXY"
+  integration_uri  = var.lambda_invoke_arns["websocket_connect"]
+}
+
+resource "aws_apigatewayv2_integration" "websocket_disconnect" {
+  api_id           = aws_apigatewayv2_api.mino_websocket.id
+  integration_type = "AWS_PROXY"
+  integration_uri  = var.lambda_invoke_arns["websocket_disconnect"]
+}
+
+resource "aws_apigatewayv2_integration" "websocket_message" {
+  api_id           = aws_apigatewayv2_api.mino_websocket.id
+  integration_type = "AWS_PROXY"
+  integration_uri  = var.lambda_invoke_arns["websocket_message"]
+}
+
+resource "aws_apigatewayv2_route" "websocket_connect" {
+  api_id    = aws_apigatewayv2_api.mino_websocket.id
+  route_key = "$connect"
+  target    = "integrations/${aws_apigatewayv2_integration.websocket_connect.id}"
+}
+
+resource "aws_apigatewayv2_route" "websocket_disconnect" {
+  api_id    = aws_apigatewayv2_api.mino_websocket.id
+  route_key = "$disconnect"
+  target    = "integrations/${aws_apigatewayv2_integration.websocket_disconnect.id}"
+}
+
+resource "aws_apigatewayv2_route" "websocket_message" {
+  api_id    = aws_apigatewayv2_api.mino_websocket.id
+  route_key = "$default"
+  target    = "integrations/${aws_apigatewayv2_integration.websocket_message.id}"
+}
+
+resource "aws_apigatewayv2_stage" "websocket" {
+  api_id      = aws_apigatewayv2_api.mino_websocket.id
+  name        = "dev"
+  auto_deploy = true
+}
+
+resource "aws_lambda_permission" "websocket_connect" {
+  statement_id  = "AllowExecutionFromAPIGateway"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["websocket_connect"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.mino_websocket.execution_arn}/*/$connect"
+}
+
+resource "aws_lambda_permission" "websocket_disconnect" {
+  statement_id  = "AllowExecutionFromAPIGateway"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["websocket_disconnect"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.mino_websocket.execution_arn}/*/$disconnect"
+}
+
+resource "aws_lambda_permission" "websocket_message" {
+  statement_id  = "AllowExecutionFromAPIGateway"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["websocket_message"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.mino_websocket.execution_arn}/*/$default"
+}
+
+resource "aws_lambda_permission" "apigw_sync_get" {
+  statement_id  = "AllowExecutionFromAPIGateway_sync_get"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["pull_changes"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.sync_get.http_method}${aws_api_gateway_resource.sync.path}"
+}
+
+resource "aws_lambda_permission" "apigw_sync_post" {
+  statement_id  = "AllowExecutionFromAPIGateway_sync_post"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["push_changes"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.sync_post.http_method}${aws_api_gateway_resource.sync.path}"
+}
+
+resource "aws_lambda_permission" "apigw_create_attachment" {
+  statement_id  = "AllowExecutionFromAPIGateway_create_attachment"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["create_attachment"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.create_attachment.http_method}${aws_api_gateway_resource.note_attachments.path}"
+}
+
+resource "aws_lambda_permission" "apigw_get_attachments" {
+  statement_id  = "AllowExecutionFromAPIGateway_get_attachments"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_attachments"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.get_attachments.http_method}${aws_api_gateway_resource.note_attachments.path}"
+}
+
+resource "aws_lambda_permission" "apigw_delete_attachment" {
+  statement_id  = "AllowExecutionFromAPIGateway_delete_attachment"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["delete_attachment"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.delete_attachment.http_method}${aws_api_gateway_resource.note_attachment.path}"
+}
+
+resource "aws_lambda_permission" "apigw_create_key" {
+  statement_id  = "AllowExecutionFromAPIGateway_create_key"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["create_key"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.create_key.http_method}${aws_api_gateway_resource.keys.path}"
+}
+
+resource "aws_lambda_permission" "apigw_get_keys" {
+  statement_id  = "AllowExecutionFromAPIGateway_get_keys"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_keys"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.get_keys.http_method}${aws_api_gateway_resource.keys.path}"
+}
+
+resource "aws_lambda_permission" "apigw_update_key" {
+  statement_id  = "AllowExecutionFromAPIGateway_update_key"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["update_key"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.update_key.http_method}${aws_api_gateway_resource.key.path}"
+}
+
+resource "aws_lambda_permission" "apigw_get_openapi" {
+  statement_id  = "AllowExecutionFromAPIGateway_get_openapi"
+  action        = "lambda:InvokeFunction"
+  function_name = var.lambda_function_names["get_openapi"]
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.mino_api.execution_arn}/*/${aws_api_gateway_method.get_openapi.http_method}${aws_api_gateway_resource.openapi.path}"
+}
\ No newline at end of file
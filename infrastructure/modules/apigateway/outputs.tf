@@ -8,4 +8,8 @@ output "rest_api_id" {
 
 output "root_resource_id" {
   value = aws_api_gateway_rest_api.mino_api.root_resource_id
-} 
\ No newline at end of file
+} 
+
+output "websocket_endpoint" {
+  value = aws_apigatewayv2_stage.websocket.invoke_url
+}
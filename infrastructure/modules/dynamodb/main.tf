@@ -23,10 +23,12 @@ resource "aws_dynamodb_table" "users" {
 }
 
 resource "aws_dynamodb_table" "notes" {
-  name           = "MiNoNotes"
-  billing_mode   = "PAY_PER_REQUEST"
-  hash_key       = "noteId"
-  range_key      = "userId"
+  name             = "MiNoNotes"
+  billing_mode     = "PAY_PER_REQUEST"
+  hash_key         = "noteId"
+  range_key        = "userId"
+  stream_enabled   = true
+  stream_view_type = "NEW_AND_OLD_IMAGES"
 
   attribute {
     name = "noteId"
@@ -43,6 +45,11 @@ resource "aws_dynamodb_table" "notes" {
     type = "S"
   }
 
+  attribute {
+    name = "notebookId"
+    type = "S"
+  }
+
   global_secondary_index {
     name               = "UserIdIndex"
     hash_key           = "userId"
@@ -51,4 +58,350 @@ resource "aws_dynamodb_table" "notes" {
     write_capacity     = 5
     read_capacity      = 5
   }
-} 
\ No newline at end of file
+
+  global_secondary_index {
+    name               = "NotebookIdIndex"
+    hash_key           = "notebookId"
+    range_key          = "createdAt"
+    projection_type    = "ALL"
+    write_capacity     = 5
+    read_capacity      = 5
+  }
+} 
+
+resource "aws_dynamodb_table" "notebooks" {
+  name           = "MiNoNotebooks"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "notebookId"
+  range_key      = "userId"
+
+  attribute {
+    name = "notebookId"
+    type = "S"
+  }
+
+  attribute {
+    name = "userId"
+    type = "S"
+  }
+
+  attribute {
+    name = "createdAt"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name               = "UserIdIndex"
+    hash_key           = "userId"
+    range_key          = "createdAt"
+    projection_type    = "ALL"
+    write_capacity     = 5
+    read_capacity      = 5
+  }
+}
+
+resource "aws_dynamodb_table" "search" {
+  name           = "MiNoSearchIndex"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "userId"
+  range_key      = "termNoteId"
+
+  attribute {
+    name = "userId"
+    type = "S"
+  }
+
+  attribute {
+    name = "termNoteId"
+    type = "S"
+  }
+}
+
+resource "aws_dynamodb_table" "dead_letters" {
+  name           = "MiNoStreamDeadLetters"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "eventId"
+  range_key      = "projector"
+
+  attribute {
+    name = "eventId"
+    type = "S"
+  }
+
+  attribute {
+    name = "projector"
+    type = "S"
+  }
+}
+
+resource "aws_dynamodb_table" "shares" {
+  name           = "MiNoShares"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "noteId"
+  range_key      = "sharedWithUserId"
+
+  attribute {
+    name = "noteId"
+    type = "S"
+  }
+
+  attribute {
+    name = "sharedWithUserId"
+    type = "S"
+  }
+
+  attribute {
+    name = "createdAt"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name               = "SharedWithIndex"
+    hash_key           = "sharedWithUserId"
+    range_key          = "createdAt"
+    projection_type    = "ALL"
+    write_capacity     = 5
+    read_capacity      = 5
+  }
+}
+
+resource "aws_dynamodb_table" "share_links" {
+  name           = "MiNoShareLinks"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "linkId"
+
+  # SHA-256 of the link token, the token itself is not stored
+  attribute {
+    name = "linkId"
+    type = "S"
+  }
+
+  attribute {
+    name = "ownerId"
+    type = "S"
+  }
+
+  attribute {
+    name = "createdAt"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name               = "OwnerIdIndex"
+    hash_key           = "ownerId"
+    range_key          = "createdAt"
+    projection_type    = "ALL"
+    write_capacity     = 5
+    read_capacity      = 5
+  }
+
+  # Expired links are removed by DynamoDB
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+}
+
+resource "aws_dynamodb_table" "reminders" {
+  name           = "MiNoReminders"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "bucket"
+  range_key      = "noteId"
+
+  attribute {
+    name = "bucket"
+    type = "S"
+  }
+
+  attribute {
+    name = "noteId"
+    type = "S"
+  }
+
+  # Sent reminders are removed by DynamoDB a week after they were due
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+}
+
+resource "aws_dynamodb_table" "webhooks" {
+  name           = "MiNoWebhooks"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "userId"
+  range_key      = "webhookId"
+
+  attribute {
+    name = "userId"
+    type = "S"
+  }
+
+  attribute {
+    name = "webhookId"
+    type = "S"
+  }
+}
+
+resource "aws_dynamodb_table" "webhook_deliveries" {
+  name           = "MiNoWebhookDeliveries"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "webhookId"
+  range_key      = "deliveryId"
+
+  attribute {
+    name = "webhookId"
+    type = "S"
+  }
+
+  attribute {
+    name = "deliveryId"
+    type = "S"
+  }
+
+  attribute {
+    name = "createdAt"
+    type = "S"
+  }
+
+  attribute {
+    name = "queue"
+    type = "S"
+  }
+
+  attribute {
+    name = "nextAttemptAt"
+    type = "S"
+  }
+
+  # Delivery history of a webhook, newest first
+  local_secondary_index {
+    name               = "CreatedAtIndex"
+    range_key          = "createdAt"
+    projection_type    = "ALL"
+  }
+
+  # Sparse index of the deliveries waiting for an attempt
+  global_secondary_index {
+    name               = "PendingIndex"
+    hash_key           = "queue"
+    range_key          = "nextAttemptAt"
+    projection_type    = "ALL"
+    write_capacity     = 5
+    read_capacity      = 5
+  }
+
+  # Delivery history is removed by DynamoDB after 30 days
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+}
+
+resource "aws_dynamodb_table" "connections" {
+  name           = "MiNoConnections"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "userId"
+  range_key      = "connectionId"
+
+  attribute {
+    name = "userId"
+    type = "S"
+  }
+
+  attribute {
+    name = "connectionId"
+    type = "S"
+  }
+
+  # Disconnects only carry the connection ID
+  global_secondary_index {
+    name               = "ConnectionIdIndex"
+    hash_key           = "connectionId"
+    projection_type    = "KEYS_ONLY"
+    write_capacity     = 5
+    read_capacity      = 5
+  }
+
+  # Connections whose disconnect was missed are removed by DynamoDB
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+}
+
+resource "aws_dynamodb_table" "sync_log" {
+  name           = "MiNoSyncLog"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "userId"
+  range_key      = "seq"
+
+  attribute {
+    name = "userId"
+    type = "S"
+  }
+
+  attribute {
+    name = "seq"
+    type = "S"
+  }
+
+  # Changes are removed by DynamoDB after 30 days, older sync tokens need a full sync
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+}
+
+resource "aws_dynamodb_table" "attachments" {
+  name           = "MiNoAttachments"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "noteId"
+  range_key      = "attachmentId"
+
+  attribute {
+    name = "noteId"
+    type = "S"
+  }
+
+  attribute {
+    name = "attachmentId"
+    type = "S"
+  }
+
+  attribute {
+    name = "upload"
+    type = "S"
+  }
+
+  attribute {
+    name = "uploadDeadline"
+    type = "S"
+  }
+
+  # Sparse index of the attachments still waiting for their file
+  global_secondary_index {
+    name               = "UploadIndex"
+    hash_key           = "upload"
+    range_key          = "uploadDeadline"
+    projection_type    = "ALL"
+    write_capacity     = 5
+    read_capacity      = 5
+  }
+}
+
+resource "aws_dynamodb_table" "keys" {
+  name           = "MiNoKeys"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "userId"
+  range_key      = "keyId"
+
+  attribute {
+    name = "userId"
+    type = "S"
+  }
+
+  attribute {
+    name = "keyId"
+    type = "S"
+  }
+}
\ No newline at end of file
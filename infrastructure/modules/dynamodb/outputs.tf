@@ -12,4 +12,104 @@ output "notes_table_name" {
 
 output "notes_table_arn" {
   value = aws_dynamodb_table.notes.arn
-} 
\ No newline at end of file
+} 
+
+output "notebooks_table_name" {
+  value = aws_dynamodb_table.notebooks.name
+}
+
+output "notebooks_table_arn" {
+  value = aws_dynamodb_table.notebooks.arn
+}
+
+output "search_table_name" {
+  value = aws_dynamodb_table.search.name
+}
+
+output "search_table_arn" {
+  value = aws_dynamodb_table.search.arn
+}
+
+output "dead_letters_table_name" {
+  value = aws_dynamodb_table.dead_letters.name
+}
+
+output "dead_letters_table_arn" {
+  value = aws_dynamodb_table.dead_letters.arn
+}
+
+output "notes_stream_arn" {
+  value = aws_dynamodb_table.notes.stream_arn
+}
+
+output "shares_table_name" {
+  value = aws_dynamodb_table.shares.name
+}
+
+output "shares_table_arn" {
+  value = aws_dynamodb_table.shares.arn
+}
+
+output "share_links_table_name" {
+  value = aws_dynamodb_table.share_links.name
+}
+
+output "share_links_table_arn" {
+  value = aws_dynamodb_table.share_links.arn
+}
+
+output "reminders_table_name" {
+  value = aws_dynamodb_table.reminders.name
+}
+
+output "reminders_table_arn" {
+  value = aws_dynamodb_table.reminders.arn
+}
+
+output "webhooks_table_name" {
+  value = aws_dynamodb_table.webhooks.name
+}
+
+output "webhooks_table_arn" {
+  value = aws_dynamodb_table.webhooks.arn
+}
+
+output "webhook_deliveries_table_name" {
+  value = aws_dynamodb_table.webhook_deliveries.name
+}
+
+output "webhook_deliveries_table_arn" {
+  value = aws_dynamodb_table.webhook_deliveries.arn
+}
+
+output "connections_table_name" {
+  value = aws_dynamodb_table.connections.name
+}
+
+output "connections_table_arn" {
+  value = aws_dynamodb_table.connections.arn
+}
+
+output "sync_log_table_name" {
+  value = aws_dynamodb_table.sync_log.name
+}
+
+output "sync_log_table_arn" {
+  value = aws_dynamodb_table.sync_log.arn
+}
+
+output "attachments_table_name" {
+  value = aws_dynamodb_table.attachments.name
+}
+
+output "attachments_table_arn" {
+  value = aws_dynamodb_table.attachments.arn
+}
+
+output "keys_table_name" {
+  value = aws_dynamodb_table.keys.name
+}
+
+output "keys_table_arn" {
+  value = aws_dynamodb_table.keys.arn
+}
\ No newline at end of file
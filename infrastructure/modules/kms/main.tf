@@ -0,0 +1,11 @@
+# Master key wrapping the data keys notes are encrypted with at rest. A
+# rotation adds a key with the next version alias, see the README.
+resource "aws_kms_key" "notes_v1" {
+  description             = "MiNo note encryption master key v1"
+  deletion_window_in_days = 7
+}
+
+resource "aws_kms_alias" "notes_v1" {
+  name          = "alias/mino-notes-v1"
+  target_key_id = aws_kms_key.notes_v1.key_id
+}
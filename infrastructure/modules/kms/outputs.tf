@@ -0,0 +1,3 @@
+output "notes_key_alias" {
+  value = aws_kms_alias.notes_v1.name
+}
@@ -29,7 +29,21 @@ resource "aws_iam_policy" "lambda_policy" {
           "dynamodb:UpdateItem",
           "dynamodb:DeleteItem",
           "dynamodb:Query",
-          "dynamodb:Scan"
+          "dynamodb:Scan",
+          "dynamodb:BatchGetItem",
+          "dynamodb:BatchWriteItem",
+          "dynamodb:TransactWriteItems",
+          "dynamodb:ConditionCheckItem"
+        ]
+        Effect   = "Allow"
+        Resource = "*"
+      },
+      {
+        Action = [
+          "dynamodb:DescribeStream",
+          "dynamodb:GetRecords",
+          "dynamodb:GetShardIterator",
+          "dynamodb:ListStreams"
         ]
         Effect   = "Allow"
         Resource = "*"
@@ -42,6 +56,30 @@ resource "aws_iam_policy" "lambda_policy" {
         ]
         Effect   = "Allow"
         Resource = "*"
+      },
+      {
+        Action = [
+          "kms:GenerateDataKey",
+          "kms:Decrypt"
+        ]
+        Effect   = "Allow"
+        Resource = "*"
+      },
+      {
+        Action = [
+          "s3:PutObject",
+          "s3:GetObject",
+          "s3:DeleteObject"
+        ]
+        Effect   = "Allow"
+        Resource = "*"
+      },
+      {
+        Action = [
+          "execute-api:ManageConnections"
+        ]
+        Effect   = "Allow"
+        Resource = "*"
       }
     ]
   })
@@ -80,6 +118,182 @@ resource "null_resource" "check_lambda_files" {
         echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/delete_note.zip"
         exit 1
       fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_notebooks.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_notebooks.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/create_notebook.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/create_notebook.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/update_notebook.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/update_notebook.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/delete_notebook.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/delete_notebook.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/move_note.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/move_note.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/update_note_flags.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/update_note_flags.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/search_notes.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/search_notes.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/notes_stream.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/notes_stream.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/patch_note.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/patch_note.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_note.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_note.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/share_note.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/share_note.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_note_shares.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_note_shares.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/revoke_share.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/revoke_share.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_shared_notes.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_shared_notes.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/create_share_link.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/create_share_link.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_share_links.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_share_links.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/revoke_share_link.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/revoke_share_link.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_public_note.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_public_note.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/add_checklist_item.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/add_checklist_item.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/update_checklist_item.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/update_checklist_item.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/reorder_checklist_items.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/reorder_checklist_items.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/remove_checklist_item.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/remove_checklist_item.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/dispatch_reminders.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/dispatch_reminders.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/create_webhook.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/create_webhook.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_webhooks.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_webhooks.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/update_webhook.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/update_webhook.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/delete_webhook.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/delete_webhook.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_webhook_deliveries.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_webhook_deliveries.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/deliver_webhooks.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/deliver_webhooks.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/websocket_connect.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/websocket_connect.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/websocket_disconnect.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/websocket_disconnect.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/websocket_message.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/websocket_message.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/pull_changes.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/pull_changes.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/push_changes.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/push_changes.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/create_attachment.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/create_attachment.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_attachments.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_attachments.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/delete_attachment.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/delete_attachment.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/create_key.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/create_key.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_keys.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_keys.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/update_key.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/update_key.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/reencrypt_notes.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/reencrypt_notes.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/api.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/api.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/get_openapi.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/get_openapi.zip"
+        exit 1
+      fi
+      if [ ! -f "${path.module}/../../../backend/bin/reconcile_attachments.zip" ]; then
+        echo "Error: Lambda zip file not found: ${path.module}/../../../backend/bin/reconcile_attachments.zip"
+        exit 1
+      fi
     EOT
     interpreter = ["bash", "-c"]
   }
@@ -103,6 +317,7 @@ resource "aws_lambda_function" "auth_lambda" {
     variables = {
       USERS_TABLE = "MiNoUsers"
       JWT_SECRET  = "local-dev-jwt-secret"
+      LOG_LEVEL   = var.log_level
     }
   }
 
@@ -120,6 +335,7 @@ resource "aws_lambda_function" "register_lambda" {
   environment {
     variables = {
       USERS_TABLE = "MiNoUsers"
+      LOG_LEVEL   = var.log_level
     }
   }
 
@@ -136,8 +352,13 @@ resource "aws_lambda_function" "get_notes_lambda" {
   
   environment {
     variables = {
-      NOTES_TABLE = "MiNoNotes"
-      JWT_SECRET  = "local-dev-jwt-secret"
+      NOTES_TABLE            = "MiNoNotes"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      NOTEBOOKS_TABLE        = "MiNoNotebooks"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
     }
   }
 
@@ -154,8 +375,15 @@ resource "aws_lambda_function" "create_note_lambda" {
   
   environment {
     variables = {
-      NOTES_TABLE = "MiNoNotes"
-      JWT_SECRET  = "local-dev-jwt-secret"
+      NOTES_TABLE            = "MiNoNotes"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      NOTEBOOKS_TABLE        = "MiNoNotebooks"
+      KEYS_TABLE             = "MiNoKeys"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      USERS_TABLE            = "MiNoUsers"
+      LOG_LEVEL              = var.log_level
     }
   }
 
@@ -172,8 +400,15 @@ resource "aws_lambda_function" "update_note_lambda" {
   
   environment {
     variables = {
-      NOTES_TABLE = "MiNoNotes"
-      JWT_SECRET  = "local-dev-jwt-secret"
+      NOTES_TABLE            = "MiNoNotes"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      SHARES_TABLE           = "MiNoShares"
+      KEYS_TABLE             = "MiNoKeys"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      USERS_TABLE            = "MiNoUsers"
+      LOG_LEVEL              = var.log_level
     }
   }
 
@@ -190,10 +425,1084 @@ resource "aws_lambda_function" "delete_note_lambda" {
   
   environment {
     variables = {
-      NOTES_TABLE = "MiNoNotes"
-      JWT_SECRET  = "local-dev-jwt-secret"
+      NOTES_TABLE            = "MiNoNotes"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      SHARES_TABLE           = "MiNoShares"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      USERS_TABLE            = "MiNoUsers"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+} 
+
+resource "aws_lambda_function" "get_notebooks_lambda" {
+  function_name = "mino_get_notebooks"
+  filename      = "${path.module}/../../../backend/bin/get_notebooks.zip"
+  handler       = "get_notebooks"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      NOTEBOOKS_TABLE        = "MiNoNotebooks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "create_notebook_lambda" {
+  function_name = "mino_create_notebook"
+  filename      = "${path.module}/../../../backend/bin/create_notebook.zip"
+  handler       = "create_notebook"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      NOTEBOOKS_TABLE        = "MiNoNotebooks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "update_notebook_lambda" {
+  function_name = "mino_update_notebook"
+  filename      = "${path.module}/../../../backend/bin/update_notebook.zip"
+  handler       = "update_notebook"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      NOTEBOOKS_TABLE        = "MiNoNotebooks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "delete_notebook_lambda" {
+  function_name = "mino_delete_notebook"
+  filename      = "${path.module}/../../../backend/bin/delete_notebook.zip"
+  handler       = "delete_notebook"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      NOTEBOOKS_TABLE        = "MiNoNotebooks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "move_note_lambda" {
+  function_name = "mino_move_note"
+  filename      = "${path.module}/../../../backend/bin/move_note.zip"
+  handler       = "move_note"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      NOTEBOOKS_TABLE        = "MiNoNotebooks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "update_note_flags_lambda" {
+  function_name = "mino_update_note_flags"
+  filename      = "${path.module}/../../../backend/bin/update_note_flags.zip"
+  handler       = "update_note_flags"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "search_notes_lambda" {
+  function_name = "mino_search_notes"
+  filename      = "${path.module}/../../../backend/bin/search_notes.zip"
+  handler       = "search_notes"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SEARCH_TABLE           = "MiNoSearchIndex"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      SEARCH_INDEX_KEY       = "bWluby1sb2NhbC1kZXYtc2VhcmNoLWluZGV4LWtleSE="
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "notes_stream_lambda" {
+  function_name = "mino_notes_stream"
+  filename      = "${path.module}/../../../backend/bin/notes_stream.zip"
+  handler       = "notes_stream"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 60
+  
+  environment {
+    variables = {
+      NOTES_TABLE              = "MiNoNotes"
+      SEARCH_TABLE             = "MiNoSearchIndex"
+      DEAD_LETTERS_TABLE       = "MiNoStreamDeadLetters"
+      SHARES_TABLE             = "MiNoShares"
+      SHARE_LINKS_TABLE        = "MiNoShareLinks"
+      REMINDERS_TABLE          = "MiNoReminders"
+      WEBHOOKS_TABLE           = "MiNoWebhooks"
+      WEBHOOK_DELIVERIES_TABLE = "MiNoWebhookDeliveries"
+      CONNECTIONS_TABLE        = "MiNoConnections"
+      SYNC_LOG_TABLE           = "MiNoSyncLog"
+      USERS_TABLE              = "MiNoUsers"
+      ATTACHMENTS_TABLE        = "MiNoAttachments"
+      ATTACHMENTS_BUCKET       = "mino-attachments"
+      ENCRYPTION_KEYRING       = "kms"
+      ENCRYPTION_KEYS          = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION   = "v1"
+      SEARCH_INDEX_KEY         = "bWluby1sb2NhbC1kZXYtc2VhcmNoLWluZGV4LWtleSE="
+      LOG_LEVEL                = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+# Feed note changes from the MiNoNotes stream to the notes_stream Lambda
+resource "aws_lambda_event_source_mapping" "notes_stream" {
+  event_source_arn        = var.notes_stream_arn
+  function_name           = aws_lambda_function.notes_stream_lambda.arn
+  starting_position       = "LATEST"
+  batch_size              = 100
+  function_response_types = ["ReportBatchItemFailures"]
+}
+
+resource "aws_lambda_function" "patch_note_lambda" {
+  function_name = "mino_patch_note"
+  filename      = "${path.module}/../../../backend/bin/patch_note.zip"
+  handler       = "patch_note"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      NOTEBOOKS_TABLE        = "MiNoNotebooks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      SHARES_TABLE           = "MiNoShares"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      USERS_TABLE            = "MiNoUsers"
+      LOG_LEVEL              = var.log_level
     }
   }
 
   depends_on = [null_resource.check_lambda_files]
-} 
\ No newline at end of file
+}
+
+resource "aws_lambda_function" "get_note_lambda" {
+  function_name = "mino_get_note"
+  filename      = "${path.module}/../../../backend/bin/get_note.zip"
+  handler       = "get_note"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      SHARES_TABLE           = "MiNoShares"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "share_note_lambda" {
+  function_name = "mino_share_note"
+  filename      = "${path.module}/../../../backend/bin/share_note.zip"
+  handler       = "share_note"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      USERS_TABLE            = "MiNoUsers"
+      NOTES_TABLE            = "MiNoNotes"
+      SHARES_TABLE           = "MiNoShares"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "get_note_shares_lambda" {
+  function_name = "mino_get_note_shares"
+  filename      = "${path.module}/../../../backend/bin/get_note_shares.zip"
+  handler       = "get_note_shares"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARES_TABLE           = "MiNoShares"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "revoke_share_lambda" {
+  function_name = "mino_revoke_share"
+  filename      = "${path.module}/../../../backend/bin/revoke_share.zip"
+  handler       = "revoke_share"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARES_TABLE           = "MiNoShares"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "get_shared_notes_lambda" {
+  function_name = "mino_get_shared_notes"
+  filename      = "${path.module}/../../../backend/bin/get_shared_notes.zip"
+  handler       = "get_shared_notes"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARES_TABLE           = "MiNoShares"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "create_share_link_lambda" {
+  function_name = "mino_create_share_link"
+  filename      = "${path.module}/../../../backend/bin/create_share_link.zip"
+  handler       = "create_share_link"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARE_LINKS_TABLE      = "MiNoShareLinks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "get_share_links_lambda" {
+  function_name = "mino_get_share_links"
+  filename      = "${path.module}/../../../backend/bin/get_share_links.zip"
+  handler       = "get_share_links"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARE_LINKS_TABLE      = "MiNoShareLinks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "revoke_share_link_lambda" {
+  function_name = "mino_revoke_share_link"
+  filename      = "${path.module}/../../../backend/bin/revoke_share_link.zip"
+  handler       = "revoke_share_link"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARE_LINKS_TABLE      = "MiNoShareLinks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "get_public_note_lambda" {
+  function_name = "mino_get_public_note"
+  filename      = "${path.module}/../../../backend/bin/get_public_note.zip"
+  handler       = "get_public_note"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARE_LINKS_TABLE      = "MiNoShareLinks"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "add_checklist_item_lambda" {
+  function_name = "mino_add_checklist_item"
+  filename      = "${path.module}/../../../backend/bin/add_checklist_item.zip"
+  handler       = "add_checklist_item"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARES_TABLE           = "MiNoShares"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      USERS_TABLE            = "MiNoUsers"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "update_checklist_item_lambda" {
+  function_name = "mino_update_checklist_item"
+  filename      = "${path.module}/../../../backend/bin/update_checklist_item.zip"
+  handler       = "update_checklist_item"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARES_TABLE           = "MiNoShares"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      USERS_TABLE            = "MiNoUsers"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "reorder_checklist_items_lambda" {
+  function_name = "mino_reorder_checklist_items"
+  filename      = "${path.module}/../../../backend/bin/reorder_checklist_items.zip"
+  handler       = "reorder_checklist_items"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARES_TABLE           = "MiNoShares"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "remove_checklist_item_lambda" {
+  function_name = "mino_remove_checklist_item"
+  filename      = "${path.module}/../../../backend/bin/remove_checklist_item.zip"
+  handler       = "remove_checklist_item"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SHARES_TABLE           = "MiNoShares"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      USERS_TABLE            = "MiNoUsers"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "dispatch_reminders_lambda" {
+  function_name = "mino_dispatch_reminders"
+  filename      = "${path.module}/../../../backend/bin/dispatch_reminders.zip"
+  handler       = "dispatch_reminders"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 60
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      USERS_TABLE            = "MiNoUsers"
+      REMINDERS_TABLE        = "MiNoReminders"
+      NOTIFIER               = "log"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+# Look for due reminders every minute
+resource "aws_cloudwatch_event_rule" "dispatch_reminders" {
+  name                = "mino_dispatch_reminders"
+  schedule_expression = "rate(1 minute)"
+}
+
+resource "aws_cloudwatch_event_target" "dispatch_reminders" {
+  rule = aws_cloudwatch_event_rule.dispatch_reminders.name
+  arn  = aws_lambda_function.dispatch_reminders_lambda.arn
+}
+
+resource "aws_lambda_permission" "events_dispatch_reminders" {
+  statement_id  = "AllowExecutionFromEventBridge"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.dispatch_reminders_lambda.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.dispatch_reminders.arn
+}
+
+resource "aws_lambda_function" "create_webhook_lambda" {
+  function_name = "mino_create_webhook"
+  filename      = "${path.module}/../../../backend/bin/create_webhook.zip"
+  handler       = "create_webhook"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      WEBHOOKS_TABLE = "MiNoWebhooks"
+      USERS_TABLE    = "MiNoUsers"
+      JWT_SECRET     = "local-dev-jwt-secret"
+      LOG_LEVEL      = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "get_webhooks_lambda" {
+  function_name = "mino_get_webhooks"
+  filename      = "${path.module}/../../../backend/bin/get_webhooks.zip"
+  handler       = "get_webhooks"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      WEBHOOKS_TABLE = "MiNoWebhooks"
+      JWT_SECRET     = "local-dev-jwt-secret"
+      LOG_LEVEL      = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "update_webhook_lambda" {
+  function_name = "mino_update_webhook"
+  filename      = "${path.module}/../../../backend/bin/update_webhook.zip"
+  handler       = "update_webhook"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      WEBHOOKS_TABLE = "MiNoWebhooks"
+      JWT_SECRET     = "local-dev-jwt-secret"
+      LOG_LEVEL      = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "delete_webhook_lambda" {
+  function_name = "mino_delete_webhook"
+  filename      = "${path.module}/../../../backend/bin/delete_webhook.zip"
+  handler       = "delete_webhook"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      WEBHOOKS_TABLE = "MiNoWebhooks"
+      USERS_TABLE    = "MiNoUsers"
+      JWT_SECRET     = "local-dev-jwt-secret"
+      LOG_LEVEL      = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "get_webhook_deliveries_lambda" {
+  function_name = "mino_get_webhook_deliveries"
+  filename      = "${path.module}/../../../backend/bin/get_webhook_deliveries.zip"
+  handler       = "get_webhook_deliveries"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      WEBHOOKS_TABLE           = "MiNoWebhooks"
+      WEBHOOK_DELIVERIES_TABLE = "MiNoWebhookDeliveries"
+      JWT_SECRET               = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING       = "kms"
+      ENCRYPTION_KEYS          = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION   = "v1"
+      LOG_LEVEL                = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "deliver_webhooks_lambda" {
+  function_name = "mino_deliver_webhooks"
+  filename      = "${path.module}/../../../backend/bin/deliver_webhooks.zip"
+  handler       = "deliver_webhooks"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 300
+  
+  environment {
+    variables = {
+      WEBHOOKS_TABLE           = "MiNoWebhooks"
+      WEBHOOK_DELIVERIES_TABLE = "MiNoWebhookDeliveries"
+      ENCRYPTION_KEYRING       = "kms"
+      ENCRYPTION_KEYS          = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION   = "v1"
+      LOG_LEVEL                = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+# Retry due webhook deliveries every minute
+resource "aws_cloudwatch_event_rule" "deliver_webhooks" {
+  name                = "mino_deliver_webhooks"
+  schedule_expression = "rate(1 minute)"
+}
+
+resource "aws_cloudwatch_event_target" "deliver_webhooks" {
+  rule = aws_cloudwatch_event_rule.deliver_webhooks.name
+  arn  = aws_lambda_function.deliver_webhooks_lambda.arn
+}
+
+resource "aws_lambda_permission" "events_deliver_webhooks" {
+  statement_id  = "AllowExecutionFromEventBridge"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.deliver_webhooks_lambda.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.deliver_webhooks.arn
+}
+
+resource "aws_lambda_function" "websocket_connect_lambda" {
+  function_name = "mino_websocket_connect"
+  filename      = "${path.module}/../../../backend/bin/websocket_connect.zip"
+  handler       = "websocket_connect"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      CONNECTIONS_TABLE = "MiNoConnections"
+      JWT_SECRET        = "local-dev-jwt-secret"
+      LOG_LEVEL         = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "websocket_disconnect_lambda" {
+  function_name = "mino_websocket_disconnect"
+  filename      = "${path.module}/../../../backend/bin/websocket_disconnect.zip"
+  handler       = "websocket_disconnect"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      CONNECTIONS_TABLE = "MiNoConnections"
+      LOG_LEVEL         = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "websocket_message_lambda" {
+  function_name = "mino_websocket_message"
+  filename      = "${path.module}/../../../backend/bin/websocket_message.zip"
+  handler       = "websocket_message"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      CONNECTIONS_TABLE = "MiNoConnections"
+      LOG_LEVEL         = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "pull_changes_lambda" {
+  function_name = "mino_pull_changes"
+  filename      = "${path.module}/../../../backend/bin/pull_changes.zip"
+  handler       = "pull_changes"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      SYNC_LOG_TABLE         = "MiNoSyncLog"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "push_changes_lambda" {
+  function_name = "mino_push_changes"
+  filename      = "${path.module}/../../../backend/bin/push_changes.zip"
+  handler       = "push_changes"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      NOTEBOOKS_TABLE        = "MiNoNotebooks"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      KEYS_TABLE             = "MiNoKeys"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      USERS_TABLE            = "MiNoUsers"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "create_attachment_lambda" {
+  function_name = "mino_create_attachment"
+  filename      = "${path.module}/../../../backend/bin/create_attachment.zip"
+  handler       = "create_attachment"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      USERS_TABLE            = "MiNoUsers"
+      SHARES_TABLE           = "MiNoShares"
+      ATTACHMENTS_TABLE      = "MiNoAttachments"
+      ATTACHMENTS_BUCKET     = "mino-attachments"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "get_attachments_lambda" {
+  function_name = "mino_get_attachments"
+  filename      = "${path.module}/../../../backend/bin/get_attachments.zip"
+  handler       = "get_attachments"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      USERS_TABLE            = "MiNoUsers"
+      SHARES_TABLE           = "MiNoShares"
+      ATTACHMENTS_TABLE      = "MiNoAttachments"
+      ATTACHMENTS_BUCKET     = "mino-attachments"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "delete_attachment_lambda" {
+  function_name = "mino_delete_attachment"
+  filename      = "${path.module}/../../../backend/bin/delete_attachment.zip"
+  handler       = "delete_attachment"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      USERS_TABLE            = "MiNoUsers"
+      SHARES_TABLE           = "MiNoShares"
+      ATTACHMENTS_TABLE      = "MiNoAttachments"
+      ATTACHMENTS_BUCKET     = "mino-attachments"
+      JWT_SECRET             = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "create_key_lambda" {
+  function_name = "mino_create_key"
+  filename      = "${path.module}/../../../backend/bin/create_key.zip"
+  handler       = "create_key"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      KEYS_TABLE = "MiNoKeys"
+      JWT_SECRET = "local-dev-jwt-secret"
+      LOG_LEVEL  = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "get_keys_lambda" {
+  function_name = "mino_get_keys"
+  filename      = "${path.module}/../../../backend/bin/get_keys.zip"
+  handler       = "get_keys"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      KEYS_TABLE = "MiNoKeys"
+      JWT_SECRET = "local-dev-jwt-secret"
+      LOG_LEVEL  = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "update_key_lambda" {
+  function_name = "mino_update_key"
+  filename      = "${path.module}/../../../backend/bin/update_key.zip"
+  handler       = "update_key"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      KEYS_TABLE = "MiNoKeys"
+      JWT_SECRET = "local-dev-jwt-secret"
+      LOG_LEVEL  = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "reencrypt_notes_lambda" {
+  function_name = "mino_reencrypt_notes"
+  filename      = "${path.module}/../../../backend/bin/reencrypt_notes.zip"
+  handler       = "reencrypt_notes"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 900
+  
+  environment {
+    variables = {
+      NOTES_TABLE            = "MiNoNotes"
+      ENCRYPTION_KEYRING     = "kms"
+      ENCRYPTION_KEYS        = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION = "v1"
+      SEARCH_TABLE           = "MiNoSearchIndex"
+      SEARCH_INDEX_KEY       = "bWluby1sb2NhbC1kZXYtc2VhcmNoLWluZGV4LWtleSE="
+      LOG_LEVEL              = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+# Every REST route in one function, used when api_mode is "router"
+resource "aws_lambda_function" "api_lambda" {
+  function_name = "mino_api"
+  filename      = "${path.module}/../../../backend/bin/api.zip"
+  handler       = "api"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      USERS_TABLE              = "MiNoUsers"
+      NOTES_TABLE              = "MiNoNotes"
+      NOTEBOOKS_TABLE          = "MiNoNotebooks"
+      SHARES_TABLE             = "MiNoShares"
+      SHARE_LINKS_TABLE        = "MiNoShareLinks"
+      SEARCH_TABLE             = "MiNoSearchIndex"
+      WEBHOOKS_TABLE           = "MiNoWebhooks"
+      WEBHOOK_DELIVERIES_TABLE = "MiNoWebhookDeliveries"
+      SYNC_LOG_TABLE           = "MiNoSyncLog"
+      ATTACHMENTS_TABLE        = "MiNoAttachments"
+      KEYS_TABLE               = "MiNoKeys"
+      ATTACHMENTS_BUCKET       = "mino-attachments"
+      JWT_SECRET               = "local-dev-jwt-secret"
+      ENCRYPTION_KEYRING       = "kms"
+      ENCRYPTION_KEYS          = "v1=alias/mino-notes-v1"
+      ENCRYPTION_KEY_VERSION   = "v1"
+      SEARCH_INDEX_KEY         = "bWluby1sb2NhbC1kZXYtc2VhcmNoLWluZGV4LWtleSE="
+      LOG_LEVEL                = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "get_openapi_lambda" {
+  function_name = "mino_get_openapi"
+  filename      = "${path.module}/../../../backend/bin/get_openapi.zip"
+  handler       = "get_openapi"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 10
+  
+  environment {
+    variables = {
+      LOG_LEVEL = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+resource "aws_lambda_function" "reconcile_attachments_lambda" {
+  function_name = "mino_reconcile_attachments"
+  filename      = "${path.module}/../../../backend/bin/reconcile_attachments.zip"
+  handler       = "reconcile_attachments"
+  role          = aws_iam_role.lambda_role.arn
+  runtime       = "go1.x"
+  timeout       = 300
+  
+  environment {
+    variables = {
+      ATTACHMENTS_TABLE  = "MiNoAttachments"
+      ATTACHMENTS_BUCKET = "mino-attachments"
+      USERS_TABLE        = "MiNoUsers"
+      LOG_LEVEL          = var.log_level
+    }
+  }
+
+  depends_on = [null_resource.check_lambda_files]
+}
+
+# Settle attachment uploads whose window has passed every 5 minutes
+resource "aws_cloudwatch_event_rule" "reconcile_attachments" {
+  name                = "mino_reconcile_attachments"
+  schedule_expression = "rate(5 minutes)"
+}
+
+resource "aws_cloudwatch_event_target" "reconcile_attachments" {
+  rule = aws_cloudwatch_event_rule.reconcile_attachments.name
+  arn  = aws_lambda_function.reconcile_attachments_lambda.arn
+}
+
+resource "aws_lambda_permission" "events_reconcile_attachments" {
+  statement_id  = "AllowExecutionFromEventBridge"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.reconcile_attachments_lambda.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.reconcile_attachments.arn
+}
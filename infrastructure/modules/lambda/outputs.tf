@@ -1,21 +1,109 @@
 output "lambda_invoke_arns" {
   value = {
-    "auth"        = aws_lambda_function.auth_lambda.invoke_arn
-    "register"    = aws_lambda_function.register_lambda.invoke_arn
-    "get_notes"   = aws_lambda_function.get_notes_lambda.invoke_arn
-    "create_note" = aws_lambda_function.create_note_lambda.invoke_arn
-    "update_note" = aws_lambda_function.update_note_lambda.invoke_arn
-    "delete_note" = aws_lambda_function.delete_note_lambda.invoke_arn
+    "auth"                    = aws_lambda_function.auth_lambda.invoke_arn
+    "register"                = aws_lambda_function.register_lambda.invoke_arn
+    "get_notes"               = aws_lambda_function.get_notes_lambda.invoke_arn
+    "create_note"             = aws_lambda_function.create_note_lambda.invoke_arn
+    "update_note"             = aws_lambda_function.update_note_lambda.invoke_arn
+    "delete_note"             = aws_lambda_function.delete_note_lambda.invoke_arn
+    "get_notebooks"           = aws_lambda_function.get_notebooks_lambda.invoke_arn
+    "create_notebook"         = aws_lambda_function.create_notebook_lambda.invoke_arn
+    "update_notebook"         = aws_lambda_function.update_notebook_lambda.invoke_arn
+    "delete_notebook"         = aws_lambda_function.delete_notebook_lambda.invoke_arn
+    "move_note"               = aws_lambda_function.move_note_lambda.invoke_arn
+    "update_note_flags"       = aws_lambda_function.update_note_flags_lambda.invoke_arn
+    "search_notes"            = aws_lambda_function.search_notes_lambda.invoke_arn
+    "notes_stream"            = aws_lambda_function.notes_stream_lambda.invoke_arn
+    "patch_note"              = aws_lambda_function.patch_note_lambda.invoke_arn
+    "get_note"                = aws_lambda_function.get_note_lambda.invoke_arn
+    "share_note"              = aws_lambda_function.share_note_lambda.invoke_arn
+    "get_note_shares"         = aws_lambda_function.get_note_shares_lambda.invoke_arn
+    "revoke_share"            = aws_lambda_function.revoke_share_lambda.invoke_arn
+    "get_shared_notes"        = aws_lambda_function.get_shared_notes_lambda.invoke_arn
+    "create_share_link"       = aws_lambda_function.create_share_link_lambda.invoke_arn
+    "get_share_links"         = aws_lambda_function.get_share_links_lambda.invoke_arn
+    "revoke_share_link"       = aws_lambda_function.revoke_share_link_lambda.invoke_arn
+    "get_public_note"         = aws_lambda_function.get_public_note_lambda.invoke_arn
+    "add_checklist_item"      = aws_lambda_function.add_checklist_item_lambda.invoke_arn
+    "update_checklist_item"   = aws_lambda_function.update_checklist_item_lambda.invoke_arn
+    "reorder_checklist_items" = aws_lambda_function.reorder_checklist_items_lambda.invoke_arn
+    "remove_checklist_item"   = aws_lambda_function.remove_checklist_item_lambda.invoke_arn
+    "dispatch_reminders"      = aws_lambda_function.dispatch_reminders_lambda.invoke_arn
+    "create_webhook"          = aws_lambda_function.create_webhook_lambda.invoke_arn
+    "get_webhooks"            = aws_lambda_function.get_webhooks_lambda.invoke_arn
+    "update_webhook"          = aws_lambda_function.update_webhook_lambda.invoke_arn
+    "delete_webhook"          = aws_lambda_function.delete_webhook_lambda.invoke_arn
+    "get_webhook_deliveries"  = aws_lambda_function.get_webhook_deliveries_lambda.invoke_arn
+    "deliver_webhooks"        = aws_lambda_function.deliver_webhooks_lambda.invoke_arn
+    "websocket_connect"       = aws_lambda_function.websocket_connect_lambda.invoke_arn
+    "websocket_disconnect"    = aws_lambda_function.websocket_disconnect_lambda.invoke_arn
+    "websocket_message"       = aws_lambda_function.websocket_message_lambda.invoke_arn
+    "pull_changes"            = aws_lambda_function.pull_changes_lambda.invoke_arn
+    "push_changes"            = aws_lambda_function.push_changes_lambda.invoke_arn
+    "create_attachment"       = aws_lambda_function.create_attachment_lambda.invoke_arn
+    "get_attachments"         = aws_lambda_function.get_attachments_lambda.invoke_arn
+    "delete_attachment"       = aws_lambda_function.delete_attachment_lambda.invoke_arn
+    "create_key"              = aws_lambda_function.create_key_lambda.invoke_arn
+    "get_keys"                = aws_lambda_function.get_keys_lambda.invoke_arn
+    "update_key"              = aws_lambda_function.update_key_lambda.invoke_arn
+    "reencrypt_notes"         = aws_lambda_function.reencrypt_notes_lambda.invoke_arn
+    "api"                     = aws_lambda_function.api_lambda.invoke_arn
+    "get_openapi"             = aws_lambda_function.get_openapi_lambda.invoke_arn
+    "reconcile_attachments"   = aws_lambda_function.reconcile_attachments_lambda.invoke_arn
   }
 }
 
 output "lambda_function_names" {
   value = {
-    "auth"        = aws_lambda_function.auth_lambda.function_name
-    "register"    = aws_lambda_function.register_lambda.function_name
-    "get_notes"   = aws_lambda_function.get_notes_lambda.function_name
-    "create_note" = aws_lambda_function.create_note_lambda.function_name
-    "update_note" = aws_lambda_function.update_note_lambda.function_name
-    "delete_note" = aws_lambda_function.delete_note_lambda.function_name
+    "auth"                    = aws_lambda_function.auth_lambda.function_name
+    "register"                = aws_lambda_function.register_lambda.function_name
+    "get_notes"               = aws_lambda_function.get_notes_lambda.function_name
+    "create_note"             = aws_lambda_function.create_note_lambda.function_name
+    "update_note"             = aws_lambda_function.update_note_lambda.function_name
+    "delete_note"             = aws_lambda_function.delete_note_lambda.function_name
+    "get_notebooks"           = aws_lambda_function.get_notebooks_lambda.function_name
+    "create_notebook"         = aws_lambda_function.create_notebook_lambda.function_name
+    "update_notebook"         = aws_lambda_function.update_notebook_lambda.function_name
+    "delete_notebook"         = aws_lambda_function.delete_notebook_lambda.function_name
+    "move_note"               = aws_lambda_function.move_note_lambda.function_name
+    "update_note_flags"       = aws_lambda_function.update_note_flags_lambda.function_name
+    "search_notes"            = aws_lambda_function.search_notes_lambda.function_name
+    "notes_stream"            = aws_lambda_function.notes_stream_lambda.function_name
+    "patch_note"              = aws_lambda_function.patch_note_lambda.function_name
+    "get_note"                = aws_lambda_function.get_note_lambda.function_name
+    "share_note"              = aws_lambda_function.share_note_lambda.function_name
+    "get_note_shares"         = aws_lambda_function.get_note_shares_lambda.function_name
+    "revoke_share"            = aws_lambda_function.revoke_share_lambda.function_name
+    "get_shared_notes"        = aws_lambda_function.get_shared_notes_lambda.function_name
+    "create_share_link"       = aws_lambda_function.create_share_link_lambda.function_name
+    "get_share_links"         = aws_lambda_function.get_share_links_lambda.function_name
+    "revoke_share_link"       = aws_lambda_function.revoke_share_link_lambda.function_name
+    "get_public_note"         = aws_lambda_function.get_public_note_lambda.function_name
+    "add_checklist_item"      = aws_lambda_function.add_checklist_item_lambda.function_name
+    "update_checklist_item"   = aws_lambda_function.update_checklist_item_lambda.function_name
+    "reorder_checklist_items" = aws_lambda_function.reorder_checklist_items_lambda.function_name
+    "remove_checklist_item"   = aws_lambda_function.remove_checklist_item_lambda.function_name
+    "dispatch_reminders"      = aws_lambda_function.dispatch_reminders_lambda.function_name
+    "create_webhook"          = aws_lambda_function.create_webhook_lambda.function_name
+    "get_webhooks"            = aws_lambda_function.get_webhooks_lambda.function_name
+    "update_webhook"          = aws_lambda_function.update_webhook_lambda.function_name
+    "delete_webhook"          = aws_lambda_function.delete_webhook_lambda.function_name
+    "get_webhook_deliveries"  = aws_lambda_function.get_webhook_deliveries_lambda.function_name
+    "deliver_webhooks"        = aws_lambda_function.deliver_webhooks_lambda.function_name
+    "websocket_connect"       = aws_lambda_function.websocket_connect_lambda.function_name
+    "websocket_disconnect"    = aws_lambda_function.websocket_disconnect_lambda.function_name
+    "websocket_message"       = aws_lambda_function.websocket_message_lambda.function_name
+    "pull_changes"            = aws_lambda_function.pull_changes_lambda.function_name
+    "push_changes"            = aws_lambda_function.push_changes_lambda.function_name
+    "create_attachment"       = aws_lambda_function.create_attachment_lambda.function_name
+    "get_attachments"         = aws_lambda_function.get_attachments_lambda.function_name
+    "delete_attachment"       = aws_lambda_function.delete_attachment_lambda.function_name
+    "create_key"              = aws_lambda_function.create_key_lambda.function_name
+    "get_keys"                = aws_lambda_function.get_keys_lambda.function_name
+    "update_key"              = aws_lambda_function.update_key_lambda.function_name
+    "reencrypt_notes"         = aws_lambda_function.reencrypt_notes_lambda.function_name
+    "api"                     = aws_lambda_function.api_lambda.function_name
+    "get_openapi"             = aws_lambda_function.get_openapi_lambda.function_name
+    "reconcile_attachments"   = aws_lambda_function.reconcile_attachments_lambda.function_name
   }
 } 
\ No newline at end of file
@@ -0,0 +1,9 @@
+variable "notes_stream_arn" {
+  description = "ARN of the MiNoNotes DynamoDB stream"
+  type        = string
+}
+variable "log_level" {
+  description = "Level of the Lambda logs: debug, info, warn or error"
+  type        = string
+  default     = "info"
+}
@@ -57,4 +57,22 @@ resource "aws_s3_object" "css" {
   content_type = "text/css"
   etag = filemd5("${path.module}/../../../frontend/styles.css")
   depends_on = [aws_s3_bucket.frontend]
-} 
\ No newline at end of file
+} 
+
+# Note attachments, uploaded and downloaded by the browser through presigned URLs
+resource "aws_s3_bucket" "attachments" {
+  bucket = "mino-attachments"
+  force_destroy = true
+}
+
+resource "aws_s3_bucket_cors_configuration" "attachments" {
+  bucket = aws_s3_bucket.attachments.bucket
+
+  cors_rule {
+    allowed_methods = ["GET", "PUT"]
+    allowed_origins = ["*"]
+    allowed_headers = ["*"]
+    expose_headers  = ["ETag"]
+    max_age_seconds = 3000
+  }
+}
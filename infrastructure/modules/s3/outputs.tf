@@ -4,4 +4,8 @@ output "bucket_name" {
 
 output "website_endpoint" {
   value = aws_s3_bucket_website_configuration.frontend.website_endpoint
-} 
\ No newline at end of file
+} 
+
+output "attachments_bucket_name" {
+  value = aws_s3_bucket.attachments.bucket
+}
@@ -81,6 +81,16 @@ update_api_id() {
     sed -i "s|^const API_ID = '.*';|const API_ID = '$API_ID';|" frontend/app.js
     
     success "Updated frontend/app.js with API ID: $API_ID"
+    
+    # Get the WebSocket endpoint, live updates are optional
+    WS_URL=$(aws --endpoint-url=http://192.168.0.250:4566 apigatewayv2 get-apis --query "Items[?Name=='mino-websocket'].ApiEndpoint" --output text 2>/dev/null)
+    
+    if [ -n "$WS_URL" ] && [ "$WS_URL" != "None" ]; then
+        sed -i "s|^const WS_URL = '.*';|const WS_URL = '$WS_URL/dev';|" frontend/app.js
+        success "Updated frontend/app.js with WebSocket URL: $WS_URL/dev"
+    else
+        warning "WebSocket API not found, notes will not update live"
+    fi
 }
 
 # Build backend functions
@@ -115,7 +125,7 @@ build_backend() {
     log "Building Lambda functions..."
     mkdir -p bin
     
-    MODULES="auth register get_notes create_note update_note delete_note"
+    MODULES="auth register get_notes create_note update_note delete_note get_notebooks create_notebook update_notebook delete_notebook move_note update_note_flags search_notes notes_stream patch_note get_note share_note get_note_shares revoke_share get_shared_notes create_share_link get_share_links revoke_share_link get_public_note add_checklist_item update_checklist_item reorder_checklist_items remove_checklist_item dispatch_reminders create_webhook get_webhooks update_webhook delete_webhook get_webhook_deliveries deliver_webhooks websocket_connect websocket_disconnect websocket_message pull_changes push_changes create_attachment get_attachments delete_attachment create_key get_keys update_key reencrypt_notes api get_openapi reconcile_attachments"
     for module in $MODULES; do
         log "Building $module..."
         